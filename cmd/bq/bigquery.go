@@ -3,6 +3,8 @@ package bq
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/bq/datasets"
+	"github.com/sbchaos/opms/cmd/bq/query"
 	"github.com/sbchaos/opms/cmd/bq/tables"
 	"github.com/sbchaos/opms/lib/config"
 )
@@ -15,6 +17,8 @@ func NewBigQueryCommand(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.AddCommand(
+		datasets.NewDatasetCommand(cfg),
+		query.NewQueryCommand(cfg),
 		tables.NewTableCommand(cfg),
 	)
 	return cmd
@@ -0,0 +1,22 @@
+package datasets
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// NewDatasetCommand initializes command for datasets
+func NewDatasetCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "dataset",
+		Aliases: []string{"datasets"},
+		Short:   "Commands that will let the user to operate on datasets",
+		Example: "opms bq dataset [sub-command]",
+	}
+	cmd.AddCommand(
+		NewListCommand(cfg),
+	)
+
+	return cmd
+}
@@ -0,0 +1,93 @@
+package datasets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/external/gcp"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+var timeout = time.Minute * 10
+
+type listCommand struct {
+	cfg *config.Config
+
+	project    string
+	namePrefix string
+	format     string
+}
+
+// NewListCommand initializes command to list the datasets of a project
+func NewListCommand(cfg *config.Config) *cobra.Command {
+	list := &listCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the datasets of a project",
+		Example: "opms bq datasets list -p proj -n raw_",
+		RunE:    list.RunE,
+	}
+
+	cmd.Flags().StringVarP(&list.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&list.namePrefix, "prefix", "n", "", "Dataset name prefix")
+	cmd.Flags().StringVarP(&list.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.MarkFlagRequired("project")
+	return cmd
+}
+
+func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
+	provider, err := gcp.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	datasets, err := gcp.ListDatasets(ctx, client, r.project, r.namePrefix)
+	if err != nil {
+		return fmt.Errorf("failed to list datasets of %s: %w", r.project, err)
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Dataset", "Location", "Created", "Last Modified", "Description"})
+
+	for _, name := range datasets {
+		md, err := client.DatasetInProject(r.project, name).Metadata(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] failed to get metadata of %s: %s\n", name, err)
+			continue
+		}
+
+		printer.AddField(name)
+		printer.AddField(md.Location)
+		printer.AddField(md.CreationTime.Format(time.DateTime))
+		printer.AddField(md.LastModifiedTime.Format(time.DateTime))
+		printer.AddField(md.Description)
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+	return nil
+}
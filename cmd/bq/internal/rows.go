@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"cloud.google.com/go/bigquery"
+
+	"github.com/sbchaos/opms/external/gcp"
+	"github.com/sbchaos/opms/lib/printers/table"
+)
+
+// AddRow adds the values of row to printer and ends the row, printers which keep types get
+// json values, others get the values as string with RECORD and REPEATED fields as json
+func AddRow(printer table.Printer, schema bigquery.Schema, row []bigquery.Value) {
+	vp, typed := printer.(table.ValuePrinter)
+	for i, value := range row {
+		var field *bigquery.FieldSchema
+		if i < len(schema) {
+			field = schema[i]
+		}
+
+		if typed {
+			vp.AddValue(gcp.JSONValue(value, field))
+		} else {
+			printer.AddField(gcp.ValueString(value, field))
+		}
+	}
+	printer.EndRow()
+}
+
+// Headers returns the names of fields in schema
+func Headers(schema bigquery.Schema) []string {
+	headers := make([]string, len(schema))
+	for i, field := range schema {
+		headers[i] = field.Name
+	}
+	return headers
+}
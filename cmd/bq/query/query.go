@@ -0,0 +1,195 @@
+package query
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/iterator"
+
+	"github.com/sbchaos/opms/cmd/bq/internal"
+	"github.com/sbchaos/opms/external/gcp"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+const driveScope = "https://www.googleapis.com/auth/drive"
+
+type queryCommand struct {
+	cfg *config.Config
+
+	query   string
+	sqlFile string
+	project string
+	params  []string
+	format  string
+	maxRows int
+	out     string
+	timeout time.Duration
+}
+
+// NewQueryCommand initializes command to run a query on bigquery
+func NewQueryCommand(cfg *config.Config) *cobra.Command {
+	qc := &queryCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "Run a query on bigquery",
+		Long: `Run a query with the service account of the project, named parameters are passed as
+name=value for STRING or name:TYPE=value, eg --param dt:DATE=2024-01-01.
+RECORD and REPEATED fields are written as json.`,
+		Example: `opms bq query -p data-proj -q "SELECT * FROM ds.users WHERE id = @id" --param id:INT64=10
+opms bq query -p data-proj -f query.sql -o jsonl --max-rows 0 --out users.jsonl`,
+		RunE: qc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&qc.query, "query", "q", "", "Query to run")
+	cmd.Flags().StringVarP(&qc.sqlFile, "file", "f", "", "Query filename to run, - for stdin")
+	cmd.Flags().StringVarP(&qc.project, "project", "p", "", "Project to run the query in")
+	cmd.Flags().StringArrayVar(&qc.params, "param", nil, "Query parameter as name=value or name:TYPE=value, can be repeated")
+	cmd.Flags().StringVarP(&qc.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().IntVar(&qc.maxRows, "max-rows", 1000, "Maximum number of rows to write, 0 for no limit")
+	cmd.Flags().StringVar(&qc.out, "out", "", "File to write the rows, instead of stdout")
+	cmd.Flags().DurationVar(&qc.timeout, "timeout", 10*time.Minute, "Timeout for the query")
+	return cmd
+}
+
+func (r *queryCommand) RunE(_ *cobra.Command, _ []string) error {
+	query := r.query
+	if r.sqlFile != "" {
+		content, err := cmdutil.ReadFile(r.sqlFile, os.Stdin)
+		if err != nil {
+			return err
+		}
+		query = string(content)
+	}
+	if strings.TrimSpace(query) == "" {
+		return errors.New("either --query or --file is required")
+	}
+	if r.maxRows < 0 {
+		return errors.New("--max-rows should not be negative")
+	}
+
+	params := make([]bigquery.QueryParameter, len(r.params))
+	for i, p := range r.params {
+		param, err := gcp.ParseQueryParameter(p)
+		if err != nil {
+			return err
+		}
+		params[i] = param
+	}
+
+	provider, err := gcp.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project, driveScope)
+	if err != nil {
+		return fmt.Errorf("failed to get client for project %s: %w", r.project, err)
+	}
+
+	var w io.Writer = os.Stdout
+	isTTY := false
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+	if r.out == "" {
+		isTTY = t.IsTerminalOutput()
+	} else {
+		f, err := os.Create(r.out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", r.out, err)
+		}
+		defer f.Close()
+
+		buf := bufio.NewWriter(f)
+		defer buf.Flush()
+		w = buf
+	}
+
+	printer, err := table.NewWithFormat(w, r.format, isTTY, size)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), r.timeout)
+	defer cancelFunc()
+
+	q := client.Query(query)
+	q.Parameters = params
+
+	count, err := r.runQuery(ctx, q, printer)
+	if err != nil {
+		return err
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to write rows: %w", err)
+	}
+
+	if r.out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d rows to %s\n", count, r.out)
+	}
+	return nil
+}
+
+// runQuery writes the rows to printer as they are read, csv and jsonl output is not kept in memory
+func (r *queryCommand) runQuery(ctx context.Context, q *bigquery.Query, printer table.Printer) (int, error) {
+	it, err := q.Read(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error while running query: %w", err)
+	}
+
+	// row numbers are only for display, other formats are used as input to other commands
+	withRowNum := r.format == "" || strings.EqualFold(r.format, table.FormatTable)
+
+	var headers []string
+	if withRowNum {
+		headers = append(headers, "Row")
+	}
+
+	count := 0
+	for {
+		var row []bigquery.Value
+		err = it.Next(&row)
+		if errors.Is(err, iterator.Done) {
+			if count == 0 {
+				printer.AddHeader(append(headers, internal.Headers(it.Schema)...))
+			}
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("error while reading rows: %w", err)
+		}
+
+		// schema is available after the first call to Next, also when it returns iterator.Done
+		if count == 0 {
+			printer.AddHeader(append(headers, internal.Headers(it.Schema)...))
+		}
+
+		count++
+		if withRowNum {
+			printer.AddField(strconv.Itoa(count))
+		}
+		internal.AddRow(printer, it.Schema, row)
+
+		if r.maxRows > 0 && count >= r.maxRows {
+			if it.TotalRows > uint64(count) {
+				fmt.Fprintf(os.Stderr, "Showing %d of %d rows, use --max-rows to change the limit\n", count, it.TotalRows)
+			}
+			break
+		}
+	}
+	return count, nil
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/spf13/cobra"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 
 	"github.com/sbchaos/opms/external/gcp"
@@ -31,9 +34,23 @@ type countCommand struct {
 	name     string
 	fileName string
 
-	mappingJson string
-	workers     int
-	mu          sync.Mutex
+	mappingJson   string
+	workers       int
+	metadata      bool
+	partitionDate string
+	byPartition   bool
+	format        string
+}
+
+// partitionCount is the number of rows of a partition, partition is empty for the whole table
+type partitionCount struct {
+	partition string
+	count     int64
+}
+
+type tableCount struct {
+	name   string
+	counts []partitionCount
 }
 
 // NewCountCommand initializes command to count number of rows in table
@@ -41,21 +58,36 @@ func NewCountCommand(cfg *config.Config) *cobra.Command {
 	count := &countCommand{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "count",
-		Short:   "Count the rows in table",
-		Example: "opms bq tables count",
-		RunE:    count.RunE,
+		Use:   "count",
+		Short: "Count the rows in table",
+		Long: `Count the rows of tables with COUNT(*), or with --metadata from __TABLES__ and
+INFORMATION_SCHEMA.PARTITIONS which does not scan the table. Counts from metadata do not
+include the rows in streaming buffer.`,
+		Example: `opms bq tables count -n proj.dataset.table
+opms bq tables count -f tables.txt --metadata --by-partition -o csv
+opms bq tables count -f tables.txt --partition-date 2024-01-01 -w 5`,
+		RunE: count.RunE,
 	}
 
 	cmd.Flags().StringVarP(&count.name, "name", "n", "", "Table name")
 	cmd.Flags().StringVarP(&count.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
 	cmd.Flags().StringVarP(&count.mappingJson, "mapping", "m", "", "Project mapping for the names")
 	cmd.Flags().IntVarP(&count.workers, "workers", "w", 1, "Number of parallel workers")
+	cmd.Flags().BoolVar(&count.metadata, "metadata", false, "Count from table metadata without scanning the table")
+	cmd.Flags().StringVar(&count.partitionDate, "partition-date", "", "Count only the partition of the date, eg 2024-01-01")
+	cmd.Flags().BoolVar(&count.byPartition, "by-partition", false, "Count the rows of every partition")
+	cmd.Flags().StringVarP(&count.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
 
 	return cmd
 }
 
 func (r *countCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.partitionDate != "" {
+		if _, err := time.Parse(time.DateOnly, r.partitionDate); err != nil {
+			return fmt.Errorf("invalid --partition-date %s, expected YYYY-MM-DD", r.partitionDate)
+		}
+	}
+
 	provider, err := gcp.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
@@ -100,57 +132,93 @@ func (r *countCommand) RunE(_ *cobra.Command, _ []string) error {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 	defer cancelFunc()
 
-	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	printer.AddHeader([]string{"Table", "Count", "Error"})
-
-	tasks := make([]func() pool.JobResult[string], len(tableNames))
-	for i, t1 := range tableNames {
-		t1 := t1
-		tasks[i] = func() pool.JobResult[string] {
-			err = r.queryTable(ctx, provider, t1, printer)
-			return pool.JobResult[string]{
-				Output: t1.String(),
-				Err:    err,
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+
+	withPartition := r.withPartition()
+	if withPartition {
+		printer.AddHeader([]string{"Table", "Partition", "Count", "Error"})
+	} else {
+		printer.AddHeader([]string{"Table", "Count", "Error"})
+	}
+
+	jobs := make(chan pool.Job[tableCount], 20)
+	go func() {
+		for _, t1 := range tableNames {
+			t1 := t1
+			jobs <- func() pool.JobResult[tableCount] {
+				counts, err := r.countTable(ctx, provider, t1)
+				return pool.JobResult[tableCount]{
+					Output: tableCount{name: t1.String(), counts: counts},
+					Err:    err,
+				}
 			}
 		}
-	}
+		close(jobs)
+	}()
+
+	// results are written as the tables are counted, only the table format waits for all rows
+	failed := 0
+	for out := range pool.StartPool(r.workers, jobs) {
+		if out.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Name: %s, Err: %s\n", out.Output.name, out.Err)
 
-	outchan := pool.RunWithWorkers(r.workers, tasks)
+			printer.AddField(out.Output.name)
+			if withPartition {
+				printer.AddField(r.partitionDate)
+			}
+			printer.AddField("-1")
+			printer.AddField(gcp.ClassifyError(out.Err))
+			printer.EndRow()
+			continue
+		}
+
+		for _, c := range out.Output.counts {
+			printer.AddField(out.Output.name)
+			if withPartition {
+				printer.AddField(c.partition)
+			}
+			printer.AddField(strconv.FormatInt(c.count, 10))
+			printer.AddField("")
+			printer.EndRow()
+		}
+	}
 
 	err = printer.Render()
 	if err != nil {
 		return fmt.Errorf("failed to print table: %w", err)
 	}
 
-	fmt.Println()
-	fmt.Println()
-	fmt.Fprintln(os.Stderr, "Error(s) encountered:")
-	for out := range outchan {
-		if out.Err != nil {
-			fmt.Fprintf(os.Stderr, "Name: %s, Err: %s\n", out.Output, out.Err)
-		}
+	if failed > 0 {
+		return fmt.Errorf("failed to count %d of %d tables", failed, len(tableNames))
 	}
-
 	return nil
 }
 
-func (r *countCommand) queryTable(ctx context.Context, provider *gcp.ClientProvider, tab names.Table, printer table.Printer) error {
+func (r *countCommand) withPartition() bool {
+	return r.byPartition || r.partitionDate != ""
+}
+
+func (r *countCommand) countTable(ctx context.Context, provider *gcp.ClientProvider, tab names.Table) ([]partitionCount, error) {
 	client, err := provider.GetClient(tab.Schema.ProjectID, driveScope)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	qr := `SELECT COUNT(*) FROM ` + tab.String()
-	q := client.Query(qr)
+	q, err := r.countQuery(ctx, client, tab)
+	if err != nil {
+		return nil, err
+	}
 
 	it, err := q.Read(ctx)
 	if err != nil {
-		r.mu.Lock()
-		printer.AddField(tab.String())
-		addFailure(printer, "permission issue")
-		r.mu.Unlock()
-		return fmt.Errorf("error while reading from bq: %w", err)
+		return nil, fmt.Errorf("error while reading from bq: %w", err)
 	}
+
+	var counts []partitionCount
 	for {
 		var row []bigquery.Value
 		err = it.Next(&row)
@@ -158,32 +226,110 @@ func (r *countCommand) queryTable(ctx context.Context, provider *gcp.ClientProvi
 			break
 		}
 		if err != nil {
-			r.mu.Lock()
-			printer.AddField(tab.String())
-			addFailure(printer, "failure in query")
-			r.mu.Unlock()
-			return err
+			return nil, fmt.Errorf("error while reading rows: %w", err)
 		}
 
+		c := partitionCount{}
+		if len(row) == 2 {
+			c.partition = partitionName(row[0])
+			row = row[1:]
+		}
 		if len(row) != 1 {
-			r.mu.Lock()
-			printer.AddField(tab.String())
-			addFailure(printer, "too many rows")
-			r.mu.Unlock()
+			return nil, fmt.Errorf("unexpected columns in result: %d", len(row))
 		}
 
-		r.mu.Lock()
-		printer.AddField(tab.String())
-		printer.AddField(fmt.Sprintf("%v", row[0]))
-		printer.AddField("")
-		printer.EndRow()
-		r.mu.Unlock()
+		// counts of partitions are NULL in metadata for the partitions being written
+		if n, ok := row[0].(int64); ok {
+			c.count = n
+		}
+		counts = append(counts, c)
 	}
-	return nil
+
+	if len(counts) == 0 {
+		if !r.withPartition() {
+			return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "table not found in __TABLES__"}
+		}
+		if r.partitionDate != "" {
+			counts = append(counts, partitionCount{partition: r.partitionDate})
+		}
+	}
+	return counts, nil
+}
+
+// countQuery builds the query for count, metadata queries read __TABLES__ and
+// INFORMATION_SCHEMA.PARTITIONS and partitions of other queries use the partition column
+func (r *countCommand) countQuery(ctx context.Context, client *bigquery.Client, tab names.Table) (*bigquery.Query, error) {
+	dataset := fmt.Sprintf("`%s.%s`", tab.Schema.ProjectID, tab.Schema.SchemaID)
+	params := []bigquery.QueryParameter{{Name: "table", Value: tab.TableID}}
+
+	var qr string
+	switch {
+	case r.metadata && !r.withPartition():
+		qr = fmt.Sprintf("SELECT row_count FROM `%s.%s.__TABLES__` WHERE table_id = @table", tab.Schema.ProjectID, tab.Schema.SchemaID)
+
+	case r.metadata:
+		qr = "SELECT partition_id, total_rows FROM " + dataset + ".INFORMATION_SCHEMA.PARTITIONS WHERE table_name = @table"
+		if r.partitionDate != "" {
+			// partition ids of DAY and HOUR partitions start with the date as YYYYMMDD
+			qr += " AND partition_id LIKE @partition"
+			params = append(params, bigquery.QueryParameter{
+				Name:  "partition",
+				Value: strings.ReplaceAll(r.partitionDate, "-", "") + "%",
+			})
+		}
+		qr += " ORDER BY partition_id"
+
+	case r.withPartition():
+		column, err := partitionColumn(ctx, client, tab)
+		if err != nil {
+			return nil, err
+		}
+
+		expr := "DATE(" + column + ")"
+		qr = fmt.Sprintf("SELECT CAST(%s AS STRING), COUNT(*) FROM `%s`", expr, tab.String())
+		if r.partitionDate != "" {
+			qr += fmt.Sprintf(" WHERE %s = '%s'", expr, r.partitionDate)
+		}
+		qr += " GROUP BY 1 ORDER BY 1"
+		params = nil
+
+	default:
+		qr = "SELECT COUNT(*) FROM `" + tab.String() + "`"
+		params = nil
+	}
+
+	q := client.Query(qr)
+	q.Parameters = params
+	return q, nil
+}
+
+// partitionColumn returns the column of time partitioned table, _PARTITIONTIME for ingestion time
+func partitionColumn(ctx context.Context, client *bigquery.Client, tab names.Table) (string, error) {
+	md, err := client.DatasetInProject(tab.Schema.ProjectID, tab.Schema.SchemaID).Table(tab.TableID).Metadata(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if md.TimePartitioning == nil {
+		return "", fmt.Errorf("table %s is not partitioned by time", tab.String())
+	}
+
+	field := gcp.PartitionFields(md)[0]
+	if strings.HasPrefix(field, "_PARTITION") {
+		return field, nil
+	}
+	return "`" + field + "`", nil
 }
 
-func addFailure(printer table.Printer, msg string) {
-	printer.AddField("-1")
-	printer.AddField(msg)
-	printer.EndRow()
+// partitionName formats the partition id of metadata as a date, 20240101 is 2024-01-01
+func partitionName(v bigquery.Value) string {
+	s, ok := v.(string)
+	if !ok {
+		return gcp.ValueString(v, nil)
+	}
+	if len(s) == 8 {
+		if d, err := time.Parse("20060102", s); err == nil {
+			return d.Format(time.DateOnly)
+		}
+	}
+	return s
 }
@@ -1,74 +1,103 @@
 package tables
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/spf13/cobra"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 
 	"github.com/sbchaos/opms/external/gcp"
 	"github.com/sbchaos/opms/lib/cmdutil"
 	"github.com/sbchaos/opms/lib/config"
 	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/pool"
 	"github.com/sbchaos/opms/lib/printers/table"
 	"github.com/sbchaos/opms/lib/term"
 )
 
+const (
+	statusCreated   = "created"
+	statusUpdated   = "updated"
+	statusUnchanged = "unchanged"
+	statusWritten   = "written"
+	statusFailed    = "failed"
+)
+
 type fetchDDL struct {
-	cfg       *config.Config
-	name      string
-	fileName  string
-	outputDir string
+	cfg         *config.Config
+	name        string
+	fileName    string
+	dataset     string
+	outputDir   string
+	tableTypes  []string
+	workers     int
+	incremental bool
 
 	provider *gcp.ClientProvider
-	printer  table.Printer
+}
+
+// tableDDL is the ddl of a table with the status of writing it to file
+type tableDDL struct {
+	table  names.Table
+	typ    string
+	ddl    string
+	status string
+	err    error
 }
 
 func NewFetchDDLCommand(cfg *config.Config) *cobra.Command {
 	fetch := &fetchDDL{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "fetch-ddl",
-		Short:   "Fetch DDL for the table",
-		Example: "opms bq tables count",
-		RunE:    fetch.RunE,
+		Use:   "fetch-ddl",
+		Short: "Fetch DDL for the table",
+		Long: `Fetch the DDL of tables from INFORMATION_SCHEMA.TABLES, with one query for each dataset.
+The DDL is written to <output-dir>/<project>/<dataset>/<table>.sql, with --incremental only
+the files with a changed DDL are written.`,
+		Example: `opms bq tables fetch-ddl -n proj.dataset.table -o ddl
+opms bq tables fetch-ddl -d proj.dataset -t TABLE,VIEW -o ddl --incremental
+opms bq tables fetch-ddl -f tables.txt -w 5 -o ddl`,
+		RunE: fetch.RunE,
 	}
 
 	cmd.Flags().StringVarP(&fetch.name, "name", "n", "", "Table name")
 	cmd.Flags().StringVarP(&fetch.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
+	cmd.Flags().StringVarP(&fetch.dataset, "dataset", "d", "", "Dataset as project.dataset, fetches all the tables")
 	cmd.Flags().StringVarP(&fetch.outputDir, "output-dir", "o", "", "Output directory")
+	cmd.Flags().StringSliceVarP(&fetch.tableTypes, "type", "t", nil, "Table types to fetch, eg TABLE,VIEW,MATERIALIZED_VIEW")
+	cmd.Flags().IntVarP(&fetch.workers, "workers", "w", 1, "Number of parallel workers")
+	cmd.Flags().BoolVar(&fetch.incremental, "incremental", false, "Write only the files where DDL has changed")
 	return cmd
 }
 
 func (m *fetchDDL) RunE(_ *cobra.Command, _ []string) error {
-	client, err := gcp.NewClientProvider(m.cfg)
-	if err != nil {
-		return err
+	for _, typ := range m.tableTypes {
+		if !validType(typ) {
+			return fmt.Errorf("invalid table type: %s", typ)
+		}
 	}
-	m.provider = client
 
-	var tableNames []string
-	if m.name == "" && m.fileName == "" {
-		return errors.New("either --name or --filename is required")
+	groups, err := m.datasetTables()
+	if err != nil {
+		return err
 	}
 
-	if m.name != "" {
-		tableNames = []string{m.name}
+	client, err := gcp.NewClientProvider(m.cfg)
+	if err != nil {
+		return err
 	}
+	m.provider = client
 
-	if m.fileName != "" {
-		fields, err := cmdutil.ReadLines(m.fileName, os.Stdin)
-		if err != nil {
-			return err
-		}
-
-		tableNames = fields
-	}
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
@@ -76,19 +105,51 @@ func (m *fetchDDL) RunE(_ *cobra.Command, _ []string) error {
 	defer cancelFunc()
 
 	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	m.printer = printer
+	printer.AddHeader([]string{"Table", "Type", "Status", "Error"})
 
-	printer.AddHeader([]string{"Table", "Status", "Error"})
+	jobs := make(chan pool.Job[[]tableDDL], 20)
+	go func() {
+		for _, schema := range sortedSchemas(groups) {
+			schema, tables := schema, groups[schema]
+			jobs <- func() pool.JobResult[[]tableDDL] {
+				ddls, err := m.queryDDL(ctx, schema, tables)
+				if err != nil {
+					err = fmt.Errorf("%s: %w", schema.String(), err)
+				}
+				return pool.JobResult[[]tableDDL]{Output: ddls, Err: err}
+			}
+		}
+		close(jobs)
+	}()
 
-	errs := make([]error, 0)
-	for _, t1 := range tableNames {
-		err = m.queryDDL(ctx, t1)
-		if err != nil {
-			errs = append(errs, err)
+	var errs []error
+	counts := map[string]int{}
+	for res := range pool.StartPool(m.workers, jobs) {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+		for _, d := range res.Output {
+			if d.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", d.table.String(), d.err))
+			}
+			counts[d.status]++
+
+			printer.AddField(d.table.String())
+			printer.AddField(d.typ)
+			printer.AddField(d.status)
+			if d.err != nil {
+				printer.AddField(gcp.ClassifyError(d.err))
+			} else {
+				printer.AddField("")
+			}
+			printer.EndRow()
 		}
 	}
 
 	printer.Render()
+	if m.incremental {
+		fmt.Printf("\nCreated: %d, Updated: %d, Unchanged: %d\n", counts[statusCreated], counts[statusUpdated], counts[statusUnchanged])
+	}
 	if len(errs) != 0 {
 		fmt.Println("Errors:")
 		for _, err := range errs {
@@ -98,26 +159,84 @@ func (m *fetchDDL) RunE(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func (m *fetchDDL) queryDDL(ctx context.Context, tableName string) error {
-	tb, err := names.FromTableName(tableName)
-	if err != nil {
-		return err
+// datasetTables groups the tables by dataset, the tables are nil for fetching the whole dataset
+func (m *fetchDDL) datasetTables() (map[names.Schema][]string, error) {
+	var tableNames []string
+	switch {
+	case m.dataset != "":
+		schema, err := names.FromSchemaName(m.dataset)
+		if err != nil {
+			return nil, err
+		}
+		return map[names.Schema][]string{schema: nil}, nil
+
+	case m.name != "":
+		tableNames = []string{m.name}
+
+	case m.fileName != "":
+		fields, err := cmdutil.ReadLines(m.fileName, os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		tableNames = fields
+
+	default:
+		return nil, errors.New("one of --name, --filename or --dataset is required")
 	}
 
-	client, err := m.provider.GetClient(tb.Schema.ProjectID, driveScope)
+	groups := map[names.Schema][]string{}
+	for _, name := range tableNames {
+		tb, err := names.FromTableName(name)
+		if err != nil {
+			return nil, err
+		}
+		groups[tb.Schema] = append(groups[tb.Schema], tb.TableID)
+	}
+	return groups, nil
+}
+
+// queryDDL fetches the ddl of the tables in dataset with a single query, all tables when tables is nil
+func (m *fetchDDL) queryDDL(ctx context.Context, schema names.Schema, tables []string) ([]tableDDL, error) {
+	client, err := m.provider.GetClient(schema.ProjectID, driveScope)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT table_name, table_type, ddl FROM `%s.%s`.INFORMATION_SCHEMA.TABLES", schema.ProjectID, schema.SchemaID)
+	var conditions []string
+	var params []bigquery.QueryParameter
+	if tables != nil {
+		conditions = append(conditions, "table_name IN UNNEST(@tables)")
+		params = append(params, bigquery.QueryParameter{Name: "tables", Value: tables})
 	}
+	if len(m.tableTypes) > 0 {
+		types := make([]string, len(m.tableTypes))
+		for i, typ := range m.tableTypes {
+			// INFORMATION_SCHEMA uses BASE TABLE for the type TABLE of api
+			types[i] = strings.ToUpper(typ)
+			if types[i] == string(bigquery.RegularTable) {
+				types[i] = "BASE TABLE"
+			}
+			types[i] = strings.ReplaceAll(types[i], "_", " ")
+		}
+		conditions = append(conditions, "table_type IN UNNEST(@types)")
+		params = append(params, bigquery.QueryParameter{Name: "types", Value: types})
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY table_name"
 
-	query := fmt.Sprintf("select table_name, ddl from `%s.%s`.INFORMATION_SCHEMA.TABLES where table_name = '%s';", tb.Schema.ProjectID, tb.Schema.SchemaID, tb.TableID)
 	q := client.Query(query)
-	m.printer.AddField(tableName)
+	q.Parameters = params
 
 	it, err := q.Read(ctx)
 	if err != nil {
-		fetchFailure(m.printer, "permission issue")
-		return fmt.Errorf("error while reading from bq: %w", err)
+		return nil, fmt.Errorf("error while reading from bq: %w", err)
 	}
+
+	found := map[string]bool{}
+	var ddls []tableDDL
 	for {
 		var row []bigquery.Value
 		err = it.Next(&row)
@@ -125,37 +244,74 @@ func (m *fetchDDL) queryDDL(ctx context.Context, tableName string) error {
 			break
 		}
 		if err != nil {
-			fetchFailure(m.printer, "failure in query")
-			return err
+			return ddls, err
 		}
-
-		if len(row) != 2 {
-			fetchFailure(m.printer, "too many rows")
+		if len(row) != 3 {
+			return ddls, fmt.Errorf("unexpected columns in result: %d", len(row))
 		}
-		content, ok := row[1].(string)
+
+		name, _ := row[0].(string)
+		typ, _ := row[1].(string)
+		d := tableDDL{table: names.TableWithSchema(schema, name), typ: typ}
+		found[name] = true
+
+		content, ok := row[2].(string)
 		if !ok {
-			fetchFailure(m.printer, "unable to parse dll")
-			return nil
+			d.status, d.err = statusFailed, errors.New("unable to parse ddl")
+		} else {
+			d.ddl = content
+			d.status, d.err = m.writeDDL(d)
 		}
+		ddls = append(ddls, d)
+	}
 
-		toWritePath := tableName + ".sql"
-		if m.outputDir != "" {
-			toWritePath = path.Join(m.outputDir, toWritePath)
+	for _, name := range tables {
+		if !found[name] && len(m.tableTypes) == 0 {
+			ddls = append(ddls, tableDDL{
+				table:  names.TableWithSchema(schema, name),
+				status: statusFailed,
+				err:    &googleapi.Error{Code: http.StatusNotFound, Message: "table not found"},
+			})
 		}
+	}
+	return ddls, nil
+}
 
-		err = cmdutil.WriteFileAndDir(toWritePath, []byte(content))
-		if err != nil {
-			fetchFailure(m.printer, "failure in write file")
-			return err
+// writeDDL writes the ddl to <project>/<dataset>/<table>.sql, in incremental mode the file is
+// written only when content is different
+func (m *fetchDDL) writeDDL(d tableDDL) (string, error) {
+	toWritePath := filepath.Join(m.outputDir, d.table.Schema.ProjectID, d.table.Schema.SchemaID, d.table.TableID+".sql")
+	content := []byte(d.ddl)
+
+	status := statusWritten
+	if m.incremental {
+		existing, err := os.ReadFile(toWritePath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			status = statusCreated
+		case err != nil:
+			return statusFailed, err
+		case bytes.Equal(existing, content):
+			return statusUnchanged, nil
+		default:
+			status = statusUpdated
 		}
+	}
 
-		m.printer.AddField("success")
-		m.printer.EndRow()
+	err := cmdutil.WriteFileAndDir(toWritePath, content)
+	if err != nil {
+		return statusFailed, fmt.Errorf("failure in write file: %w", err)
 	}
-	return nil
+	return status, nil
 }
 
-func fetchFailure(printer table.Printer, message string) {
-	printer.AddField(message)
-	printer.EndRow()
+func sortedSchemas(groups map[names.Schema][]string) []names.Schema {
+	schemas := make([]names.Schema, 0, len(groups))
+	for s := range groups {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].String() < schemas[j].String()
+	})
+	return schemas
 }
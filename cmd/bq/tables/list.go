@@ -0,0 +1,258 @@
+package tables
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/external/gcp"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/pool"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+var tableTypes = []bigquery.TableType{
+	bigquery.RegularTable,
+	bigquery.ViewTable,
+	bigquery.ExternalTable,
+	bigquery.MaterializedView,
+	bigquery.Snapshot,
+}
+
+type listCommand struct {
+	cfg *config.Config
+
+	dataset    string
+	project    string
+	namePrefix string
+	tableType  string
+	format     string
+	recursive  bool
+	out        string
+	workers    int
+}
+
+type tableInfo struct {
+	name string
+	md   *bigquery.TableMetadata
+}
+
+// NewListCommand initializes command to list the tables of a dataset
+func NewListCommand(cfg *config.Config) *cobra.Command {
+	list := &listCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the tables of a dataset",
+		Long: `List the tables with the row count, size, last modified time, partitioning and clustering.
+With --recursive the tables of all the datasets in the project are written as a csv inventory.`,
+		Example: `opms bq tables list -d proj.dataset -n user_ -t TABLE
+opms bq tables list -p proj --recursive --out inventory.csv`,
+		RunE: list.RunE,
+	}
+
+	cmd.Flags().StringVarP(&list.dataset, "dataset", "d", "", "Dataset as project.dataset")
+	cmd.Flags().StringVarP(&list.project, "project", "p", "", "Project, used with --recursive")
+	cmd.Flags().StringVarP(&list.namePrefix, "prefix", "n", "", "Table name prefix")
+	cmd.Flags().StringVarP(&list.tableType, "type", "t", "", "Table type: TABLE, VIEW, EXTERNAL, MATERIALIZED_VIEW, SNAPSHOT")
+	cmd.Flags().StringVarP(&list.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl, csv for --recursive")
+	cmd.Flags().BoolVarP(&list.recursive, "recursive", "r", false, "List the tables of all the datasets in project")
+	cmd.Flags().StringVar(&list.out, "out", "", "File to write the list, instead of stdout")
+	cmd.Flags().IntVarP(&list.workers, "workers", "w", 5, "Number of parallel workers to fetch metadata")
+	return cmd
+}
+
+func (r *listCommand) RunE(cmd *cobra.Command, _ []string) error {
+	project, datasets, err := r.targets()
+	if err != nil {
+		return err
+	}
+
+	if r.tableType != "" && !validType(r.tableType) {
+		return fmt.Errorf("invalid table type: %s", r.tableType)
+	}
+
+	if r.recursive && !cmd.Flags().Changed("format") {
+		r.format = table.FormatCSV
+	}
+
+	provider, err := gcp.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(project)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	if r.recursive {
+		datasets, err = gcp.ListDatasets(ctx, client, project, "")
+		if err != nil {
+			return fmt.Errorf("failed to list datasets of %s: %w", project, err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	isTTY := false
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+	if r.out == "" {
+		isTTY = t.IsTerminalOutput()
+	} else {
+		f, err := os.Create(r.out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", r.out, err)
+		}
+		defer f.Close()
+
+		buf := bufio.NewWriter(f)
+		defer buf.Flush()
+		w = buf
+	}
+
+	printer, err := table.NewWithFormat(w, r.format, isTTY, size)
+	if err != nil {
+		return err
+	}
+
+	headers := []string{"Table", "Type", "Rows", "Size", "Last Modified", "Partitioning", "Clustering"}
+	if r.recursive {
+		headers = append([]string{"Dataset"}, headers...)
+	}
+	printer.AddHeader(headers)
+
+	human := r.format == "" || strings.EqualFold(r.format, table.FormatTable)
+	count := 0
+	for _, dataset := range datasets {
+		tables, err := r.listTables(ctx, client, project, dataset)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] failed to list tables of %s.%s: %s\n", project, dataset, err)
+			continue
+		}
+
+		for _, info := range tables {
+			if r.recursive {
+				printer.AddField(dataset)
+			}
+			addTableInfo(printer, info, human)
+			count++
+		}
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+
+	if r.out != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d tables from %d datasets to %s\n", count, len(datasets), r.out)
+	}
+	return nil
+}
+
+// targets returns the project with the dataset to list, datasets are resolved later for --recursive
+func (r *listCommand) targets() (string, []string, error) {
+	if r.recursive {
+		if r.project == "" {
+			return "", nil, errors.New("--project is required with --recursive")
+		}
+		return r.project, nil, nil
+	}
+
+	if r.dataset == "" {
+		return "", nil, errors.New("either --dataset or --project with --recursive is required")
+	}
+
+	schema, err := names.FromSchemaName(r.dataset)
+	if err != nil {
+		return "", nil, err
+	}
+	return schema.ProjectID, []string{schema.SchemaID}, nil
+}
+
+// listTables fetches the metadata of tables in the dataset, the list is sorted by name
+func (r *listCommand) listTables(ctx context.Context, client *bigquery.Client, project, dataset string) ([]tableInfo, error) {
+	tableNames, err := gcp.ListTables(ctx, client, project, dataset, r.namePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := client.DatasetInProject(project, dataset)
+	jobs := make(chan pool.Job[tableInfo], 20)
+	go func() {
+		for _, name := range tableNames {
+			name := name
+			jobs <- func() pool.JobResult[tableInfo] {
+				md, err := ds.Table(name).Metadata(ctx)
+				return pool.JobResult[tableInfo]{Output: tableInfo{name: name, md: md}, Err: err}
+			}
+		}
+		close(jobs)
+	}()
+
+	var tables []tableInfo
+	for res := range pool.StartPool(r.workers, jobs) {
+		if res.Err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] failed to get metadata of %s.%s.%s: %s\n", project, dataset, res.Output.name, res.Err)
+			continue
+		}
+		if r.tableType != "" && !strings.EqualFold(string(res.Output.md.Type), r.tableType) {
+			continue
+		}
+		tables = append(tables, res.Output)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].name < tables[j].name
+	})
+	return tables, nil
+}
+
+func addTableInfo(printer table.Printer, info tableInfo, human bool) {
+	md := info.md
+	printer.AddField(info.name)
+	printer.AddField(string(md.Type))
+
+	if md.Type == bigquery.ViewTable {
+		printer.AddField("")
+		printer.AddField("")
+	} else {
+		printer.AddField(strconv.FormatUint(md.NumRows, 10))
+		if human {
+			printer.AddField(text.HumanBytes(md.NumBytes))
+		} else {
+			printer.AddField(strconv.FormatInt(md.NumBytes, 10))
+		}
+	}
+
+	printer.AddField(md.LastModifiedTime.Format(time.DateTime))
+	printer.AddField(gcp.Partitioning(md))
+	printer.AddField(gcp.Clustering(md))
+	printer.EndRow()
+}
+
+func validType(typ string) bool {
+	for _, t := range tableTypes {
+		if strings.EqualFold(string(t), typ) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/spf13/cobra"
 	"google.golang.org/api/iterator"
 
+	"github.com/sbchaos/opms/cmd/bq/internal"
 	"github.com/sbchaos/opms/external/gcp"
 	"github.com/sbchaos/opms/lib/config"
 	"github.com/sbchaos/opms/lib/names"
@@ -21,93 +24,247 @@ import (
 type readCommand struct {
 	cfg *config.Config
 
-	name string
+	name      string
+	limit     int
+	columns   []string
+	where     string
+	partition string
+	format    string
+	useQuery  bool
 }
 
-// NewReadCommand initializes command to read number of rows in table
+// NewReadCommand initializes command to read the rows in table
 func NewReadCommand(cfg *config.Config) *cobra.Command {
 	read := &readCommand{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "read",
-		Short:   "Read the rows in table",
-		Example: "opms bq tables read",
-		RunE:    read.RunE,
+		Use:   "read",
+		Short: "Read the rows in table",
+		Long: `Read the rows of a table with tabledata.list, which does not cost a query. A query is
+used for --where, for views and external tables, or when --query is set.`,
+		Example: `opms bq tables read -n proj.dataset.table --limit 10
+opms bq tables read -n proj.dataset.table -p 2024-01-01 -c id,name -o csv
+opms bq tables read -n proj.dataset.table -p 2024-01-01 --where "amount > 10" -o jsonl --limit 0`,
+		RunE: read.RunE,
 	}
 
 	cmd.Flags().StringVarP(&read.name, "name", "n", "", "Table name")
+	cmd.Flags().IntVarP(&read.limit, "limit", "l", 100, "Maximum number of rows to read, 0 for no limit")
+	cmd.Flags().StringSliceVarP(&read.columns, "columns", "c", nil, "Columns to read, comma separated")
+	cmd.Flags().StringVarP(&read.where, "where", "w", "", "Filter condition for the rows, reads with a query")
+	cmd.Flags().StringVarP(&read.partition, "partition", "p", "", "Partition to read, date as 2024-01-01 or partition id as 2024010100")
+	cmd.Flags().StringVarP(&read.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().BoolVar(&read.useQuery, "query", false, "Read with a query instead of tabledata.list")
 	cmd.MarkFlagRequired("name")
 	return cmd
 }
 
 func (r *readCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.limit < 0 {
+		return errors.New("--limit should not be negative")
+	}
+
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
 	provider, err := gcp.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
 	}
 
+	client, err := provider.GetClient(tab.Schema.ProjectID, driveScope)
+	if err != nil {
+		return err
+	}
+
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 	defer cancelFunc()
 
-	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-
-	err = readTable(ctx, provider, r.name, printer)
+	bqTable := client.DatasetInProject(tab.Schema.ProjectID, tab.Schema.SchemaID).Table(tab.TableID)
+	md, err := bqTable.Metadata(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get metadata of %s: %w", tab.String(), err)
 	}
 
-	printer.Render()
-	return nil
-}
-
-func readTable(ctx context.Context, provider *gcp.ClientProvider, tableName string, printer table.Printer) error {
-	tb, err := names.FromTableName(tableName)
+	fields, err := selectFields(md.Schema, r.columns)
 	if err != nil {
 		return err
 	}
 
-	client, err := provider.GetClient(tb.Schema.ProjectID, driveScope)
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
 	if err != nil {
 		return err
 	}
 
-	qr := `SELECT * FROM ` + tableName
-	q := client.Query(qr)
-
-	it, err := q.Read(ctx)
-	if err != nil {
-		return fmt.Errorf("error while reading from bq: %w", err)
+	withRowNum := r.format == "" || strings.EqualFold(r.format, table.FormatTable)
+	headers := internal.Headers(fields)
+	if withRowNum {
+		headers = append([]string{"Row"}, headers...)
 	}
+	printer.AddHeader(headers)
+
+	if r.useQuery || r.where != "" || md.Type != bigquery.RegularTable {
+		query, err := r.buildQuery(tab, md)
+		if err != nil {
+			return err
+		}
+		it, err := client.Query(query).Read(ctx)
+		if err != nil {
+			return fmt.Errorf("error while reading from bq: %w", err)
+		}
+		err = r.readRows(it, fields, nil, printer, withRowNum)
+		if err != nil {
+			return err
+		}
+	} else {
+		// partitions are read with the decorator, table$20240101
+		if r.partition != "" {
+			bqTable = client.DatasetInProject(tab.Schema.ProjectID, tab.Schema.SchemaID).Table(tab.TableID + "$" + partitionID(r.partition))
+		}
 
-	headers := []string{"row"}
-	for _, field := range it.Schema {
-		headers = append(headers, field.Name)
+		it := bqTable.Read(ctx)
+		if r.limit > 0 && r.limit < 10000 {
+			it.PageInfo().MaxSize = r.limit
+		}
+		var indexes []int
+		if len(r.columns) > 0 {
+			indexes = fieldIndexes(md.Schema, fields)
+		}
+		err = r.readRows(it, fields, indexes, printer, withRowNum)
+		if err != nil {
+			return err
+		}
 	}
 
-	rowNum := 1
-	for {
+	return printer.Render()
+}
+
+// readRows writes the rows of iterator, indexes selects the columns from rows of tabledata.list
+func (r *readCommand) readRows(it *bigquery.RowIterator, fields bigquery.Schema, indexes []int, printer table.Printer, withRowNum bool) error {
+	rowNum := 0
+	for r.limit == 0 || rowNum < r.limit {
 		var row []bigquery.Value
-		err = it.Next(&row)
+		err := it.Next(&row)
 		if errors.Is(err, iterator.Done) {
 			break
 		}
 		if err != nil {
-			return err
+			return fmt.Errorf("error while reading rows: %w", err)
 		}
-		printer.AddField(strconv.Itoa(rowNum))
-		decodeRow(row, printer)
+
+		if indexes != nil {
+			selected := make([]bigquery.Value, len(indexes))
+			for i, idx := range indexes {
+				selected[i] = row[idx]
+			}
+			row = selected
+		}
+
 		rowNum++
+		if withRowNum {
+			printer.AddField(strconv.Itoa(rowNum))
+		}
+		internal.AddRow(printer, fields, row)
 	}
 	return nil
 }
 
-func decodeRow(row []bigquery.Value, printer table.Printer) {
-	for _, field := range row {
-		val := fmt.Sprintf("%v", field)
-		printer.AddField(val)
+func (r *readCommand) buildQuery(tab names.Table, md *bigquery.TableMetadata) (string, error) {
+	cols := "*"
+	if len(r.columns) > 0 {
+		quoted := make([]string, len(r.columns))
+		for i, c := range r.columns {
+			quoted[i] = "`" + c + "`"
+		}
+		cols = strings.Join(quoted, ", ")
+	}
+
+	var conditions []string
+	if r.partition != "" {
+		cond, err := partitionCondition(md, r.partition)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, cond)
+	}
+	if r.where != "" {
+		conditions = append(conditions, "("+r.where+")")
+	}
+
+	if md.RequirePartitionFilter && len(conditions) == 0 {
+		return "", fmt.Errorf("table requires a partition filter, provide --partition or a --where on %s",
+			strings.Join(gcp.PartitionFields(md), ", "))
+	}
+
+	query := "SELECT " + cols + " FROM `" + tab.String() + "`"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if r.limit > 0 {
+		query += " LIMIT " + strconv.Itoa(r.limit)
+	}
+	return query, nil
+}
+
+// partitionCondition filters the date of time partitioned table, partition is YYYY-MM-DD or YYYYMMDD
+func partitionCondition(md *bigquery.TableMetadata, partition string) (string, error) {
+	if md.TimePartitioning == nil {
+		return "", errors.New("--partition with a query is supported only for time partitioned tables, use --where")
+	}
+
+	d, err := time.Parse("20060102", partitionID(partition))
+	if err != nil {
+		return "", fmt.Errorf("invalid partition %s, expected date as YYYY-MM-DD", partition)
+	}
+
+	field := gcp.PartitionFields(md)[0]
+	if !strings.HasPrefix(field, "_PARTITION") {
+		field = "`" + field + "`"
+	}
+	return fmt.Sprintf("DATE(%s) = '%s'", field, d.Format(time.DateOnly)), nil
+}
+
+// partitionID returns the id used in partition decorator, 2024-01-01 is 20240101
+func partitionID(partition string) string {
+	return strings.ReplaceAll(partition, "-", "")
+}
+
+// selectFields returns the fields of the columns in order, all the fields when columns are empty
+func selectFields(schema bigquery.Schema, columns []string) (bigquery.Schema, error) {
+	if len(columns) == 0 {
+		return schema, nil
+	}
+
+	fields := make(bigquery.Schema, len(columns))
+	for i, c := range columns {
+		for _, f := range schema {
+			if strings.EqualFold(f.Name, c) {
+				fields[i] = f
+				break
+			}
+		}
+		if fields[i] == nil {
+			return nil, fmt.Errorf("column %s not found in table", c)
+		}
+	}
+	return fields, nil
+}
+
+// fieldIndexes returns the position of fields in schema
+func fieldIndexes(schema bigquery.Schema, fields bigquery.Schema) []int {
+	indexes := make([]int, len(fields))
+	for i, f := range fields {
+		for j, s := range schema {
+			if s == f {
+				indexes[i] = j
+				break
+			}
+		}
 	}
-	printer.EndRow()
+	return indexes
 }
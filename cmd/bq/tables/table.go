@@ -10,10 +10,12 @@ import (
 func NewTableCommand(cfg *config.Config) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "table",
+		Aliases: []string{"tables"},
 		Short:   "Commands that will let the user to operate on tables",
 		Example: "opms bq table [sub-command]",
 	}
 	cmd.AddCommand(
+		NewListCommand(cfg),
 		NewCountCommand(cfg),
 		NewReadCommand(cfg),
 		NewFetchDDLCommand(cfg),
@@ -5,12 +5,16 @@ import (
 
 	"github.com/sbchaos/opms/cmd/airflow"
 	"github.com/sbchaos/opms/cmd/bq"
+	"github.com/sbchaos/opms/cmd/diff"
 	"github.com/sbchaos/opms/cmd/drive"
 	"github.com/sbchaos/opms/cmd/gsheet"
 	"github.com/sbchaos/opms/cmd/mc"
 	"github.com/sbchaos/opms/cmd/optimus"
+	"github.com/sbchaos/opms/cmd/optimus/lineage"
 	"github.com/sbchaos/opms/cmd/oss"
 	"github.com/sbchaos/opms/cmd/profiles"
+	"github.com/sbchaos/opms/cmd/reconcile"
+	"github.com/sbchaos/opms/cmd/translate"
 	"github.com/sbchaos/opms/lib/config"
 )
 
@@ -41,6 +45,10 @@ func New(cfg *config.Config) *cli.Command {
 		drive.NewDriveCommand(cfg),
 		gsheet.NewGsheetsCommand(cfg),
 		airflow.NewAirflowCommand(cfg),
+		diff.NewDiffCommand(cfg),
+		reconcile.NewReconcileCommand(cfg),
+		lineage.NewLineageCommand(cfg),
+		translate.NewTranslateCommand(cfg),
 	)
 
 	return cmd
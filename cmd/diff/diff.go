@@ -0,0 +1,21 @@
+package diff
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// NewDiffCommand initializes commands for comparing resources
+func NewDiffCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   "Commands for comparing resources across maxcompute and bigquery",
+		Example: "opms diff [sub-command]",
+	}
+
+	cmd.AddCommand(
+		NewSchemaCommand(cfg),
+	)
+	return cmd
+}
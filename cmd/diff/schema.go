@@ -0,0 +1,307 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/external/gcp"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/schema"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+const (
+	sourceBQ = "bq"
+	sourceMC = "mc"
+
+	timeout = time.Minute * 10
+)
+
+type tableRef struct {
+	source string
+	table  names.Table
+}
+
+func (t tableRef) String() string {
+	return t.source + ":" + t.table.String()
+}
+
+type schemaCommand struct {
+	cfg *config.Config
+
+	fileName    string
+	mappingJson string
+	typeMapJson string
+	source      string
+	target      string
+	format      string
+
+	ignoreDescriptions bool
+	ignoreOrder        bool
+
+	provider   *gcp.ClientProvider
+	mcProvider *mcc.ClientProvider
+}
+
+// NewSchemaCommand compares the schema of tables
+func NewSchemaCommand(cfg *config.Config) *cobra.Command {
+	sc := &schemaCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "schema [left right]",
+		Short: "Compare schema of tables across bigquery and maxcompute",
+		Long: `Compare the schema of right table with the left table, names are prefixed with bq: or mc:.
+With --filename every line is a left table, the right table is found with the project mapping.
+Types of a bigquery table are mapped with the type mapping before comparing with a maxcompute table.`,
+		Example: `opms diff schema bq:proj.dataset.table mc:proj.schema.table -t type_map.json
+opms diff schema -f tables.txt -m proj_map.json -t type_map.json --format csv`,
+		Args: func(_ *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return errors.New("both left and right tables are required")
+			}
+			return nil
+		},
+		RunE: sc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&sc.fileName, "filename", "f", "", "Filename with list of left tables, - for stdin")
+	cmd.Flags().StringVarP(&sc.mappingJson, "mapping", "m", "", "Project mapping from left to right names")
+	cmd.Flags().StringVarP(&sc.typeMapJson, "type-map", "t", "", "Mapping json of BQ to maxcompute type")
+	cmd.Flags().StringVar(&sc.source, "source", sourceBQ, "Source of the left tables when not prefixed")
+	cmd.Flags().StringVar(&sc.target, "target", sourceMC, "Source of the right tables when not prefixed")
+	cmd.Flags().StringVarP(&sc.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().BoolVar(&sc.ignoreDescriptions, "ignore-descriptions", false, "Do not compare column descriptions")
+	cmd.Flags().BoolVar(&sc.ignoreOrder, "ignore-order", false, "Do not compare the order of columns")
+	return cmd
+}
+
+func (r *schemaCommand) RunE(_ *cobra.Command, args []string) error {
+	if len(args) == 0 && r.fileName == "" {
+		return errors.New("either left and right tables or --filename is required")
+	}
+
+	pairs, err := r.tablePairs(args)
+	if err != nil {
+		return err
+	}
+
+	typeMapping := map[string]string{}
+	if r.typeMapJson != "" {
+		err = cmdutil.ReadJsonFile(r.typeMapJson, os.Stdin, &typeMapping)
+		if err != nil {
+			return err
+		}
+	}
+	upperMapping := make(map[string]string, len(typeMapping))
+	for k, v := range typeMapping {
+		upperMapping[strings.ToUpper(k)] = v
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Left", "Right", "Kind", "Column", "Left Value", "Right Value"})
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	differences := 0
+	for _, pair := range pairs {
+		left, right := pair[0], pair[1]
+		diffs, err := r.compare(ctx, left, right, upperMapping)
+		if err != nil {
+			differences++
+			addDiffRow(printer, left, right, schema.Difference{Kind: "error", Left: err.Error()})
+			continue
+		}
+
+		differences += len(diffs)
+		for _, d := range diffs {
+			addDiffRow(printer, left, right, d)
+		}
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print differences: %w", err)
+	}
+
+	if differences > 0 {
+		return fmt.Errorf("found %d differences", differences)
+	}
+	return nil
+}
+
+func (r *schemaCommand) tablePairs(args []string) ([][2]tableRef, error) {
+	if len(args) == 2 {
+		left, err := parseRef(args[0], r.source)
+		if err != nil {
+			return nil, err
+		}
+		right, err := parseRef(args[1], r.target)
+		if err != nil {
+			return nil, err
+		}
+		return [][2]tableRef{{left, right}}, nil
+	}
+
+	projectMapping := map[string]string{}
+	if r.mappingJson != "" {
+		err := cmdutil.ReadJsonFile(r.mappingJson, os.Stdin, &projectMapping)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lines, err := cmdutil.ReadLines(r.fileName, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs [][2]tableRef
+	for _, line := range lines {
+		left, err := parseRef(line, r.source)
+		if err != nil {
+			return nil, err
+		}
+
+		mapped, err := names.MapName(projectMapping, left.table.String())
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]tableRef{left, {source: r.target, table: mapped}})
+	}
+	return pairs, nil
+}
+
+func (r *schemaCommand) compare(ctx context.Context, left, right tableRef, typeMapping map[string]string) ([]schema.Difference, error) {
+	leftSchema, err := r.loadSchema(ctx, left)
+	if err != nil {
+		return nil, err
+	}
+
+	rightSchema, err := r.loadSchema(ctx, right)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := schema.Options{
+		IgnoreDescriptions:   r.ignoreDescriptions,
+		IgnoreColumnOrdering: r.ignoreOrder,
+	}
+	if left.source == sourceBQ && right.source == sourceMC {
+		opts.TypeMapping = typeMapping
+	}
+	return schema.Compare(leftSchema, rightSchema, opts), nil
+}
+
+func (r *schemaCommand) loadSchema(ctx context.Context, ref tableRef) (schema.Table, error) {
+	if ref.source == sourceMC {
+		return r.loadMCSchema(ref.table)
+	}
+	return r.loadBQSchema(ctx, ref.table)
+}
+
+func (r *schemaCommand) loadBQSchema(ctx context.Context, tab names.Table) (schema.Table, error) {
+	if r.provider == nil {
+		provider, err := gcp.NewClientProvider(r.cfg)
+		if err != nil {
+			return schema.Table{}, err
+		}
+		r.provider = provider
+	}
+
+	client, err := r.provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return schema.Table{}, err
+	}
+
+	md, err := client.DatasetInProject(tab.Schema.ProjectID, tab.Schema.SchemaID).Table(tab.TableID).Metadata(ctx)
+	if err != nil {
+		return schema.Table{}, fmt.Errorf("failed to get metadata for %s: %w", tab.String(), err)
+	}
+
+	st := schema.Table{Name: tab.String(), Partition: gcp.PartitionFields(md)}
+	for _, f := range md.Schema {
+		st.Columns = append(st.Columns, schema.Column{
+			Name:        f.Name,
+			Type:        gcp.FieldType(f),
+			Description: f.Description,
+		})
+	}
+	return st, nil
+}
+
+func (r *schemaCommand) loadMCSchema(tab names.Table) (schema.Table, error) {
+	if r.mcProvider == nil {
+		provider, err := mcc.NewClientProvider(r.cfg)
+		if err != nil {
+			return schema.Table{}, err
+		}
+		r.mcProvider = provider
+	}
+
+	client, err := r.mcProvider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return schema.Table{}, err
+	}
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	tabl := client.Table(tab.TableID)
+	err = tabl.Load()
+	if err != nil {
+		return schema.Table{}, fmt.Errorf("failed to load table %s: %w", tab.String(), err)
+	}
+
+	st := schema.Table{Name: tab.String()}
+	for _, c := range tabl.Schema().Columns {
+		st.Columns = append(st.Columns, schema.Column{Name: c.Name, Type: c.Type.String(), Description: c.Comment})
+	}
+	for _, c := range tabl.PartitionColumns() {
+		st.Columns = append(st.Columns, schema.Column{Name: c.Name, Type: c.Type.String(), Description: c.Comment})
+		st.Partition = append(st.Partition, c.Name)
+	}
+	return st, nil
+}
+
+func parseRef(name string, defaultSource string) (tableRef, error) {
+	source := defaultSource
+	if prefix, rest, found := strings.Cut(name, ":"); found {
+		source = strings.ToLower(prefix)
+		name = rest
+	}
+
+	if source != sourceBQ && source != sourceMC {
+		return tableRef{}, fmt.Errorf("unknown source %s for %s, use bq: or mc:", source, name)
+	}
+
+	tab, err := names.FromTableName(name)
+	if err != nil {
+		return tableRef{}, err
+	}
+	return tableRef{source: source, table: tab}, nil
+}
+
+func addDiffRow(printer table.Printer, left, right tableRef, d schema.Difference) {
+	printer.AddField(left.String())
+	printer.AddField(right.String())
+	printer.AddField(d.Kind)
+	printer.AddField(d.Column)
+	printer.AddField(d.Left)
+	printer.AddField(d.Right)
+	printer.EndRow()
+}
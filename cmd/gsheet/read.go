@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -27,6 +28,7 @@ type readCommand struct {
 
 	noFormat bool
 	proj     string
+	format   string
 }
 
 func NewReadCommand(cfg *config.Config) *cobra.Command {
@@ -44,6 +46,7 @@ func NewReadCommand(cfg *config.Config) *cobra.Command {
 	cmd.Flags().StringVarP(&read.sheetRange, "sheetRange", "r", "", "Sheet range")
 	cmd.Flags().StringVarP(&read.proj, "project", "p", "", "Project")
 	cmd.Flags().BoolVarP(&read.noFormat, "no-format", "f", false, "Do not get formatted data")
+	cmd.Flags().StringVarP(&read.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
 
 	return cmd
 }
@@ -76,21 +79,32 @@ func (r *readCommand) RunE(_ *cobra.Command, _ []string) error {
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
-	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
 
 	content, err := gsheet.GetSheetContent(client, sheetID, r.sheetRange)
 	if err != nil {
 		return err
 	}
 
-	headers := []string{"num"}
+	// row numbers are only for display, other formats are used as input to other commands
+	withRowNum := r.format == "" || strings.EqualFold(r.format, table.FormatTable)
+
+	var headers []string
+	if withRowNum {
+		headers = append(headers, "num")
+	}
 	for _, r1 := range content[0] {
 		headers = append(headers, fmt.Sprintf("%v", r1))
 	}
 
 	printer.AddHeader(headers)
 	for i, row := range content[1:] {
-		printer.AddField(strconv.Itoa(i + 1))
+		if withRowNum {
+			printer.AddField(strconv.Itoa(i + 1))
+		}
 		for _, r1 := range row {
 			printer.AddField(fmt.Sprintf("%s", r1))
 		}
@@ -0,0 +1,101 @@
+package acl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+// NewACLCommand initializes command for permissions
+func NewACLCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "acl",
+		Short:   "Inspect the permissions on a project",
+		Example: "opms mc acl [sub-command]",
+	}
+	cmd.AddCommand(
+		NewShowCommand(cfg),
+		NewWhoamiCommand(cfg),
+	)
+	return cmd
+}
+
+// runQuery runs a security query like show grants in the project
+func runQuery(client *odps.Odps, proj, query string) (string, error) {
+	sm := client.Project(proj).SecurityManager()
+	out, err := sm.RunQuery(query, false, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to run %q: %w", query, err)
+	}
+	return out, nil
+}
+
+// roleGrants returns the grants of the roles from describe role, roles which already have grants
+// in known are skipped. The grants in the description have no principal so the role is set as the principal
+func roleGrants(client *odps.Odps, proj string, roles []string, known []internal.Grant) ([]internal.Grant, error) {
+	listed := map[string]bool{}
+	for _, g := range known {
+		if g.Kind == "role" {
+			listed[g.Principal] = true
+		}
+	}
+
+	var grants []internal.Grant
+	for _, role := range roles {
+		if listed[role] {
+			continue
+		}
+
+		out, err := runQuery(client, proj, fmt.Sprintf("describe role %s;", role))
+		if err != nil {
+			return nil, err
+		}
+
+		_, described := internal.ParseGrants(out)
+		for i := range described {
+			if described[i].Principal == "" {
+				described[i].Principal, described[i].Kind = role, "role"
+			}
+		}
+		grants = append(grants, described...)
+	}
+	return grants, nil
+}
+
+func printGrants(format string, grants []internal.Grant) error {
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+
+	printer.AddHeader([]string{"Principal", "Type", "Authorization", "Effect", "Object", "Privileges"})
+	for _, g := range grants {
+		effect := "allow"
+		if !g.Allow {
+			effect = "deny"
+		}
+		if g.Conditional {
+			effect += " with condition"
+		}
+
+		printer.AddField(g.Principal)
+		printer.AddField(g.Kind)
+		printer.AddField(g.Authorization)
+		printer.AddField(effect)
+		printer.AddField(g.Object)
+		printer.AddField(strings.Join(g.Privileges, ", "))
+		printer.EndRow()
+	}
+	return printer.Render()
+}
@@ -0,0 +1,163 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+type showCommand struct {
+	cfg *config.Config
+
+	tableName string
+	project   string
+	users     bool
+	check     bool
+	format    string
+}
+
+func NewShowCommand(cfg *config.Config) *cobra.Command {
+	show := &showCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "show [user action table]",
+		Short: "Show who has access to a table",
+		Long: `Show the principals and their privileges on a table from show acl, both the ACL and
+the policy grants are listed. With --users, list the users added to the project.
+With --check, answer yes or no if the user can do the action on the table,
+the grants of user and the descriptions of its roles are considered. Conditions of
+the policy grants are not evaluated, a conditional allow does not give access.`,
+		Example: `opms mc acl show --table proj.schema.table
+opms mc acl show --users -p proj
+opms mc acl show --check 'ALIYUN$user@example.com' select proj.schema.table`,
+		RunE: show.RunE,
+	}
+
+	cmd.Flags().StringVarP(&show.tableName, "table", "t", "", "Table name")
+	cmd.Flags().StringVarP(&show.project, "project", "p", "", "Project for --users")
+	cmd.Flags().BoolVar(&show.users, "users", false, "List the users of the project")
+	cmd.Flags().BoolVar(&show.check, "check", false, "Check if a user can do an action on a table")
+	cmd.Flags().StringVarP(&show.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	return cmd
+}
+
+func (r *showCommand) RunE(_ *cobra.Command, args []string) error {
+	if r.check {
+		if len(args) != 3 {
+			return errors.New("--check requires user, action and table")
+		}
+		return r.checkAccess(args[0], args[1], args[2])
+	}
+	if r.users {
+		return r.listUsers()
+	}
+
+	if r.tableName == "" {
+		return errors.New("--table is required")
+	}
+
+	tab, err := names.FromTableName(r.tableName)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return err
+	}
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	query := fmt.Sprintf("show acl for %s.%s on type table;", tab.Schema.SchemaID, tab.TableID)
+	out, err := runQuery(client, tab.Schema.ProjectID, query)
+	if err != nil {
+		return err
+	}
+
+	_, grants := internal.ParseGrants(out)
+	return printGrants(r.format, grants)
+}
+
+func (r *showCommand) checkAccess(user, action, tableName string) error {
+	tab, err := names.FromTableName(tableName)
+	if err != nil {
+		return err
+	}
+
+	proj := tab.Schema.ProjectID
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(proj)
+	if err != nil {
+		return err
+	}
+
+	out, err := runQuery(client, proj, fmt.Sprintf("show grants for %s;", user))
+	if err != nil {
+		return err
+	}
+
+	roles, grants := internal.ParseGrants(out)
+	// grants of a role are not always included in the output for the user
+	described, err := roleGrants(client, proj, roles, grants)
+	if err != nil {
+		return err
+	}
+	grants = append(grants, described...)
+
+	object := internal.TableObject(proj, tab.Schema.SchemaID, tab.TableID)
+	if internal.IsAllowed(grants, action, object) {
+		fmt.Println("yes")
+		return nil
+	}
+
+	fmt.Println("no")
+	return fmt.Errorf("%s cannot %s %s", user, action, tab.String())
+}
+
+func (r *showCommand) listUsers() error {
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+
+	out, err := runQuery(client, client.DefaultProjectName(), "list users;")
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"User"})
+	for _, user := range internal.ParseUsers(out) {
+		printer.AddField(user)
+		printer.EndRow()
+	}
+	return printer.Render()
+}
@@ -0,0 +1,76 @@
+package acl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+)
+
+type whoamiCommand struct {
+	cfg *config.Config
+
+	project string
+	format  string
+}
+
+func NewWhoamiCommand(cfg *config.Config) *cobra.Command {
+	whoami := &whoamiCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "whoami",
+		Short:   "Show the current user with its roles and grants",
+		Long:    "Show the current user with its roles, the grants of user and the grants in the descriptions of its roles",
+		Example: "opms mc acl whoami -p proj",
+		RunE:    whoami.RunE,
+	}
+
+	cmd.Flags().StringVarP(&whoami.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&whoami.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	return cmd
+}
+
+func (r *whoamiCommand) RunE(_ *cobra.Command, _ []string) error {
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	out, err := runQuery(client, proj, "whoami;")
+	if err != nil {
+		return err
+	}
+	// only the grants are written to stdout for the structured formats, so that the output can be parsed
+	info := os.Stdout
+	if r.format != table.FormatTable {
+		info = os.Stderr
+	}
+	fmt.Fprintln(info, strings.TrimSpace(out))
+
+	grantsOut, err := runQuery(client, proj, "show grants;")
+	if err != nil {
+		return err
+	}
+
+	roles, grants := internal.ParseGrants(grantsOut)
+	described, err := roleGrants(client, proj, roles, grants)
+	if err != nil {
+		return err
+	}
+	grants = append(grants, described...)
+
+	fmt.Fprintf(info, "Roles: %s\n\n", strings.Join(roles, ", "))
+	return printGrants(r.format, grants)
+}
@@ -0,0 +1,216 @@
+package function
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+type deployCommand struct {
+	cfg *config.Config
+
+	fileName string
+	prune    bool
+	dryRun   bool
+	yes      bool
+}
+
+func NewDeployCommand(cfg *config.Config) *cobra.Command {
+	deploy := &deployCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "deploy",
+		Short: "Create, update or drop functions to match a file",
+		Long: `Reconcile the functions of a project with the functions declared in a yaml file:
+
+project: proj
+schema: default
+functions:
+  - name: parse_json
+    class_path: com.example.ParseJson
+    resources: [udfs.jar]
+
+The plan is shown before any change, every resource used by the functions must exist.
+Functions not in the file are dropped only with --prune, which is refused when the file has no functions.`,
+		Example: "opms mc udf deploy -f functions.yaml --dry-run",
+		RunE:    deploy.RunE,
+	}
+
+	cmd.Flags().StringVarP(&deploy.fileName, "filename", "f", "", "Yaml file with the functions")
+	cmd.Flags().BoolVar(&deploy.prune, "prune", false, "Drop the functions which are not in the file")
+	cmd.Flags().BoolVar(&deploy.dryRun, "dry-run", false, "Only show the plan")
+	cmd.Flags().BoolVarP(&deploy.yes, "yes", "y", false, "Apply without asking for confirmation")
+	cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+func (r *deployCommand) RunE(_ *cobra.Command, _ []string) error {
+	spec, err := internal.ReadDeployFile(r.fileName)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(spec.Project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	schema := "default"
+	if spec.Schema != "" {
+		schema = spec.Schema
+	}
+	client.SetCurrentSchemaName(schema)
+
+	missing, err := missingResources(client, spec.Functions)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("resources not found in %s.%s: %s", proj, schema, strings.Join(missing, ", "))
+	}
+
+	existing, err := listFunctions(client, "")
+	if err != nil {
+		return err
+	}
+
+	current := make([]internal.FunctionSpec, len(existing))
+	for i, f := range existing {
+		current[i] = internal.FunctionSpec{Name: f.Name(), ClassPath: f.ClassPath(), Resources: resourceNames(f)}
+	}
+
+	steps, err := internal.PlanDeploy(spec.Functions, current, r.prune)
+	if err != nil {
+		return err
+	}
+	printPlan(steps)
+
+	changes := 0
+	for _, s := range steps {
+		if s.Action != internal.ActionUnchanged {
+			changes++
+		}
+	}
+	if changes == 0 {
+		fmt.Println("Functions are up to date")
+		return nil
+	}
+	if r.dryRun {
+		return nil
+	}
+	if !r.yes && !internal.Confirm(fmt.Sprintf("Apply %d changes to %s.%s? [y/N] ", changes, proj, schema)) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	functions := odps.NewFunctions(client)
+
+	var failed []string
+	var entries []internal.AuditEntry
+	for _, s := range steps {
+		if s.Action == internal.ActionUnchanged {
+			continue
+		}
+
+		fun := odps.NewFunctionBuilder().
+			Name(s.Spec.Name).
+			SchemaName(schema).
+			ClassPath(s.Spec.ClassPath).
+			Resources(s.Spec.Resources).
+			Build()
+
+		switch s.Action {
+		case internal.ActionCreate:
+			err = functions.Create(proj, schema, fun)
+		case internal.ActionUpdate:
+			err = functions.Update(proj, schema, fun)
+		case internal.ActionDrop:
+			err = functions.Delete(s.Spec.Name)
+		}
+
+		entry := internal.AuditEntry{
+			Action:  s.Action + "_function",
+			Target:  proj + "." + schema + "." + s.Spec.Name,
+			Details: s.Change,
+			Status:  "success",
+		}
+		if err != nil {
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			failed = append(failed, fmt.Sprintf("%s %s: %s", s.Action, s.Spec.Name, err))
+		} else {
+			fmt.Printf("%s %s: done\n", s.Action, s.Spec.Name)
+		}
+		entries = append(entries, entry)
+	}
+
+	err = internal.WriteAudit(r.cfg, entries...)
+	if err != nil {
+		fmt.Printf("failed to write audit log: %s\n", err)
+	}
+
+	if len(failed) > 0 {
+		return errors.New("failed to deploy functions:\n" + strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+func printPlan(steps []internal.DeployStep) {
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Action", "Function", "Class Path", "Resources", "Change"})
+	for _, s := range steps {
+		printer.AddField(s.Action)
+		printer.AddField(s.Spec.Name)
+		printer.AddField(s.Spec.ClassPath)
+		printer.AddField(strings.Join(s.Spec.Resources, ","))
+		printer.AddField(s.Change)
+		printer.EndRow()
+	}
+	printer.Render()
+}
+
+// missingResources returns the resources used by the functions which do not exist in the project
+func missingResources(client *odps.Odps, functions []internal.FunctionSpec) ([]string, error) {
+	resources := odps.NewResources(client)
+
+	checked := map[string]bool{}
+	var missing []string
+	for _, f := range functions {
+		for _, name := range f.Resources {
+			if checked[name] {
+				continue
+			}
+			checked[name] = true
+
+			exists, err := resources.Get(name).Exist()
+			if err != nil {
+				return nil, fmt.Errorf("failed to check resource %s: %w", name, err)
+			}
+			if !exists {
+				missing = append(missing, name)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
@@ -36,7 +36,12 @@ func NewDropCommand(cfg *config.Config) *cobra.Command {
 }
 
 func (r *dropCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
 	if err != nil {
 		return err
 	}
@@ -45,13 +50,6 @@ func (r *dropCommand) RunE(_ *cobra.Command, _ []string) error {
 	if r.schema != "" {
 		schema = r.schema
 	}
-
-	proj := client.DefaultProjectName()
-	if r.project != "" {
-		proj = r.project
-	}
-
-	client.SetDefaultProjectName(proj)
 	client.SetCurrentSchemaName(schema)
 
 	functions := odps.NewFunctions(client)
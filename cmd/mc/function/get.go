@@ -39,7 +39,12 @@ func NewGetCommand(cfg *config.Config) *cobra.Command {
 }
 
 func (r *getCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
 	if err != nil {
 		return err
 	}
@@ -48,13 +53,6 @@ func (r *getCommand) RunE(_ *cobra.Command, _ []string) error {
 	if r.schema != "" {
 		schema = r.schema
 	}
-
-	proj := client.DefaultProjectName()
-	if r.project != "" {
-		proj = r.project
-	}
-
-	client.SetDefaultProjectName(proj)
 	client.SetCurrentSchemaName(schema)
 
 	if r.name == "" {
@@ -0,0 +1,125 @@
+package function
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+type listCommand struct {
+	cfg *config.Config
+
+	project string
+	schema  string
+
+	prefix string
+	format string
+}
+
+func NewListCommand(cfg *config.Config) *cobra.Command {
+	list := &listCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the functions",
+		Example: "opms mc udf list -n parse_",
+		RunE:    list.RunE,
+	}
+
+	cmd.Flags().StringVarP(&list.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&list.schema, "schema", "s", "", "Schema")
+
+	cmd.Flags().StringVarP(&list.prefix, "prefix", "n", "", "Function name prefix")
+	cmd.Flags().StringVarP(&list.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	return cmd
+}
+
+func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+
+	schema := "default"
+	if r.schema != "" {
+		schema = r.schema
+	}
+	client.SetCurrentSchemaName(schema)
+
+	functions, err := listFunctions(client, r.prefix)
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+
+	printer.AddHeader([]string{"Name", "Class Path", "Resources", "Owner", "Created"})
+	for _, f := range functions {
+		printer.AddField(f.Name())
+		printer.AddField(f.ClassPath())
+		printer.AddField(strings.Join(resourceNames(f), ","))
+		printer.AddField(f.Owner())
+		printer.AddField(f.CreationTime().Format(time.DateTime))
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print functions: %w", err)
+	}
+	return nil
+}
+
+// listFunctions returns the functions with name starting with prefix, sorted by name
+func listFunctions(client *odps.Odps, prefix string) ([]*odps.Function, error) {
+	var functions []*odps.Function
+	var errs []string
+
+	odps.NewFunctions(client).List(func(f *odps.Function, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		if strings.HasPrefix(strings.ToLower(f.Name()), strings.ToLower(prefix)) {
+			functions = append(functions, f)
+		}
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to list functions: %s", strings.Join(errs, ", "))
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name() < functions[j].Name()
+	})
+	return functions, nil
+}
+
+// resourceNames returns the names of resources without the project and schema path
+func resourceNames(f *odps.Function) []string {
+	var res []string
+	for _, r := range f.Resources() {
+		res = append(res, r[strings.LastIndex(r, "/")+1:])
+	}
+	return res
+}
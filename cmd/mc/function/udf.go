@@ -18,6 +18,8 @@ func NewUDFCommand(cfg *config.Config) *cobra.Command {
 		NewGetCommand(cfg),
 		NewDropCommand(cfg),
 		NewUpdateCommand(cfg),
+		NewListCommand(cfg),
+		NewDeployCommand(cfg),
 	)
 	return cmd
 }
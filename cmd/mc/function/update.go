@@ -42,22 +42,21 @@ func NewUpdateCommand(cfg *config.Config) *cobra.Command {
 }
 
 func (r *updateCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	provider, err := mcc.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
 	}
 
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
 	schema := "default"
 	if r.schema != "" {
 		schema = r.schema
 	}
-
-	proj := client.DefaultProjectName()
-	if r.project != "" {
-		proj = r.project
-	}
-
-	client.SetDefaultProjectName(proj)
 	client.SetCurrentSchemaName(schema)
 
 	fun := odps.
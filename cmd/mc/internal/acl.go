@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// grantLine matches A or D for allow and deny, followed by C when the grant has a condition
+var grantLine = regexp.MustCompile(`^([AD])(C?)\s+(\S+?):\s*(.*)$`)
+
+const authorizationPrefix = "Authorization Type:"
+
+// Grant is a privilege on an object from the output of the security queries
+type Grant struct {
+	Principal string
+	Kind      string
+	// Authorization is ACL or Policy, empty when the output has no authorization type
+	Authorization string
+	Allow         bool
+	Conditional   bool
+	Object        string
+	Privileges    []string
+}
+
+// ParseGrants reads the text output of show grants, show acl and describe role, the principal
+// of a grant is given by the header line like [user/ALIYUN$name] or [role/name] above it.
+// The roles are listed below the [roles] header, members of a role below [users] are skipped.
+//
+//	[roles]
+//	dev
+//
+//	Authorization Type: ACL
+//	[user/ALIYUN$user@example.com]
+//	A       projects/proj/tables/sales: Describe | Select
+//	AC      projects/proj/tables/test*: Describe
+func ParseGrants(out string) (roles []string, grants []Grant) {
+	kind, principal, auth := "", "", ""
+	section := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			section = ""
+			continue
+		}
+
+		if strings.HasPrefix(line, authorizationPrefix) {
+			auth = strings.TrimSpace(strings.TrimPrefix(line, authorizationPrefix))
+			section, kind, principal = "", "", ""
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			header := strings.Trim(line, "[]")
+			if strings.EqualFold(header, "roles") || strings.EqualFold(header, "users") {
+				section, kind, principal = strings.ToLower(header), "", ""
+				continue
+			}
+			section = ""
+			kind, principal, _ = strings.Cut(header, "/")
+			continue
+		}
+
+		switch section {
+		case "roles":
+			roles = append(roles, strings.FieldsFunc(line, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})...)
+			continue
+		case "users":
+			continue
+		}
+
+		m := grantLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		var privileges []string
+		for _, p := range strings.Split(m[4], "|") {
+			if p = strings.TrimSpace(p); p != "" {
+				privileges = append(privileges, p)
+			}
+		}
+		grants = append(grants, Grant{
+			Principal:     principal,
+			Kind:          kind,
+			Authorization: auth,
+			Allow:         m[1] == "A",
+			Conditional:   m[2] == "C",
+			Object:        m[3],
+			Privileges:    privileges,
+		})
+	}
+	return roles, grants
+}
+
+// ParseUsers reads the output of list users, one user on each line
+func ParseUsers(out string) []string {
+	var users []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			users = append(users, line)
+		}
+	}
+	return users
+}
+
+// TableObject returns the object path of a table as used in the grants
+func TableObject(project, schema, table string) string {
+	if schema == "" || schema == "default" {
+		return "projects/" + project + "/tables/" + table
+	}
+	return "projects/" + project + "/schemas/" + schema + "/tables/" + table
+}
+
+// IsAllowed checks if the grants allow the action on the object, a deny takes precedence.
+// Conditions are not evaluated, so a conditional allow is not counted while a conditional deny is.
+func IsAllowed(grants []Grant, action, object string) bool {
+	allowed := false
+	for _, g := range grants {
+		if !matchObject(g.Object, object) || !hasPrivilege(g.Privileges, action) {
+			continue
+		}
+		if !g.Allow {
+			return false
+		}
+		if !g.Conditional {
+			allowed = true
+		}
+	}
+	return allowed
+}
+
+func matchObject(pattern, object string) bool {
+	pattern = strings.ToLower(pattern)
+	object = strings.ToLower(object)
+	if pattern == object {
+		return true
+	}
+
+	// grants on schema tables are also shown without the default schema
+	object = strings.Replace(object, "/schemas/default/", "/", 1)
+	ok, _ := path.Match(pattern, object)
+	return ok
+}
+
+func hasPrivilege(privileges []string, action string) bool {
+	for _, p := range privileges {
+		if strings.EqualFold(p, action) || strings.EqualFold(p, "All") || p == "*" {
+			return true
+		}
+	}
+	return false
+}
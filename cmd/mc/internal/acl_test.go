@@ -0,0 +1,131 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+const showGrants = `[roles]
+dev, analyst
+
+Authorization Type: ACL
+[role/dev]
+A       projects/sale_detail/tables/alipay: Describe | Select
+[user/ALIYUN$odps_test_user@aliyun.com]
+A       projects/sale_detail: CreateTable | CreateInstance | CreateFunction | List
+A       projects/sale_detail/tables/alipay: Describe | Select
+
+Authorization Type: Policy
+[role/dev]
+AC      projects/test/tables/test*: Describe
+DC      projects/test/tables/alifinance*: Select
+[user/ALIYUN$odps_test_user@aliyun.com]
+A       projects/test/tables/alipay: All
+D       projects/test/tables/alipay: Update
+`
+
+const showACL = `Authorization Type: ACL
+[user/RAM$main@example.com:etl]
+A       projects/proj/schemas/staging/tables/orders: Select | Describe
+[role/reader]
+A       projects/proj/schemas/staging/tables/*: Select
+`
+
+const describeRole = `[users]
+ALIYUN$odps_test_user@aliyun.com
+RAM$main@example.com:etl
+
+Authorization Type: Policy
+A       projects/proj/tables/*: Select | Describe
+D       projects/proj/tables/secret_*: *
+`
+
+func TestParseGrants(t *testing.T) {
+	t.Run("reads roles and grants of show grants", func(t *testing.T) {
+		roles, grants := internal.ParseGrants(showGrants)
+
+		assert.Equal(t, []string{"dev", "analyst"}, roles)
+		assert.Equal(t, []internal.Grant{
+			{Principal: "dev", Kind: "role", Authorization: "ACL", Allow: true, Object: "projects/sale_detail/tables/alipay", Privileges: []string{"Describe", "Select"}},
+			{Principal: "ALIYUN$odps_test_user@aliyun.com", Kind: "user", Authorization: "ACL", Allow: true, Object: "projects/sale_detail", Privileges: []string{"CreateTable", "CreateInstance", "CreateFunction", "List"}},
+			{Principal: "ALIYUN$odps_test_user@aliyun.com", Kind: "user", Authorization: "ACL", Allow: true, Object: "projects/sale_detail/tables/alipay", Privileges: []string{"Describe", "Select"}},
+			{Principal: "dev", Kind: "role", Authorization: "Policy", Allow: true, Conditional: true, Object: "projects/test/tables/test*", Privileges: []string{"Describe"}},
+			{Principal: "dev", Kind: "role", Authorization: "Policy", Allow: false, Conditional: true, Object: "projects/test/tables/alifinance*", Privileges: []string{"Select"}},
+			{Principal: "ALIYUN$odps_test_user@aliyun.com", Kind: "user", Authorization: "Policy", Allow: true, Object: "projects/test/tables/alipay", Privileges: []string{"All"}},
+			{Principal: "ALIYUN$odps_test_user@aliyun.com", Kind: "user", Authorization: "Policy", Allow: false, Object: "projects/test/tables/alipay", Privileges: []string{"Update"}},
+		}, grants)
+	})
+	t.Run("reads principals of show acl", func(t *testing.T) {
+		roles, grants := internal.ParseGrants(showACL)
+
+		assert.Empty(t, roles)
+		assert.Len(t, grants, 2)
+		assert.Equal(t, "RAM$main@example.com:etl", grants[0].Principal)
+		assert.Equal(t, "user", grants[0].Kind)
+		assert.Equal(t, "reader", grants[1].Principal)
+		assert.Equal(t, "projects/proj/schemas/staging/tables/*", grants[1].Object)
+	})
+	t.Run("skips the members of describe role", func(t *testing.T) {
+		roles, grants := internal.ParseGrants(describeRole)
+
+		assert.Empty(t, roles)
+		assert.Equal(t, []internal.Grant{
+			{Authorization: "Policy", Allow: true, Object: "projects/proj/tables/*", Privileges: []string{"Select", "Describe"}},
+			{Authorization: "Policy", Allow: false, Object: "projects/proj/tables/secret_*", Privileges: []string{"*"}},
+		}, grants)
+	})
+	t.Run("returns nothing for empty output", func(t *testing.T) {
+		roles, grants := internal.ParseGrants("")
+
+		assert.Empty(t, roles)
+		assert.Empty(t, grants)
+	})
+}
+
+func TestParseUsers(t *testing.T) {
+	users := internal.ParseUsers("ALIYUN$odps_test_user@aliyun.com\n  RAM$main@example.com:etl \n\n")
+	assert.Equal(t, []string{"ALIYUN$odps_test_user@aliyun.com", "RAM$main@example.com:etl"}, users)
+}
+
+func TestTableObject(t *testing.T) {
+	assert.Equal(t, "projects/proj/tables/orders", internal.TableObject("proj", "default", "orders"))
+	assert.Equal(t, "projects/proj/tables/orders", internal.TableObject("proj", "", "orders"))
+	assert.Equal(t, "projects/proj/schemas/staging/tables/orders", internal.TableObject("proj", "staging", "orders"))
+}
+
+func TestIsAllowed(t *testing.T) {
+	_, userGrants := internal.ParseGrants(showGrants)
+	_, aclGrants := internal.ParseGrants(showACL)
+	_, roleGrants := internal.ParseGrants(describeRole)
+
+	tests := []struct {
+		name     string
+		grants   []internal.Grant
+		action   string
+		object   string
+		expected bool
+	}{
+		{name: "exact object", grants: userGrants, action: "Select", object: "projects/sale_detail/tables/alipay", expected: true},
+		{name: "action is case insensitive", grants: userGrants, action: "select", object: "projects/sale_detail/tables/alipay", expected: true},
+		{name: "object is case insensitive", grants: userGrants, action: "Select", object: "projects/Sale_Detail/tables/AliPay", expected: true},
+		{name: "missing privilege", grants: userGrants, action: "Drop", object: "projects/sale_detail/tables/alipay", expected: false},
+		{name: "grant on project is not on its tables", grants: userGrants, action: "List", object: "projects/sale_detail/tables/alipay", expected: false},
+		{name: "all privileges", grants: userGrants, action: "Select", object: "projects/test/tables/alipay", expected: true},
+		{name: "deny takes precedence over all", grants: userGrants, action: "Update", object: "projects/test/tables/alipay", expected: false},
+		{name: "conditional allow is not counted", grants: userGrants, action: "Describe", object: "projects/test/tables/test_orders", expected: false},
+		{name: "wildcard in schema", grants: aclGrants, action: "Select", object: "projects/proj/schemas/staging/tables/payments", expected: true},
+		{name: "wildcard does not cross schemas", grants: aclGrants, action: "Select", object: "projects/proj/schemas/raw/tables/payments", expected: false},
+		{name: "default schema matches table without schema", grants: roleGrants, action: "Describe", object: "projects/proj/schemas/default/tables/orders", expected: true},
+		{name: "deny with star privilege", grants: roleGrants, action: "Select", object: "projects/proj/tables/secret_keys", expected: false},
+		{name: "no grants", grants: nil, action: "Select", object: "projects/proj/tables/orders", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, internal.IsAllowed(tt.grants, tt.action, tt.object))
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/decimal128"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	pqfile "github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/schema"
+)
+
+// ArrowSchema maps the types of the columns in headers to arrow, complex types are kept as json strings.
+// DATETIME and TIMESTAMP are in UTC, TIMESTAMP_NTZ has no timezone, times are in microseconds
+// which is the unit most readers of parquet support.
+func ArrowSchema(cols []tableschema.Column, headers []string) (*arrow.Schema, error) {
+	byName := map[string]tableschema.Column{}
+	for _, c := range cols {
+		byName[strings.ToLower(c.Name)] = c
+	}
+
+	fields := make([]arrow.Field, len(headers))
+	for i, h := range headers {
+		c, ok := byName[strings.ToLower(h)]
+		if !ok {
+			return nil, fmt.Errorf("column %s is not present in the table", h)
+		}
+
+		var typ arrow.DataType = arrow.BinaryTypes.String
+		switch c.Type.ID() {
+		case datatype.BOOLEAN:
+			typ = arrow.FixedWidthTypes.Boolean
+		case datatype.TINYINT, datatype.SMALLINT, datatype.INT:
+			typ = arrow.PrimitiveTypes.Int32
+		case datatype.BIGINT:
+			typ = arrow.PrimitiveTypes.Int64
+		case datatype.FLOAT:
+			typ = arrow.PrimitiveTypes.Float32
+		case datatype.DOUBLE:
+			typ = arrow.PrimitiveTypes.Float64
+		case datatype.DECIMAL:
+			// decimal of the 1.0 types has no precision, it is kept as text
+			dt, _ := c.Type.(datatype.DecimalType)
+			if dt.Precision > 0 {
+				typ = &arrow.Decimal128Type{Precision: dt.Precision, Scale: dt.Scale}
+			}
+		case datatype.BINARY:
+			typ = arrow.BinaryTypes.Binary
+		case datatype.DATE:
+			typ = arrow.FixedWidthTypes.Date32
+		case datatype.DATETIME, datatype.TIMESTAMP:
+			typ = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+		case datatype.TIMESTAMP_NTZ:
+			typ = &arrow.TimestampType{Unit: arrow.Microsecond}
+		}
+		fields[i] = arrow.Field{Name: h, Type: typ, Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// RecordBuilder collects the records of tunnel in arrow arrays of the schema from ArrowSchema
+type RecordBuilder struct {
+	builder   *array.RecordBuilder
+	formatter Formatter
+	rows      int
+}
+
+func NewRecordBuilder(schema *arrow.Schema, formatter Formatter) *RecordBuilder {
+	return &RecordBuilder{
+		builder:   array.NewRecordBuilder(memory.DefaultAllocator, schema),
+		formatter: formatter,
+	}
+}
+
+// Append adds a record, values are in the order of the columns of schema
+func (b *RecordBuilder) Append(record data.Record) error {
+	fields := b.builder.Fields()
+	if len(record) != len(fields) {
+		return fmt.Errorf("record has %d values, expected %d", len(record), len(fields))
+	}
+
+	for i, d := range record {
+		err := b.appendValue(fields[i], d)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", b.builder.Schema().Field(i).Name, err)
+		}
+	}
+	b.rows++
+	return nil
+}
+
+// Rows is the number of rows appended since the last record
+func (b *RecordBuilder) Rows() int {
+	return b.rows
+}
+
+// NewRecord returns the appended rows and resets the builder, the record is released by the caller
+func (b *RecordBuilder) NewRecord() arrow.Record {
+	b.rows = 0
+	return b.builder.NewRecord()
+}
+
+func (b *RecordBuilder) Release() {
+	b.builder.Release()
+}
+
+func (b *RecordBuilder) appendValue(field array.Builder, d data.Data) error {
+	if isNullData(d) {
+		field.AppendNull()
+		return nil
+	}
+
+	switch fb := field.(type) {
+	case *array.BooleanBuilder:
+		if v, ok := d.(*data.Bool); ok {
+			fb.Append(bool(*v))
+			return nil
+		}
+	case *array.Int32Builder:
+		switch v := d.(type) {
+		case *data.TinyInt:
+			fb.Append(int32(*v))
+			return nil
+		case *data.SmallInt:
+			fb.Append(int32(*v))
+			return nil
+		case *data.Int:
+			fb.Append(int32(*v))
+			return nil
+		}
+	case *array.Int64Builder:
+		if v, ok := d.(*data.BigInt); ok {
+			fb.Append(int64(*v))
+			return nil
+		}
+	case *array.Float32Builder:
+		if v, ok := d.(*data.Float); ok {
+			fb.Append(float32(*v))
+			return nil
+		}
+	case *array.Float64Builder:
+		if v, ok := d.(*data.Double); ok {
+			fb.Append(float64(*v))
+			return nil
+		}
+	case *array.Decimal128Builder:
+		if v, ok := d.(*data.Decimal); ok {
+			typ := fb.Type().(*arrow.Decimal128Type)
+			n, err := decimal128.FromString(v.Value(), typ.Precision, typ.Scale)
+			if err != nil {
+				return err
+			}
+			fb.Append(n)
+			return nil
+		}
+	case *array.BinaryBuilder:
+		if v, ok := d.(*data.Binary); ok {
+			fb.Append(*v)
+			return nil
+		}
+	case *array.StringBuilder:
+		fb.Append(b.jsonString(d))
+		return nil
+	case *array.Date32Builder:
+		if v, ok := d.(*data.Date); ok {
+			t := time.Time(*v)
+			fb.Append(arrow.Date32FromTime(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)))
+			return nil
+		}
+	case *array.TimestampBuilder:
+		var t time.Time
+		switch v := d.(type) {
+		case *data.DateTime:
+			t = time.Time(*v)
+		case *data.Timestamp:
+			t = time.Time(*v)
+		case *data.TimestampNtz:
+			// wall clock of the value is in UTC, like in the text formats
+			t = time.Time(*v)
+		default:
+			return fmt.Errorf("unexpected value of type %T for timestamp", d)
+		}
+		ts, err := arrow.TimestampFromTime(t.UTC(), arrow.Microsecond)
+		if err != nil {
+			return err
+		}
+		fb.Append(ts)
+		return nil
+	}
+	return fmt.Errorf("unexpected value of type %T for %s", d, field.Type())
+}
+
+// jsonString formats the value as text, complex types as json
+func (b *RecordBuilder) jsonString(d data.Data) string {
+	value := b.formatter.ToValue(d)
+	if s, ok := value.(string); ok {
+		return s
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(content)
+}
+
+// CheckArrowSchema returns error for the columns which can not be read with ArrowValue
+func CheckArrowSchema(schema *arrow.Schema) error {
+	for _, f := range schema.Fields() {
+		switch f.Type.ID() {
+		case arrow.BOOL, arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64, arrow.UINT8, arrow.UINT16,
+			arrow.UINT32, arrow.UINT64, arrow.FLOAT32, arrow.FLOAT64, arrow.STRING, arrow.LARGE_STRING,
+			arrow.BINARY, arrow.LARGE_BINARY, arrow.FIXED_SIZE_BINARY, arrow.DECIMAL128, arrow.DECIMAL256,
+			arrow.DATE32, arrow.DATE64, arrow.TIMESTAMP:
+		default:
+			return fmt.Errorf("column %s of type %s is not supported", f.Name, f.Type)
+		}
+	}
+	return nil
+}
+
+// ArrowValue returns the value at row i as the types accepted by FromValue, nil for null.
+// Decimals are returned as text and times are in UTC.
+func ArrowValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return int32(a.Value(i))
+	case *array.Int16:
+		return int32(a.Value(i))
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return int32(a.Value(i))
+	case *array.Uint16:
+		return int32(a.Value(i))
+	case *array.Uint32:
+		return int64(a.Value(i))
+	case *array.Uint64:
+		return strconv.FormatUint(a.Value(i), 10)
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.Binary:
+		return append([]byte{}, a.Value(i)...)
+	case *array.LargeBinary:
+		return append([]byte{}, a.Value(i)...)
+	case *array.FixedSizeBinary:
+		return append([]byte{}, a.Value(i)...)
+	case *array.Decimal128, *array.Decimal256:
+		return a.ValueStr(i)
+	case *array.Date32:
+		return a.Value(i).ToTime()
+	case *array.Date64:
+		return a.Value(i).ToTime()
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	}
+	return arr.ValueStr(i)
+}
+
+// LocalTimestamps returns for every column of the parquet file if it is a timestamp not adjusted to UTC.
+// Arrow reads all timestamps in UTC, the wall clock of these is taken as local time like the text values.
+func LocalTimestamps(f *pqfile.Reader) []bool {
+	sc := f.MetaData().Schema
+	local := make([]bool, sc.NumColumns())
+	for i := range local {
+		if ts, ok := sc.Column(i).LogicalType().(*schema.TimestampLogicalType); ok {
+			local[i] = !ts.IsAdjustedToUTC()
+		}
+	}
+	return local
+}
+
+func isNullData(d data.Data) bool {
+	if d == nil {
+		return true
+	}
+	_, ok := d.(data.NullData)
+	return ok
+}
@@ -0,0 +1,162 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	pqfile "github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/apache/arrow/go/v15/parquet/schema"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+func TestArrowParquet(t *testing.T) {
+	typeOf := datatype.NewPrimitiveType
+	cols := []tableschema.Column{
+		{Name: "flag", Type: typeOf(datatype.BOOLEAN)},
+		{Name: "small", Type: typeOf(datatype.INT)},
+		{Name: "big", Type: typeOf(datatype.BIGINT)},
+		{Name: "ratio", Type: typeOf(datatype.FLOAT)},
+		{Name: "amount", Type: typeOf(datatype.DOUBLE)},
+		{Name: "name", Type: typeOf(datatype.STRING)},
+		{Name: "raw", Type: typeOf(datatype.BINARY)},
+		{Name: "day", Type: typeOf(datatype.DATE)},
+		{Name: "created_at", Type: typeOf(datatype.TIMESTAMP)},
+		{Name: "local_at", Type: typeOf(datatype.TIMESTAMP_NTZ)},
+		{Name: "price", Type: datatype.DecimalType{Precision: 20, Scale: 3}},
+	}
+	headers := []string{"flag", "small", "big", "ratio", "amount", "name", "raw", "day", "created_at", "local_at", "price"}
+
+	ts := time.Date(2024, 3, 9, 10, 11, 12, 123456000, time.UTC)
+	jakarta := time.FixedZone("WIB", 7*3600)
+	flag, small, big := data.Bool(true), data.Int(-3), data.BigInt(1<<40)
+	ratio, amount, name := data.Float(0.5), data.Double(1.25), data.String("世界")
+	raw := data.Binary("x")
+	day := data.Date(time.Date(2024, 3, 9, 0, 0, 0, 0, jakarta))
+	created, local := data.Timestamp(ts.In(jakarta)), data.TimestampNtz(ts)
+
+	records := []data.Record{
+		{&flag, &small, &big, &ratio, &amount, &name, &raw, &day, &created, &local, data.NewDecimal(20, 3, "-12.345")},
+		{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+	}
+
+	t.Run("reads the records written to parquet", func(t *testing.T) {
+		schema, err := internal.ArrowSchema(cols, headers)
+		assert.NoError(t, err)
+
+		b := internal.NewRecordBuilder(schema, internal.Formatter{Precision: -1})
+		defer b.Release()
+		for _, r := range records {
+			assert.NoError(t, b.Append(r))
+		}
+		assert.Equal(t, 2, b.Rows())
+		rec := b.NewRecord()
+		defer rec.Release()
+
+		var buf bytes.Buffer
+		w, err := pqarrow.NewFileWriter(schema, &buf, nil, pqarrow.DefaultWriterProps())
+		assert.NoError(t, err)
+		assert.NoError(t, w.Write(rec))
+		assert.NoError(t, w.Close())
+
+		rows := readParquet(t, buf.Bytes())
+		assert.Equal(t, []any{true, int32(-3), int64(1 << 40), float32(0.5), 1.25, "世界", []byte("x"),
+			time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), ts, ts, "-12.345"}, rows[0])
+		assert.Equal(t, make([]any, len(headers)), rows[1])
+	})
+	t.Run("returns error for unknown column and invalid value", func(t *testing.T) {
+		_, err := internal.ArrowSchema(cols, []string{"flag", "other"})
+		assert.ErrorContains(t, err, "column other is not present in the table")
+
+		schema, err := internal.ArrowSchema(cols, []string{"flag", "small"})
+		assert.NoError(t, err)
+		b := internal.NewRecordBuilder(schema, internal.Formatter{Precision: -1})
+		defer b.Release()
+
+		assert.ErrorContains(t, b.Append(data.Record{&flag}), "record has 1 values, expected 2")
+		assert.ErrorContains(t, b.Append(data.Record{&flag, &big}), "column small: unexpected value of type *data.BigInt")
+	})
+}
+
+func TestArrowValue(t *testing.T) {
+	t.Run("reads file written by pyarrow with dictionary pages", func(t *testing.T) {
+		content, err := os.ReadFile("testdata/v0.7.1.parquet")
+		assert.NoError(t, err)
+
+		rows := readParquet(t, content)
+		assert.Len(t, rows, 10)
+		assert.Equal(t, []any{0.23, "Ideal", "E", "SI2", 61.5, 55.0, int64(326), 3.95, 3.98, 2.43, int64(0)}, rows[0])
+	})
+	t.Run("returns error for files which are not parquet", func(t *testing.T) {
+		_, err := pqfile.NewParquetReader(bytes.NewReader([]byte("a,b,c\n1,2,3\n")))
+		assert.Error(t, err)
+	})
+}
+
+func TestLocalTimestamps(t *testing.T) {
+	utcAt, err := schema.NewPrimitiveNodeLogical("utc_at", parquet.Repetitions.Required,
+		schema.NewTimestampLogicalType(true, schema.TimeUnitMicros), parquet.Types.Int64, -1, -1)
+	assert.NoError(t, err)
+	localAt, err := schema.NewPrimitiveNodeLogical("local_at", parquet.Repetitions.Required,
+		schema.NewTimestampLogicalType(false, schema.TimeUnitMicros), parquet.Types.Int64, -1, -1)
+	assert.NoError(t, err)
+	root, err := schema.NewGroupNode("schema", parquet.Repetitions.Required, schema.FieldList{utcAt, localAt}, -1)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := pqfile.NewParquetWriter(&buf, root)
+	rg := w.AppendRowGroup()
+	for i := 0; i < 2; i++ {
+		col, err := rg.NextColumn()
+		assert.NoError(t, err)
+		_, err = col.(*pqfile.Int64ColumnChunkWriter).WriteBatch([]int64{0}, nil, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, col.Close())
+	}
+	assert.NoError(t, rg.Close())
+	assert.NoError(t, w.Close())
+
+	f, err := pqfile.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, internal.LocalTimestamps(f))
+}
+
+func readParquet(t *testing.T, content []byte) [][]any {
+	t.Helper()
+
+	f, err := pqfile.NewParquetReader(bytes.NewReader(content))
+	assert.NoError(t, err)
+	reader, err := pqarrow.NewFileReader(f, pqarrow.ArrowReadProperties{BatchSize: 10}, memory.DefaultAllocator)
+	assert.NoError(t, err)
+
+	schema, err := reader.Schema()
+	assert.NoError(t, err)
+	assert.NoError(t, internal.CheckArrowSchema(schema))
+
+	records, err := reader.GetRecordReader(context.Background(), nil, nil)
+	assert.NoError(t, err)
+	defer records.Release()
+
+	var rows [][]any
+	for records.Next() {
+		rec := records.Record()
+		for i := 0; i < int(rec.NumRows()); i++ {
+			row := make([]any, rec.NumCols())
+			for j, col := range rec.Columns() {
+				row[j] = internal.ArrowValue(col, i)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// AuditEntry records a destructive operation done on maxcompute
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Profile string    `json:"profile,omitempty"`
+	Action  string    `json:"action"`
+	Target  string    `json:"target"`
+	Details string    `json:"details,omitempty"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// AuditFile returns the path of the local audit log
+func AuditFile() string {
+	return filepath.Join(config.CacheDir(), "audit", "mc.jsonl")
+}
+
+// WriteAudit appends the entries to the audit log, one json per line
+func WriteAudit(cfg *config.Config, entries ...AuditEntry) error {
+	auditFile := AuditFile()
+	err := os.MkdirAll(filepath.Dir(auditFile), 0o755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(auditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	profile := cfg.GetCurrentProfile().Name
+	encoder := json.NewEncoder(f)
+	for _, e := range entries {
+		if e.Time.IsZero() {
+			e.Time = time.Now()
+		}
+		if e.Profile == "" {
+			e.Profile = profile
+		}
+		err = encoder.Encode(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+)
+
+var (
+	dateLayouts     = []string{time.DateOnly, "2006/01/02", "20060102"}
+	dateTimeLayouts = []string{time.DateTime, "2006-01-02T15:04:05", time.RFC3339, time.RFC3339Nano, "2006-01-02 15:04:05.999999999", time.DateOnly}
+)
+
+// FromString converts the text value to the data of the column type, used when loading text files
+// into a table. Only primitive types are supported.
+func FromString(typ datatype.DataType, s string) (data.Data, error) {
+	switch typ.ID() {
+	case datatype.STRING:
+		v := data.String(s)
+		return &v, nil
+
+	case datatype.BIGINT, datatype.INT, datatype.SMALLINT, datatype.TINYINT:
+		bits := map[datatype.TypeID]int{datatype.BIGINT: 64, datatype.INT: 32, datatype.SMALLINT: 16, datatype.TINYINT: 8}
+		v, err := strconv.ParseInt(strings.TrimSpace(s), 10, bits[typ.ID()])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", typ.Name(), s)
+		}
+		switch typ.ID() {
+		case datatype.INT:
+			d := data.Int(v)
+			return &d, nil
+		case datatype.SMALLINT:
+			d := data.SmallInt(v)
+			return &d, nil
+		case datatype.TINYINT:
+			d := data.TinyInt(v)
+			return &d, nil
+		}
+		d := data.BigInt(v)
+		return &d, nil
+
+	case datatype.DOUBLE, datatype.FLOAT:
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", typ.Name(), s)
+		}
+		if typ.ID() == datatype.FLOAT {
+			d := data.Float(v)
+			return &d, nil
+		}
+		d := data.Double(v)
+		return &d, nil
+
+	case datatype.BOOLEAN:
+		v, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", s)
+		}
+		d := data.Bool(v)
+		return &d, nil
+
+	case datatype.DECIMAL:
+		value := strings.TrimSpace(s)
+		if _, ok := new(big.Rat).SetString(value); !ok {
+			return nil, fmt.Errorf("invalid decimal %q", s)
+		}
+		dt, _ := typ.(datatype.DecimalType)
+		return data.NewDecimal(int(dt.Precision), int(dt.Scale), value), nil
+
+	case datatype.CHAR:
+		dt, _ := typ.(datatype.CharType)
+		return data.NewChar(dt.Length, s)
+
+	case datatype.VARCHAR:
+		dt, _ := typ.(datatype.VarcharType)
+		return data.NewVarChar(dt.Length, s)
+
+	case datatype.BINARY:
+		v := data.Binary(s)
+		return &v, nil
+
+	case datatype.DATE:
+		t, err := parseTime(s, dateLayouts)
+		if err != nil {
+			return nil, err
+		}
+		v := data.Date(t)
+		return &v, nil
+
+	case datatype.DATETIME:
+		t, err := parseTime(s, dateTimeLayouts)
+		if err != nil {
+			return nil, err
+		}
+		v := data.DateTime(t)
+		return &v, nil
+
+	case datatype.TIMESTAMP:
+		t, err := parseTime(s, dateTimeLayouts)
+		if err != nil {
+			return nil, err
+		}
+		v := data.Timestamp(t)
+		return &v, nil
+
+	case datatype.TIMESTAMP_NTZ:
+		t, err := parseTime(s, dateTimeLayouts)
+		if err != nil {
+			return nil, err
+		}
+		v := data.TimestampNtz(t)
+		return &v, nil
+	}
+
+	return nil, fmt.Errorf("loading text into %s column is not supported", typ.String())
+}
+
+// FromValue converts a typed value, like the ones read from parquet files, to the data of the column type.
+// Numbers and booleans are checked like their text, times keep the instant and nil is a null value.
+func FromValue(typ datatype.DataType, v any) (data.Data, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return FromString(typ, val)
+	case []byte:
+		if typ.ID() == datatype.BINARY {
+			d := data.Binary(val)
+			return &d, nil
+		}
+		return FromString(typ, string(val))
+	case bool:
+		return FromString(typ, strconv.FormatBool(val))
+	case int32:
+		return FromString(typ, strconv.FormatInt(int64(val), 10))
+	case int64:
+		return FromString(typ, strconv.FormatInt(val, 10))
+	case float32:
+		return FromString(typ, strconv.FormatFloat(float64(val), 'f', -1, 32))
+	case float64:
+		return FromString(typ, strconv.FormatFloat(val, 'f', -1, 64))
+	case time.Time:
+		return fromTime(typ, val)
+	}
+	return nil, fmt.Errorf("loading %T into %s column is not supported", v, typ.String())
+}
+
+func fromTime(typ datatype.DataType, t time.Time) (data.Data, error) {
+	switch typ.ID() {
+	case datatype.DATE:
+		v := data.Date(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local))
+		return &v, nil
+	case datatype.DATETIME:
+		v := data.DateTime(t)
+		return &v, nil
+	case datatype.TIMESTAMP:
+		v := data.Timestamp(t)
+		return &v, nil
+	case datatype.TIMESTAMP_NTZ:
+		v := data.TimestampNtz(t)
+		return &v, nil
+	case datatype.STRING:
+		v := data.String(t.Format(time.RFC3339Nano))
+		return &v, nil
+	}
+	return nil, fmt.Errorf("loading time into %s column is not supported", typ.String())
+}
+
+func parseTime(s string, layouts []string) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected format %s", s, layouts[0])
+}
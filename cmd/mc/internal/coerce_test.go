@@ -0,0 +1,62 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+func TestFromValue(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 20, 30, 45, 0, time.UTC)
+	typeOf := datatype.NewPrimitiveType
+
+	bigint := data.BigInt(1 << 40)
+	tiny := data.TinyInt(-3)
+	double := data.Double(0.1)
+	float := data.Float(1.5)
+	boolean := data.Bool(true)
+	str := data.String("1.5")
+	binary := data.Binary("xyz")
+	date := data.Date(time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local))
+	timestamp := data.Timestamp(ts)
+
+	tests := []struct {
+		name     string
+		typ      datatype.DataType
+		value    any
+		expected data.Data
+		err      string
+	}{
+		{name: "nil", typ: typeOf(datatype.BIGINT), value: nil, expected: nil},
+		{name: "int64 to bigint", typ: typeOf(datatype.BIGINT), value: int64(1 << 40), expected: &bigint},
+		{name: "int32 to tinyint", typ: typeOf(datatype.TINYINT), value: int32(-3), expected: &tiny},
+		{name: "float to double keeps the text", typ: typeOf(datatype.DOUBLE), value: float32(0.1), expected: &double},
+		{name: "double to float", typ: typeOf(datatype.FLOAT), value: 1.5, expected: &float},
+		{name: "bool", typ: typeOf(datatype.BOOLEAN), value: true, expected: &boolean},
+		{name: "double to string", typ: typeOf(datatype.STRING), value: 1.5, expected: &str},
+		{name: "bytes to binary", typ: typeOf(datatype.BINARY), value: []byte("xyz"), expected: &binary},
+		{name: "time to date", typ: typeOf(datatype.DATE), value: ts, expected: &date},
+		{name: "time to timestamp", typ: typeOf(datatype.TIMESTAMP), value: ts, expected: &timestamp},
+		{name: "out of range", typ: typeOf(datatype.TINYINT), value: int64(300), err: "invalid"},
+		{name: "fraction to bigint", typ: typeOf(datatype.BIGINT), value: 1.5, err: "invalid"},
+		{name: "time to bigint", typ: typeOf(datatype.BIGINT), value: ts, err: "loading time into"},
+		{name: "unknown value", typ: typeOf(datatype.STRING), value: struct{}{}, err: "loading struct {} into"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := internal.FromValue(tt.typ, tt.value)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GenerateDDL rebuilds the create statement for a table, view or external table
+func GenerateDDL(t TableInfo) string {
+	if t.ViewText != "" {
+		return viewDDL(t)
+	}
+
+	b := &strings.Builder{}
+	if t.External != nil {
+		b.WriteString("CREATE EXTERNAL TABLE IF NOT EXISTS ")
+	} else {
+		b.WriteString("CREATE TABLE IF NOT EXISTS ")
+	}
+	b.WriteString(t.Name)
+	b.WriteString(" (\n")
+	writeColumns(b, t.Columns, true)
+	b.WriteString(")")
+
+	if t.Comment != "" {
+		b.WriteString("\nCOMMENT " + Quote(t.Comment))
+	}
+
+	if len(t.PartitionColumns) > 0 {
+		b.WriteString("\nPARTITIONED BY (\n")
+		writeColumns(b, t.PartitionColumns, false)
+		b.WriteString(")")
+	}
+
+	if t.Cluster != nil && len(t.Cluster.Columns) > 0 {
+		b.WriteString("\n")
+		if strings.EqualFold(t.Cluster.Type, "range") {
+			b.WriteString("RANGE ")
+		}
+		b.WriteString("CLUSTERED BY (" + strings.Join(t.Cluster.Columns, ", ") + ")")
+		if len(t.Cluster.SortCols) > 0 {
+			b.WriteString(" SORTED BY (" + strings.Join(t.Cluster.SortCols, ", ") + ")")
+		}
+		if t.Cluster.BucketNum > 0 {
+			b.WriteString(fmt.Sprintf(" INTO %d BUCKETS", t.Cluster.BucketNum))
+		}
+	}
+
+	if t.External != nil {
+		if t.External.StorageHandler != "" {
+			b.WriteString("\nSTORED BY " + Quote(t.External.StorageHandler))
+		}
+		if len(t.External.SerDeProperties) > 0 {
+			b.WriteString("\nWITH SERDEPROPERTIES (\n")
+			writeProperties(b, t.External.SerDeProperties)
+			b.WriteString(")")
+		}
+		if t.External.Location != "" {
+			b.WriteString("\nLOCATION " + Quote(t.External.Location))
+		}
+	}
+
+	if len(t.TblProperties) > 0 {
+		b.WriteString("\nTBLPROPERTIES (\n")
+		writeProperties(b, t.TblProperties)
+		b.WriteString(")")
+	}
+
+	if t.Lifecycle > 0 {
+		b.WriteString(fmt.Sprintf("\nLIFECYCLE %d", t.Lifecycle))
+	}
+	b.WriteString(";\n")
+	return b.String()
+}
+
+func viewDDL(t TableInfo) string {
+	b := &strings.Builder{}
+	if strings.Contains(strings.ToUpper(t.Type), "MATERIALIZED") {
+		b.WriteString("CREATE MATERIALIZED VIEW IF NOT EXISTS ")
+	} else {
+		b.WriteString("CREATE VIEW IF NOT EXISTS ")
+	}
+	b.WriteString(t.Name)
+
+	if len(t.Columns) > 0 {
+		b.WriteString(" (\n")
+		for i, c := range t.Columns {
+			b.WriteString("  `" + c.Name + "`")
+			if c.Comment != "" {
+				b.WriteString(" COMMENT " + Quote(c.Comment))
+			}
+			if i < len(t.Columns)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(")")
+	}
+
+	if t.Comment != "" {
+		b.WriteString("\nCOMMENT " + Quote(t.Comment))
+	}
+	b.WriteString("\nAS\n")
+	b.WriteString(strings.TrimSuffix(strings.TrimSpace(t.ViewText), ";"))
+	b.WriteString(";\n")
+	return b.String()
+}
+
+func writeColumns(b *strings.Builder, cols []ColumnInfo, withNullable bool) {
+	for i, c := range cols {
+		b.WriteString("  `" + c.Name + "` " + c.Type)
+		if withNullable && !c.Nullable {
+			b.WriteString(" NOT NULL")
+		}
+		if c.Comment != "" {
+			b.WriteString(" COMMENT " + Quote(c.Comment))
+		}
+		if i < len(cols)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+}
+
+func writeProperties(b *strings.Builder, props map[string]string) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		b.WriteString("  " + Quote(k) + "=" + Quote(props[k]))
+		if i < len(keys)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+}
+
+// Quote returns the value as a sql string literal
+func Quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDrop      = "drop"
+	ActionUnchanged = "unchanged"
+)
+
+// FunctionSpec is a function declared in the deploy file, or present in the project
+type FunctionSpec struct {
+	Name      string   `yaml:"name"`
+	ClassPath string   `yaml:"class_path"`
+	Resources []string `yaml:"resources"`
+}
+
+type DeployFile struct {
+	Project   string         `yaml:"project"`
+	Schema    string         `yaml:"schema"`
+	Functions []FunctionSpec `yaml:"functions"`
+}
+
+type DeployStep struct {
+	Action string
+	Spec   FunctionSpec
+	Change string
+}
+
+// ReadDeployFile reads the functions to deploy, every function requires name, class_path and resources
+func ReadDeployFile(fileName string) (DeployFile, error) {
+	var spec DeployFile
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return spec, err
+	}
+
+	err = yaml.Unmarshal(content, &spec)
+	if err != nil {
+		return spec, fmt.Errorf("failed to parse %s: %w", fileName, err)
+	}
+
+	names := map[string]bool{}
+	for _, f := range spec.Functions {
+		if f.Name == "" || f.ClassPath == "" || len(f.Resources) == 0 {
+			return spec, fmt.Errorf("function %q requires name, class_path and resources", f.Name)
+		}
+		if names[strings.ToLower(f.Name)] {
+			return spec, fmt.Errorf("function %s is declared more than once", f.Name)
+		}
+		names[strings.ToLower(f.Name)] = true
+	}
+	return spec, nil
+}
+
+// PlanDeploy returns the steps to change the existing functions to the declared ones, names are
+// compared ignoring case. Functions which are not declared are dropped only with prune, pruning
+// with no declared function is refused as it would drop every function of the schema.
+func PlanDeploy(declared, existing []FunctionSpec, prune bool) ([]DeployStep, error) {
+	if prune && len(declared) == 0 {
+		return nil, errors.New("refusing to prune with no functions declared, it would drop all the functions")
+	}
+
+	current := map[string]FunctionSpec{}
+	for _, f := range existing {
+		current[strings.ToLower(f.Name)] = f
+	}
+
+	var steps []DeployStep
+	seen := map[string]bool{}
+	for _, spec := range declared {
+		name := strings.ToLower(spec.Name)
+		seen[name] = true
+
+		f, ok := current[name]
+		if !ok {
+			steps = append(steps, DeployStep{Action: ActionCreate, Spec: spec})
+			continue
+		}
+
+		var changes []string
+		if f.ClassPath != spec.ClassPath {
+			changes = append(changes, fmt.Sprintf("class path %s -> %s", f.ClassPath, spec.ClassPath))
+		}
+		if !SameResources(f.Resources, spec.Resources) {
+			changes = append(changes, fmt.Sprintf("resources %s -> %s",
+				strings.Join(f.Resources, ","), strings.Join(spec.Resources, ",")))
+		}
+
+		if len(changes) == 0 {
+			steps = append(steps, DeployStep{Action: ActionUnchanged, Spec: spec})
+		} else {
+			steps = append(steps, DeployStep{Action: ActionUpdate, Spec: spec, Change: strings.Join(changes, "; ")})
+		}
+	}
+
+	if prune {
+		for _, f := range existing {
+			if !seen[strings.ToLower(f.Name)] {
+				steps = append(steps, DeployStep{Action: ActionDrop, Spec: FunctionSpec{Name: f.Name}})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// SameResources compares the resources ignoring the order
+func SameResources(a, b []string) bool {
+	a1 := slices.Clone(a)
+	b1 := slices.Clone(b)
+	sort.Strings(a1)
+	sort.Strings(b1)
+	return slices.Equal(a1, b1)
+}
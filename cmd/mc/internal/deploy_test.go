@@ -0,0 +1,151 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+func TestReadDeployFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []internal.FunctionSpec
+		err      string
+	}{
+		{
+			name: "reads functions",
+			content: `project: proj
+functions:
+  - name: parse_json
+    class_path: com.example.ParseJson
+    resources: [udfs.jar]`,
+			expected: []internal.FunctionSpec{{Name: "parse_json", ClassPath: "com.example.ParseJson", Resources: []string{"udfs.jar"}}},
+		},
+		{
+			name:     "reads empty functions",
+			content:  "project: proj\nfunctions: []",
+			expected: []internal.FunctionSpec{},
+		},
+		{
+			name: "returns error for function without resources",
+			content: `functions:
+  - name: parse_json
+    class_path: com.example.ParseJson`,
+			err: `function "parse_json" requires name, class_path and resources`,
+		},
+		{
+			name: "returns error for names declared twice ignoring case",
+			content: `functions:
+  - {name: parse_json, class_path: a.B, resources: [a.jar]}
+  - {name: Parse_JSON, class_path: a.C, resources: [a.jar]}`,
+			err: "function Parse_JSON is declared more than once",
+		},
+		{
+			name:    "returns error for invalid yaml",
+			content: "functions: {",
+			err:     "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "functions.yaml")
+			assert.NoError(t, os.WriteFile(fileName, []byte(tt.content), 0o644))
+
+			spec, err := internal.ReadDeployFile(fileName)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, spec.Functions)
+		})
+	}
+}
+
+func TestPlanDeploy(t *testing.T) {
+	existing := []internal.FunctionSpec{
+		{Name: "Parse_JSON", ClassPath: "com.example.ParseJson", Resources: []string{"b.jar", "a.jar"}},
+		{Name: "to_upper", ClassPath: "com.example.Upper", Resources: []string{"a.jar"}},
+		{Name: "old_fn", ClassPath: "com.example.Old", Resources: []string{"a.jar"}},
+	}
+	declared := []internal.FunctionSpec{
+		{Name: "parse_json", ClassPath: "com.example.ParseJson", Resources: []string{"a.jar", "b.jar"}},
+		{Name: "TO_UPPER", ClassPath: "com.example.Upper2", Resources: []string{"c.jar"}},
+		{Name: "new_fn", ClassPath: "com.example.New", Resources: []string{"a.jar"}},
+	}
+
+	tests := []struct {
+		name     string
+		declared []internal.FunctionSpec
+		prune    bool
+		expected []internal.DeployStep
+		err      string
+	}{
+		{
+			name:     "compares names ignoring case and keeps others without prune",
+			declared: declared,
+			expected: []internal.DeployStep{
+				{Action: internal.ActionUnchanged, Spec: declared[0]},
+				{Action: internal.ActionUpdate, Spec: declared[1],
+					Change: "class path com.example.Upper -> com.example.Upper2; resources a.jar -> c.jar"},
+				{Action: internal.ActionCreate, Spec: declared[2]},
+			},
+		},
+		{
+			name:     "drops functions which are not declared with prune",
+			declared: declared[:1],
+			prune:    true,
+			expected: []internal.DeployStep{
+				{Action: internal.ActionUnchanged, Spec: declared[0]},
+				{Action: internal.ActionDrop, Spec: internal.FunctionSpec{Name: "to_upper"}},
+				{Action: internal.ActionDrop, Spec: internal.FunctionSpec{Name: "old_fn"}},
+			},
+		},
+		{
+			name:  "refuses prune with no declared functions",
+			prune: true,
+			err:   "refusing to prune with no functions declared",
+		},
+		{
+			name: "returns no steps with no declared functions without prune",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := internal.PlanDeploy(tt.declared, existing, tt.prune)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, steps)
+		})
+	}
+}
+
+func TestSameResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []string
+		expected bool
+	}{
+		{name: "same order", a: []string{"a.jar", "b.jar"}, b: []string{"a.jar", "b.jar"}, expected: true},
+		{name: "different order", a: []string{"b.jar", "a.jar"}, b: []string{"a.jar", "b.jar"}, expected: true},
+		{name: "both empty", expected: true},
+		{name: "missing resource", a: []string{"a.jar"}, b: []string{"a.jar", "b.jar"}},
+		{name: "different resource", a: []string{"a.jar"}, b: []string{"c.jar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, internal.SameResources(tt.a, tt.b))
+		})
+	}
+}
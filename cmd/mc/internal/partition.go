@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+)
+
+type PartitionValue struct {
+	Key   string
+	Value string
+}
+
+// PartitionSpec keeps the partition values in the order they were provided
+type PartitionSpec []PartitionValue
+
+// ParsePartitionSpec accepts values like dt=2024-01-01, multiple values can be
+// provided separately or joined with , or /
+func ParsePartitionSpec(specs []string) (PartitionSpec, error) {
+	var spec PartitionSpec
+	seen := map[string]bool{}
+	for _, s := range specs {
+		parts := strings.FieldsFunc(s, func(r rune) bool {
+			return r == ',' || r == '/'
+		})
+		for _, part := range parts {
+			key, value, found := strings.Cut(part, "=")
+			key = strings.TrimSpace(key)
+			if !found || key == "" {
+				return nil, fmt.Errorf("invalid partition %q, expected key=value", part)
+			}
+
+			lower := strings.ToLower(key)
+			if seen[lower] {
+				return nil, fmt.Errorf("partition key %s provided more than once", key)
+			}
+			seen[lower] = true
+
+			value = strings.Trim(strings.TrimSpace(value), `'"`)
+			spec = append(spec, PartitionValue{Key: key, Value: value})
+		}
+	}
+	return spec, nil
+}
+
+// Validate checks that every key is a partition column of the table
+func (p PartitionSpec) Validate(cols []tableschema.Column) error {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+
+	for _, pv := range p {
+		found := false
+		for _, name := range names {
+			if strings.EqualFold(name, pv.Key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if len(names) == 0 {
+				return fmt.Errorf("table is not partitioned, cannot filter on %s", pv.Key)
+			}
+			return fmt.Errorf("%s is not a partition column, available: %s", pv.Key, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// String returns the spec in the format used by tunnel and partition APIs, eg dt='2024-01-01',hh='01'
+func (p PartitionSpec) String() string {
+	parts := make([]string, len(p))
+	for i, pv := range p {
+		parts[i] = fmt.Sprintf("%s='%s'", pv.Key, pv.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Condition returns the spec as a sql predicate
+func (p PartitionSpec) Condition() string {
+	parts := make([]string, len(p))
+	for i, pv := range p {
+		parts[i] = fmt.Sprintf("%s = '%s'", pv.Key, strings.ReplaceAll(pv.Value, "'", "\\'"))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// Get returns the value for the partition key
+func (p PartitionSpec) Get(key string) (string, bool) {
+	for _, pv := range p {
+		if strings.EqualFold(pv.Key, key) {
+			return pv.Value, true
+		}
+	}
+	return "", false
+}
+
+// Matches checks every key of the pattern against the spec, values of the pattern can use glob syntax
+func (p PartitionSpec) Matches(pattern PartitionSpec) bool {
+	for _, pv := range pattern {
+		value, ok := p.Get(pv.Key)
+		if !ok {
+			return false
+		}
+		matched, err := path.Match(pv.Value, value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
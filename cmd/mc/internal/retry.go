@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"strings"
+	"time"
+)
+
+const connErr = "connection reset by peer"
+
+// IsRetryable reports errors caused by the connection which are safe to retry
+func IsRetryable(err error) bool {
+	return err != nil && strings.Contains(err.Error(), connErr)
+}
+
+// Retry runs fn up to attempts times while the error is retryable, waiting longer after every attempt
+func Retry(attempts int, fn func() error) error {
+	var err error
+	for i := 0; i < max(attempts, 1); i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * 500 * time.Millisecond)
+		}
+
+		err = fn()
+		if !IsRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+
+	"github.com/sbchaos/opms/lib/pool"
+)
+
+// AllSchemas is used as the schema name to run a command on every schema of the project
+const AllSchemas = "*"
+
+// ListSchemas returns the names of the schemas in the project, sorted by name
+func ListSchemas(client *odps.Odps, proj string) ([]string, error) {
+	var schemas []string
+	var errs []string
+
+	odps.NewSchemas(client, proj).List(func(s *odps.Schema, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		schemas = append(schemas, s.Name())
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to list schemas of %s: %s", proj, strings.Join(errs, ", "))
+	}
+
+	sort.Strings(schemas)
+	return schemas, nil
+}
+
+// ResolveSchemas returns the schemas for the schema flag, all schemas of the project for AllSchemas
+func ResolveSchemas(client *odps.Odps, proj, schema string) ([]string, error) {
+	switch schema {
+	case "":
+		return []string{"default"}, nil
+	case AllSchemas:
+		return ListSchemas(client, proj)
+	}
+	return []string{schema}, nil
+}
+
+// LoadTables lists the tables of schema and loads them with workers, listed tables only have
+// the name and type so they are loaded before reading the size, records or lifecycle.
+// The loaded tables are sorted by name, tables which failed to load are returned as errors.
+func LoadTables(client *odps.Odps, proj, schema string, workers int) ([]*odps.Table, []string) {
+	var listed []*odps.Table
+	var errs []string
+	odps.NewTables(client, proj, schema).List(func(t *odps.Table, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		listed = append(listed, t)
+	})
+
+	jobs := make(chan pool.Job[*odps.Table], 20)
+	go func() {
+		for _, t := range listed {
+			jobs <- func() pool.JobResult[*odps.Table] {
+				return pool.JobResult[*odps.Table]{Output: t, Err: Retry(3, t.Load)}
+			}
+		}
+		close(jobs)
+	}()
+
+	var tables []*odps.Table
+	for res := range pool.StartPool(workers, jobs) {
+		if res.Err != nil {
+			errs = append(errs, fmt.Sprintf("%s.%s: %s", schema, res.Output.Name(), res.Err))
+			continue
+		}
+		tables = append(tables, res.Output)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name() < tables[j].Name()
+	})
+	sort.Strings(errs)
+	return tables, errs
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"gopkg.in/yaml.v3"
+)
+
+const sheetSourceType = "GOOGLE_SHEETS"
+
+// categories of the differences between a sheet and the table schema
+const (
+	CategoryColumns = "column_count_mismatch"
+	CategoryHeader  = "header_mismatch"
+	CategoryCast    = "type_cast_failure"
+)
+
+// Problem is a check on a table which failed, with the category of the check
+type Problem struct {
+	Category string
+	Detail   string
+}
+
+// SheetSource is the google sheet the external table is synced from
+type SheetSource struct {
+	URI   string
+	Range string
+}
+
+type resourceSpec struct {
+	Spec struct {
+		Source struct {
+			Type  string   `yaml:"type"`
+			URIs  []string `yaml:"uris"`
+			Range string   `yaml:"range"`
+		} `yaml:"source"`
+	} `yaml:"spec"`
+}
+
+// LoadSheetSource reads the source from the resource spec of the table, specs are kept in
+// <dir>/<table>/resource.yaml as generated by opms optimus generate externalTable
+func LoadSheetSource(dir, name string) (*SheetSource, error) {
+	path := filepath.Join(dir, name, "resource.yaml")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource spec: %w", err)
+	}
+
+	var spec resourceSpec
+	err = yaml.Unmarshal(content, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resource spec %s: %w", path, err)
+	}
+
+	source := spec.Spec.Source
+	if source.Type != "" && !strings.EqualFold(source.Type, sheetSourceType) {
+		return nil, fmt.Errorf("source type %s is not a google sheet", source.Type)
+	}
+	if len(source.URIs) == 0 {
+		return nil, errors.New("resource spec does not have a source uri")
+	}
+
+	return &SheetSource{URI: source.URIs[0], Range: source.Range}, nil
+}
+
+// CompareSheet checks the header row against the columns and converts the first sampleRows rows,
+// a negative sampleRows converts all the rows
+func CompareSheet(content [][]interface{}, cols []tableschema.Column, sampleRows int) []Problem {
+	if len(content) == 0 {
+		return nil
+	}
+
+	var problems []Problem
+	header := content[0]
+	if len(header) != len(cols) {
+		problems = append(problems, Problem{CategoryColumns,
+			fmt.Sprintf("sheet has %d columns, table has %d", len(header), len(cols))})
+	}
+
+	for i, col := range cols {
+		if i >= len(header) {
+			break
+		}
+		h := strings.TrimSpace(fmt.Sprintf("%v", header[i]))
+		if !strings.EqualFold(h, col.Name) {
+			problems = append(problems, Problem{CategoryHeader,
+				fmt.Sprintf("column %d is %q in sheet, %q in table", i+1, h, col.Name)})
+		}
+	}
+
+	rows := content[1:]
+	if sampleRows >= 0 && len(rows) > sampleRows {
+		rows = rows[:sampleRows]
+	}
+
+	// only the first failure of a column is reported
+	for i, col := range cols {
+		if !isCastable(col.Type) {
+			continue
+		}
+		for j, row := range rows {
+			if i >= len(row) {
+				continue
+			}
+			value := fmt.Sprintf("%v", row[i])
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			_, err := FromString(col.Type, value)
+			if err != nil {
+				problems = append(problems, Problem{CategoryCast,
+					fmt.Sprintf("row %d column %s: %s", j+2, col.Name, err)})
+				break
+			}
+		}
+	}
+	return problems
+}
+
+// isCastable is false for the nested types, which are not read from sheets as text
+func isCastable(typ datatype.DataType) bool {
+	if typ == nil {
+		return false
+	}
+	switch typ.ID() {
+	case datatype.ARRAY, datatype.MAP, datatype.STRUCT, datatype.JSON:
+		return false
+	}
+	return true
+}
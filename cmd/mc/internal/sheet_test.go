@@ -0,0 +1,113 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+func TestCompareSheet(t *testing.T) {
+	cols := []tableschema.Column{
+		{Name: "id", Type: datatype.NewPrimitiveType(datatype.BIGINT)},
+		{Name: "name", Type: datatype.NewPrimitiveType(datatype.STRING)},
+		{Name: "tags", Type: datatype.NewArrayType(datatype.NewPrimitiveType(datatype.STRING))},
+	}
+
+	t.Run("returns no problems when sheet matches the table", func(t *testing.T) {
+		content := [][]interface{}{
+			{"ID", " name ", "tags"},
+			{"1", "first", `["a"]`},
+			{"", "second"},
+		}
+		assert.Empty(t, internal.CompareSheet(content, cols, 100))
+	})
+	t.Run("returns no problems for empty content", func(t *testing.T) {
+		assert.Empty(t, internal.CompareSheet(nil, cols, 100))
+	})
+	t.Run("reports column count and header mismatch", func(t *testing.T) {
+		content := [][]interface{}{{"id", "full_name"}}
+
+		problems := internal.CompareSheet(content, cols, 100)
+		assert.Equal(t, []internal.Problem{
+			{Category: internal.CategoryColumns, Detail: "sheet has 2 columns, table has 3"},
+			{Category: internal.CategoryHeader, Detail: `column 2 is "full_name" in sheet, "name" in table`},
+		}, problems)
+	})
+	t.Run("reports the first cast failure of a column", func(t *testing.T) {
+		content := [][]interface{}{
+			{"id", "name", "tags"},
+			{"1", "first", "not checked"},
+			{"abc", "second", ""},
+			{"1.5", "third", ""},
+		}
+
+		problems := internal.CompareSheet(content, cols, 100)
+		assert.Len(t, problems, 1)
+		assert.Equal(t, internal.CategoryCast, problems[0].Category)
+		assert.Contains(t, problems[0].Detail, "row 3 column id: invalid")
+		assert.Contains(t, problems[0].Detail, `"abc"`)
+	})
+	t.Run("only converts the sample rows", func(t *testing.T) {
+		content := [][]interface{}{
+			{"id", "name", "tags"},
+			{"1", "first", ""},
+			{"abc", "second", ""},
+		}
+
+		assert.Empty(t, internal.CompareSheet(content, cols, 1))
+		assert.Len(t, internal.CompareSheet(content, cols, -1), 1)
+	})
+}
+
+func TestLoadSheetSource(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		dir := t.TempDir()
+		tableDir := filepath.Join(dir, "proj.schema.table")
+		assert.NoError(t, os.MkdirAll(tableDir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(tableDir, "resource.yaml"), []byte(content), 0o644))
+		return dir
+	}
+
+	t.Run("reads the first uri and range of spec", func(t *testing.T) {
+		dir := write(t, `version: 2
+name: proj.schema.table
+type: external_table
+spec:
+  source:
+    type: GOOGLE_SHEETS
+    uris:
+      - https://docs.google.com/spreadsheets/d/abc
+      - https://docs.google.com/spreadsheets/d/def
+    range: "Sheet1!A:D"
+`)
+
+		source, err := internal.LoadSheetSource(dir, "proj.schema.table")
+		assert.NoError(t, err)
+		assert.Equal(t, &internal.SheetSource{URI: "https://docs.google.com/spreadsheets/d/abc", Range: "Sheet1!A:D"}, source)
+	})
+	t.Run("returns error when spec is missing", func(t *testing.T) {
+		_, err := internal.LoadSheetSource(t.TempDir(), "proj.schema.table")
+		assert.ErrorContains(t, err, "failed to read resource spec")
+	})
+	t.Run("returns error for invalid yaml", func(t *testing.T) {
+		dir := write(t, "spec: [")
+		_, err := internal.LoadSheetSource(dir, "proj.schema.table")
+		assert.ErrorContains(t, err, "failed to parse resource spec")
+	})
+	t.Run("returns error for other source types", func(t *testing.T) {
+		dir := write(t, "spec:\n  source:\n    type: OSS\n    uris: [oss://bucket/path]\n")
+		_, err := internal.LoadSheetSource(dir, "proj.schema.table")
+		assert.ErrorContains(t, err, "source type OSS is not a google sheet")
+	})
+	t.Run("returns error without uri", func(t *testing.T) {
+		dir := write(t, "spec:\n  source:\n    type: GOOGLE_SHEETS\n")
+		_, err := internal.LoadSheetSource(dir, "proj.schema.table")
+		assert.ErrorContains(t, err, "resource spec does not have a source uri")
+	})
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+)
+
+type ColumnInfo struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+	Nullable bool   `json:"nullable" yaml:"nullable"`
+	Comment  string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+type ClusterInfo struct {
+	Type      string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Columns   []string `json:"columns,omitempty" yaml:"columns,omitempty"`
+	SortCols  []string `json:"sort_columns,omitempty" yaml:"sort_columns,omitempty"`
+	BucketNum int      `json:"bucket_num,omitempty" yaml:"bucket_num,omitempty"`
+}
+
+// ExternalInfo has the storage details of external tables, properties of the table are kept
+// in TableInfo as they are set for managed tables too, like transactional
+type ExternalInfo struct {
+	StorageHandler  string            `json:"storage_handler,omitempty" yaml:"storage_handler,omitempty"`
+	Location        string            `json:"location,omitempty" yaml:"location,omitempty"`
+	SerDeProperties map[string]string `json:"serde_properties,omitempty" yaml:"serde_properties,omitempty"`
+}
+
+// TableInfo is the complete description of a table, kept stable for diffing in json or yaml.
+// Fields are only added to it, the json and yaml keys are not renamed or moved.
+type TableInfo struct {
+	Name             string            `json:"name" yaml:"name"`
+	Type             string            `json:"type" yaml:"type"`
+	Comment          string            `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Owner            string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	CreatedTime      time.Time         `json:"created_time" yaml:"created_time"`
+	LastModifiedTime time.Time         `json:"last_modified_time" yaml:"last_modified_time"`
+	Lifecycle        int               `json:"lifecycle" yaml:"lifecycle"`
+	Size             int64             `json:"size" yaml:"size"`
+	RecordNum        int               `json:"record_num" yaml:"record_num"`
+	Columns          []ColumnInfo      `json:"columns" yaml:"columns"`
+	PartitionColumns []ColumnInfo      `json:"partition_columns,omitempty" yaml:"partition_columns,omitempty"`
+	Cluster          *ClusterInfo      `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	External         *ExternalInfo     `json:"external,omitempty" yaml:"external,omitempty"`
+	TblProperties    map[string]string `json:"tbl_properties,omitempty" yaml:"tbl_properties,omitempty"`
+	ViewText         string            `json:"view_text,omitempty" yaml:"view_text,omitempty"`
+}
+
+// NewTableInfo collects the details from a loaded table
+func NewTableInfo(name string, t *odps.Table) TableInfo {
+	schema := t.Schema()
+
+	info := TableInfo{
+		Name:             name,
+		Type:             t.Type().String(),
+		Comment:          t.Comment(),
+		Owner:            t.Owner(),
+		CreatedTime:      t.CreatedTime(),
+		LastModifiedTime: t.LastModifiedTime(),
+		Lifecycle:        t.Lifecycle(),
+		Size:             t.Size(),
+		RecordNum:        t.RecordNum(),
+		Columns:          toColumnInfo(schema.Columns),
+		PartitionColumns: toColumnInfo(t.PartitionColumns()),
+		TblProperties:    schema.TblProperties,
+		ViewText:         t.ViewText(),
+	}
+
+	cluster := schema.ClusterInfo
+	if cluster.ClusterType != "" || len(cluster.ClusterCols) > 0 {
+		sortCols := make([]string, len(cluster.SortCols))
+		for i, c := range cluster.SortCols {
+			sortCols[i] = c.Name + " " + string(c.Order)
+		}
+		info.Cluster = &ClusterInfo{
+			Type:      cluster.ClusterType,
+			Columns:   cluster.ClusterCols,
+			SortCols:  sortCols,
+			BucketNum: cluster.BucketNum,
+		}
+	}
+
+	if schema.IsExternal || schema.StorageHandler != "" || schema.Location != "" {
+		info.External = &ExternalInfo{
+			StorageHandler:  schema.StorageHandler,
+			Location:        schema.Location,
+			SerDeProperties: schema.SerDeProperties,
+		}
+	}
+
+	return info
+}
+
+func toColumnInfo(cols []tableschema.Column) []ColumnInfo {
+	infos := make([]ColumnInfo, len(cols))
+	for i, c := range cols {
+		typ := ""
+		if c.Type != nil {
+			typ = c.Type.String()
+		}
+		infos[i] = ColumnInfo{
+			Name:     c.Name,
+			Type:     typ,
+			Nullable: c.IsNullable,
+			Comment:  c.Comment,
+		}
+	}
+	return infos
+}
@@ -0,0 +1,62 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+func TestTableInfoShape(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	info := internal.TableInfo{
+		Name:             "proj.schema.table",
+		Type:             "EXTERNAL_TABLE",
+		CreatedTime:      created,
+		LastModifiedTime: created,
+		Lifecycle:        30,
+		Columns:          []internal.ColumnInfo{{Name: "id", Type: "BIGINT", Nullable: true}},
+		External: &internal.ExternalInfo{
+			StorageHandler:  "com.aliyun.odps.CsvStorageHandler",
+			Location:        "oss://bucket/path",
+			SerDeProperties: map[string]string{"odps.text.option.delimiter": ","},
+		},
+		TblProperties: map[string]string{"transactional": "true"},
+	}
+
+	t.Run("json keeps table properties at table level", func(t *testing.T) {
+		content, err := json.Marshal(info)
+		assert.NoError(t, err)
+
+		assert.JSONEq(t, `{
+  "name": "proj.schema.table",
+  "type": "EXTERNAL_TABLE",
+  "created_time": "2024-01-02T03:04:05Z",
+  "last_modified_time": "2024-01-02T03:04:05Z",
+  "lifecycle": 30,
+  "size": 0,
+  "record_num": 0,
+  "columns": [{"name": "id", "type": "BIGINT", "nullable": true}],
+  "external": {
+    "storage_handler": "com.aliyun.odps.CsvStorageHandler",
+    "location": "oss://bucket/path",
+    "serde_properties": {"odps.text.option.delimiter": ","}
+  },
+  "tbl_properties": {"transactional": "true"}
+}`, string(content))
+	})
+
+	t.Run("yaml uses the same keys", func(t *testing.T) {
+		content, err := yaml.Marshal(info)
+		assert.NoError(t, err)
+
+		var fields map[string]any
+		assert.NoError(t, yaml.Unmarshal(content, &fields))
+		assert.Equal(t, map[string]any{"transactional": "true"}, fields["tbl_properties"])
+		assert.NotContains(t, fields["external"], "tbl_properties")
+	})
+}
@@ -1,74 +1,302 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
 	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+
+	"github.com/sbchaos/opms/lib/printers/table"
 )
 
+const (
+	NullString = "NULL"
+
+	DateLayout      = "2006-01-02"
+	DateTimeLayout  = "2006-01-02 15:04:05"
+	TimestampLayout = "2006-01-02 15:04:05.999999999"
+)
+
+// Formatter converts the values read with the sql driver or the tunnel to text or to json values
+type Formatter struct {
+	// Location is used for DATETIME and TIMESTAMP values, nil keeps the location of the value.
+	// DATE and TIMESTAMP_NTZ have no timezone and are never converted.
+	Location *time.Location
+	// Precision is the number of decimals for FLOAT and DOUBLE, -1 for the shortest exact value
+	Precision int
+}
+
+var defaultFormatter = Formatter{Precision: -1}
+
+// NewFormatter returns the formatter for the timezone name, empty timezone keeps the location of values
+func NewFormatter(timezone string, precision int) (Formatter, error) {
+	f := Formatter{Precision: precision}
+	if timezone == "" {
+		return f, nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return f, fmt.Errorf("invalid timezone %s: %w", timezone, err)
+	}
+	f.Location = loc
+	return f, nil
+}
+
+// ToString formats the value with the default formatter
 func ToString(r any) string {
-	rr, ok := r.(sqldriver.NullAble)
-	if !ok {
-		return ForceString(r)
+	return defaultFormatter.ToString(r)
+}
+
+// ToString formats the value as text, nested types are formatted as json and null as NULL
+func (f Formatter) ToString(r any) string {
+	v := f.ToValue(r)
+	switch v := v.(type) {
+	case nil:
+		return NullString
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return f.formatFloat(float64(v), 32)
+	case float64:
+		return f.formatFloat(v, 64)
+	case json.Number:
+		return v.String()
 	}
-	if rr.IsNull() {
-		return "NULL"
+
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
 	}
+	return string(content)
+}
 
+// AddField adds the value to printer, printers supporting typed values get the json value
+func (f Formatter) AddField(printer table.Printer, r any) {
+	if vp, ok := printer.(table.ValuePrinter); ok {
+		vp.AddValue(f.ToValue(r))
+		return
+	}
+	printer.AddField(f.ToString(r))
+}
+
+// ToValue converts the value to a json compatible value: nil, bool, int64, float32, float64, string,
+// []any or map[string]any. FLOAT is kept as float32, dates and times are formatted as text,
+// decimals are kept exact.
+func (f Formatter) ToValue(r any) any {
 	switch r := r.(type) {
-	case *sqldriver.NullInt8:
-		return fmt.Sprintf("%d", r.Int8)
+	case nil:
+		return nil
+	case *any:
+		if r == nil {
+			return nil
+		}
+		return f.ToValue(*r)
+	case sqldriver.NullAble:
+		if isNilPointer(r) || r.IsNull() {
+			return nil
+		}
+		return f.nullableValue(r)
+	case data.Data:
+		if isNilPointer(r) {
+			return nil
+		}
+		return f.dataValue(r)
+	}
+	return f.goValue(r)
+}
 
+func (f Formatter) nullableValue(r sqldriver.NullAble) any {
+	switch r := r.(type) {
+	case *sqldriver.NullInt8:
+		return int64(r.Int8)
 	case *sqldriver.NullInt16:
-		return fmt.Sprintf("%d", r.Int16)
-
+		return int64(r.Int16)
 	case *sqldriver.NullInt32:
-		return fmt.Sprintf("%d", r.Int32)
-
+		return int64(r.Int32)
 	case *sqldriver.NullInt64:
-		return fmt.Sprintf("%d", r.Int64)
-
+		return r.Int64
 	case *sqldriver.NullFloat32:
-		return fmt.Sprintf("%f", r.Float32)
-
+		return r.Float32
 	case *sqldriver.NullFloat64:
-		return fmt.Sprintf("%f", r.Float64)
-
+		return r.Float64
 	case *sqldriver.NullString:
 		return r.String
-
 	case *sqldriver.NullBool:
-		return fmt.Sprintf("%v", r.Bool)
-
+		return r.Bool
+	case *sqldriver.NullDate:
+		return time.Time(r.Date).Format(DateLayout)
+	case *sqldriver.NullDateTime:
+		return f.inLocation(time.Time(r.DateTime)).Format(DateTimeLayout)
+	case *sqldriver.NullTimeStamp:
+		return f.inLocation(time.Time(r.TimeStamp)).Format(TimestampLayout)
+	case *sqldriver.NullTimeStampNtz:
+		return time.Time(r.TimeStampNtz).UTC().Format(TimestampLayout)
 	case *sqldriver.Binary:
-		return r.String()
+		return string(r.Binary)
+	}
 
-	case *data.Decimal:
-		return r.String()
+	// remaining driver types hold a data type or a string
+	if d, ok := r.(data.Data); ok {
+		return f.dataValue(d)
+	}
+	if s, ok := r.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%v", r)
+}
 
+func (f Formatter) dataValue(d data.Data) any {
+	switch d := d.(type) {
+	case data.NullData, *data.NullData:
+		return nil
+	case *data.Bool:
+		return bool(*d)
+	case *data.TinyInt:
+		return int64(*d)
+	case *data.SmallInt:
+		return int64(*d)
+	case *data.Int:
+		return int64(*d)
+	case *data.BigInt:
+		return int64(*d)
+	case *data.Float:
+		return float32(*d)
+	case *data.Double:
+		return float64(*d)
+	case *data.String:
+		return string(*d)
+	case *data.Binary:
+		return string(*d)
+	case *data.Date:
+		return time.Time(*d).Format(DateLayout)
+	case *data.DateTime:
+		return f.inLocation(time.Time(*d)).Format(DateTimeLayout)
+	case *data.Timestamp:
+		return f.inLocation(time.Time(*d)).Format(TimestampLayout)
+	case *data.TimestampNtz:
+		return time.Time(*d).UTC().Format(TimestampLayout)
+	case *data.Decimal:
+		return json.Number(d.Value())
+	case *data.Char, *data.VarChar:
+		return d.String()
+	case *data.Json:
+		var v any
+		if err := json.Unmarshal([]byte(d.GetData()), &v); err != nil {
+			return d.GetData()
+		}
+		return v
+	case *data.Array:
+		values := make([]any, 0)
+		for _, item := range d.ToSlice() {
+			values = append(values, f.ToValue(item))
+		}
+		return values
 	case *data.Map:
-		return r.String()
+		values := map[string]any{}
+		for k, v := range d.ToMap() {
+			values[f.ToString(k)] = f.ToValue(v)
+		}
+		return values
+	case *data.Struct:
+		values := map[string]any{}
+		for _, field := range d.Fields() {
+			values[field.Name] = f.ToValue(field.Value)
+		}
+		return values
+	}
+	return d.String()
+}
 
-	case *data.Array:
-		return r.String()
+func (f Formatter) goValue(r any) any {
+	// data types are scanned with pointer receivers, values are formatted through a copy
+	rv := reflect.ValueOf(r)
+	if rv.Kind() != reflect.Pointer {
+		ptr := reflect.New(rv.Type())
+		if d, ok := ptr.Interface().(data.Data); ok {
+			ptr.Elem().Set(rv)
+			return f.dataValue(d)
+		}
+	}
 
-	case *data.Struct:
+	switch r := r.(type) {
+	case string:
+		return r
+	case []byte:
+		return string(r)
+	case bool:
+		return r
+	case int:
+		return int64(r)
+	case int8:
+		return int64(r)
+	case int16:
+		return int64(r)
+	case int32:
+		return int64(r)
+	case int64:
+		return r
+	case float32:
+		return r
+	case float64:
+		return r
+	case time.Time:
+		return f.inLocation(r).Format(TimestampLayout)
+	case fmt.Stringer:
 		return r.String()
+	}
 
-	case *data.Json:
-		return r.String()
+	// pointers to the values are used when scanning rows
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		return f.ToValue(rv.Elem().Interface())
+	}
 
-	default:
-		s, ok1 := r.(string)
-		if !ok1 {
-			return ForceString(r)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = f.ToValue(rv.Index(i).Interface())
 		}
-		return s
+		return values
+	case reflect.Map:
+		values := map[string]any{}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			values[f.ToString(k.Interface())] = f.ToValue(rv.MapIndex(k).Interface())
+		}
+		return values
+	}
+	return fmt.Sprintf("%v", r)
+}
+
+// formatFloat formats FLOAT with bitSize 32, so the shortest value of 0.1 is not 0.10000000149011612
+func (f Formatter) formatFloat(v float64, bitSize int) string {
+	return strconv.FormatFloat(v, 'f', f.Precision, bitSize)
+}
+
+func (f Formatter) inLocation(t time.Time) time.Time {
+	if f.Location == nil {
+		return t
 	}
+	return t.In(f.Location)
 }
 
-func ForceString(v any) string {
-	fmt.Printf("null typecast %T %v\n", v, v)
-	return fmt.Sprintf("%v", v)
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
 }
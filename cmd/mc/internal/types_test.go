@@ -0,0 +1,175 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/datatype"
+	"github.com/aliyun/aliyun-odps-go-sdk/sqldriver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+)
+
+func TestFormatterToString(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	ts := time.Date(2024, 1, 2, 20, 30, 45, 123000000, time.UTC)
+
+	date := data.Date(time.Date(2024, 1, 2, 0, 0, 0, 0, jakarta))
+	dateTime := data.DateTime(ts)
+	timestamp := data.Timestamp(ts)
+	ntz := data.TimestampNtz(ts)
+	tiny := data.TinyInt(7)
+	double := data.Double(1.0 / 3)
+	str := data.String("abc")
+	float := data.Float(0.1)
+
+	char, err := data.NewChar(5, "ab")
+	assert.NoError(t, err)
+	varchar, err := data.NewVarChar(10, "a,b")
+	assert.NoError(t, err)
+
+	one := data.BigInt(1)
+	letter := data.String("a")
+	array := data.NewArrayWithType(datatype.NewArrayType(datatype.NewPrimitiveType(datatype.BIGINT)))
+	assert.NoError(t, array.Append(&one, &one))
+
+	mapping := data.NewMapWithType(datatype.NewMapType(datatype.NewPrimitiveType(datatype.STRING), datatype.NewPrimitiveType(datatype.FLOAT)))
+	assert.NoError(t, mapping.Set(&letter, &float))
+
+	structType := datatype.NewStructType(
+		datatype.NewStructFieldType("id", datatype.NewPrimitiveType(datatype.BIGINT)),
+		datatype.NewStructFieldType("tags", datatype.NewArrayType(datatype.NewPrimitiveType(datatype.BIGINT))),
+	)
+	record := data.NewStructWithTyp(structType)
+	assert.NoError(t, record.SetField("id", &one))
+	assert.NoError(t, record.SetField("tags", array))
+
+	var nilString *sqldriver.NullString
+	var empty any
+
+	tests := []struct {
+		name       string
+		formatter  internal.Formatter
+		useDefault bool
+		value      any
+		expected   string
+	}{
+		{name: "nil", useDefault: true, value: nil, expected: "NULL"},
+		{name: "nil driver value", useDefault: true, value: nilString, expected: "NULL"},
+		{name: "nil any", useDefault: true, value: &empty, expected: "NULL"},
+		{name: "null int", useDefault: true, value: &sqldriver.NullInt64{}, expected: "NULL"},
+		{name: "tinyint", useDefault: true, value: &sqldriver.NullInt8{Int8: -3, Valid: true}, expected: "-3"},
+		{name: "smallint", useDefault: true, value: &sqldriver.NullInt16{Int16: 300, Valid: true}, expected: "300"},
+		{name: "int", useDefault: true, value: &sqldriver.NullInt32{Int32: 70000, Valid: true}, expected: "70000"},
+		{name: "bigint", useDefault: true, value: &sqldriver.NullInt64{Int64: 1 << 40, Valid: true}, expected: "1099511627776"},
+		{name: "float", useDefault: true, value: &sqldriver.NullFloat32{Float32: 1.5, Valid: true}, expected: "1.5"},
+		{name: "float keeps the 32 bit value", useDefault: true, value: &sqldriver.NullFloat32{Float32: 0.1, Valid: true}, expected: "0.1"},
+		{name: "double", useDefault: true, value: &sqldriver.NullFloat64{Float64: 0.1, Valid: true}, expected: "0.1"},
+		{name: "double with zero precision", value: &sqldriver.NullFloat64{Float64: 3.7, Valid: true}, expected: "4"},
+		{name: "float with zero precision", value: &sqldriver.NullFloat32{Float32: 2.4, Valid: true}, expected: "2"},
+		{name: "float with precision", formatter: internal.Formatter{Precision: 3}, value: &sqldriver.NullFloat32{Float32: 0.1, Valid: true}, expected: "0.100"},
+		{
+			name:      "double with precision",
+			formatter: internal.Formatter{Precision: 2},
+			value:     &sqldriver.NullFloat64{Float64: 3.14159, Valid: true},
+			expected:  "3.14",
+		},
+		{name: "string", useDefault: true, value: &sqldriver.NullString{String: "a,b", Valid: true}, expected: "a,b"},
+		{name: "bool", useDefault: true, value: &sqldriver.NullBool{Bool: true, Valid: true}, expected: "true"},
+		{name: "binary", useDefault: true, value: &sqldriver.Binary{Binary: data.Binary("xyz"), Valid: true}, expected: "xyz"},
+		{name: "date", useDefault: true, value: &sqldriver.NullDate{Date: date, Valid: true}, expected: "2024-01-02"},
+		{name: "datetime", useDefault: true, value: &sqldriver.NullDateTime{DateTime: dateTime, Valid: true}, expected: "2024-01-02 20:30:45"},
+		{
+			name:      "datetime in timezone",
+			formatter: internal.Formatter{Location: jakarta, Precision: -1},
+			value:     &sqldriver.NullDateTime{DateTime: dateTime, Valid: true},
+			expected:  "2024-01-03 03:30:45",
+		},
+		{name: "timestamp", useDefault: true, value: &sqldriver.NullTimeStamp{TimeStamp: timestamp, Valid: true}, expected: "2024-01-02 20:30:45.123"},
+		{
+			name:      "timestamp ntz ignores timezone",
+			formatter: internal.Formatter{Location: jakarta, Precision: -1},
+			value:     &sqldriver.NullTimeStampNtz{TimeStampNtz: ntz, Valid: true},
+			expected:  "2024-01-02 20:30:45.123",
+		},
+		{
+			name:      "date ignores timezone",
+			formatter: internal.Formatter{Location: time.UTC, Precision: -1},
+			value:     &date,
+			expected:  "2024-01-02",
+		},
+		{name: "tunnel tinyint", useDefault: true, value: &tiny, expected: "7"},
+		{name: "tunnel tinyint value", useDefault: true, value: tiny, expected: "7"},
+		{name: "tunnel double", formatter: internal.Formatter{Precision: 4}, value: &double, expected: "0.3333"},
+		{name: "tunnel string", useDefault: true, value: &str, expected: "abc"},
+		{name: "tunnel float", useDefault: true, value: &float, expected: "0.1"},
+		{name: "tunnel char", useDefault: true, value: char, expected: "ab"},
+		{name: "tunnel varchar", useDefault: true, value: varchar, expected: "a,b"},
+		{name: "tunnel decimal", useDefault: true, value: data.NewDecimal(10, 2, "12.50"), expected: "12.50"},
+		{name: "tunnel decimal ignores precision", formatter: internal.Formatter{Precision: 0}, value: data.NewDecimal(10, 2, "12.50"), expected: "12.50"},
+		{name: "tunnel array", useDefault: true, value: array, expected: "[1,1]"},
+		{name: "tunnel map with float", useDefault: true, value: mapping, expected: `{"a":0.1}`},
+		{name: "tunnel struct", useDefault: true, value: record, expected: `{"id":1,"tags":[1,1]}`},
+		{name: "tunnel json", useDefault: true, value: data.NewJson(`{"b":[1,2],"a":null}`), expected: `{"a":null,"b":[1,2]}`},
+		{name: "tunnel invalid json", useDefault: true, value: data.NewJson("not json"), expected: "not json"},
+		{name: "tunnel timestamp", useDefault: true, value: &timestamp, expected: "2024-01-02 20:30:45.123"},
+		{name: "go int", useDefault: true, value: 42, expected: "42"},
+		{name: "go float32", useDefault: true, value: float32(0.1), expected: "0.1"},
+		{name: "go bytes", useDefault: true, value: []byte("raw"), expected: "raw"},
+		{name: "go slice", useDefault: true, value: []any{int64(1), "a", nil}, expected: `[1,"a",null]`},
+		{name: "go map", useDefault: true, value: map[string]any{"b": 2, "a": []string{"x"}}, expected: `{"a":["x"],"b":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.formatter
+			if tt.useDefault {
+				f = internal.Formatter{Precision: -1}
+			}
+			assert.Equal(t, tt.expected, f.ToString(tt.value))
+		})
+	}
+}
+
+func TestFormatterToValue(t *testing.T) {
+	f := internal.Formatter{Precision: -1}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{name: "null", value: &sqldriver.NullString{}, expected: nil},
+		{name: "int", value: &sqldriver.NullInt32{Int32: 5, Valid: true}, expected: int64(5)},
+		{name: "float", value: &sqldriver.NullFloat32{Float32: 1.5, Valid: true}, expected: float32(1.5)},
+		{name: "double", value: &sqldriver.NullFloat64{Float64: 2.5, Valid: true}, expected: 2.5},
+		{name: "bool", value: &sqldriver.NullBool{Bool: false, Valid: true}, expected: false},
+		{name: "string", value: &sqldriver.NullString{String: "x", Valid: true}, expected: "x"},
+		{
+			name:     "nested",
+			value:    map[string]any{"ids": []int{1, 2}},
+			expected: map[string]any{"ids": []any{int64(1), int64(2)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, f.ToValue(tt.value))
+		})
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	t.Run("returns error for unknown timezone", func(t *testing.T) {
+		_, err := internal.NewFormatter("Mars/Olympus", -1)
+		assert.ErrorContains(t, err, "invalid timezone")
+	})
+	t.Run("loads the timezone", func(t *testing.T) {
+		f, err := internal.NewFormatter("UTC", 3)
+		assert.NoError(t, err)
+		assert.Equal(t, time.UTC, f.Location)
+		assert.Equal(t, 3, f.Precision)
+	})
+}
@@ -3,9 +3,13 @@ package mc
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/mc/acl"
 	"github.com/sbchaos/opms/cmd/mc/function"
+	"github.com/sbchaos/opms/cmd/mc/partition"
 	"github.com/sbchaos/opms/cmd/mc/project"
+	"github.com/sbchaos/opms/cmd/mc/report"
 	"github.com/sbchaos/opms/cmd/mc/resource"
+	"github.com/sbchaos/opms/cmd/mc/schema"
 	"github.com/sbchaos/opms/cmd/mc/sql"
 	"github.com/sbchaos/opms/cmd/mc/tables"
 	"github.com/sbchaos/opms/cmd/mc/verify"
@@ -21,10 +25,14 @@ func NewMaxcomputeCommand(cfg *config.Config) *cobra.Command {
 
 	cmd.AddCommand(
 		project.NewProjectCommand(cfg),
+		schema.NewSchemaCommand(cfg),
 		tables.NewTableCommand(cfg),
+		partition.NewPartitionCommand(cfg),
 		resource.NewResourceCommand(cfg),
 		function.NewUDFCommand(cfg),
 		sql.NewSQLCommand(cfg),
+		report.NewReportCommand(cfg),
+		acl.NewACLCommand(cfg),
 
 		verify.NewVerifyCommand(cfg),
 	)
@@ -0,0 +1,79 @@
+package partition
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+)
+
+type addCommand struct {
+	cfg *config.Config
+
+	name       string
+	partitions []string
+}
+
+// NewAddCommand adds partitions to a table
+func NewAddCommand(cfg *config.Config) *cobra.Command {
+	add := &addCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "add",
+		Short:   "Add partitions to a table if they do not exist",
+		Example: `opms mc partitions add -n proj.schema.table -p dt=2024-01-01 -p dt=2024-01-02`,
+		RunE:    add.RunE,
+	}
+
+	cmd.Flags().StringVarP(&add.name, "name", "n", "", "Table name")
+	cmd.Flags().StringArrayVarP(&add.partitions, "partition", "p", nil, "Partition to add as key=value, can be repeated")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *addCommand) RunE(_ *cobra.Command, _ []string) error {
+	if len(r.partitions) == 0 {
+		return errors.New("at least one --partition is required")
+	}
+
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	tabl, err := loadTable(provider, tab)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range r.partitions {
+		spec, err := internal.ParsePartitionSpec([]string{p})
+		if err != nil {
+			return err
+		}
+
+		err = spec.Validate(tabl.PartitionColumns())
+		if err != nil {
+			return err
+		}
+
+		err = internal.Retry(3, func() error {
+			return tabl.AddPartition(true, spec.String())
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add partition %s: %w", spec.String(), err)
+		}
+		fmt.Printf("Added partition %s\n", spec.String())
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package partition
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+type dropCommand struct {
+	cfg *config.Config
+
+	name    string
+	pattern []string
+	from    string
+	to      string
+	key     string
+	dryRun  bool
+	yes     bool
+}
+
+// NewDropCommand drops the partitions matching a pattern or a date range
+func NewDropCommand(cfg *config.Config) *cobra.Command {
+	drop := &dropCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "drop",
+		Short: "Drop partitions matching a spec pattern or a date range",
+		Example: `opms mc partitions drop -n proj.schema.table -p "dt=2023-*" --dry-run
+opms mc partitions drop -n proj.schema.table --from 2024-01-01 --to 2024-01-31 --key dt`,
+		RunE: drop.RunE,
+	}
+
+	cmd.Flags().StringVarP(&drop.name, "name", "n", "", "Table name")
+	cmd.Flags().StringArrayVarP(&drop.pattern, "partition", "p", nil, "Spec of partitions to drop, values can use glob patterns")
+	cmd.Flags().StringVar(&drop.from, "from", "", "Start date of the range to drop (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&drop.to, "to", "", "End date of the range to drop, inclusive (YYYY-MM-DD)")
+	cmd.Flags().StringVarP(&drop.key, "key", "k", "", "Partition column with the date, defaults to the first partition column")
+	cmd.Flags().BoolVar(&drop.dryRun, "dry-run", false, "Only show the partitions which will be dropped")
+	cmd.Flags().BoolVarP(&drop.yes, "yes", "y", false, "Drop without asking for confirmation")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *dropCommand) RunE(_ *cobra.Command, _ []string) error {
+	if len(r.pattern) == 0 && r.from == "" && r.to == "" {
+		return errors.New("either --partition or --from and --to are required")
+	}
+	if (r.from == "") != (r.to == "") {
+		return errors.New("--from and --to should be used together")
+	}
+
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
+	pattern, err := internal.ParsePartitionSpec(r.pattern)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	tabl, err := loadTable(provider, tab)
+	if err != nil {
+		return err
+	}
+
+	err = pattern.Validate(tabl.PartitionColumns())
+	if err != nil {
+		return err
+	}
+
+	var key string
+	dates := map[time.Time]bool{}
+	if r.from != "" {
+		key, err = dateKey(tabl, r.key)
+		if err != nil {
+			return err
+		}
+
+		days, err := dateRange(r.from, r.to)
+		if err != nil {
+			return err
+		}
+		for _, d := range days {
+			dates[d] = true
+		}
+	}
+
+	parts, err := listPartitions(tabl)
+	if err != nil {
+		return err
+	}
+
+	layout := dateLayout
+	if key != "" {
+		var values []string
+		for _, p := range parts {
+			if value, ok := p.spec.Get(key); ok {
+				values = append(values, value)
+			}
+		}
+		layout, err = detectLayout(values)
+		if err != nil {
+			return fmt.Errorf("can not use --from and --to with %s: %w", key, err)
+		}
+	}
+
+	var toDrop []internal.PartitionSpec
+	for _, p := range parts {
+		if !p.spec.Matches(pattern) {
+			continue
+		}
+		if key != "" {
+			value, _ := p.spec.Get(key)
+			parsed, err := time.Parse(layout, value)
+			if err != nil || !dates[truncate(parsed, dateLayout)] {
+				continue
+			}
+		}
+		toDrop = append(toDrop, p.spec)
+	}
+
+	if len(toDrop) == 0 {
+		fmt.Println("No partitions matched")
+		return nil
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"#", "Partition"})
+	for i, spec := range toDrop {
+		printer.AddField(strconv.Itoa(i + 1))
+		printer.AddField(spec.String())
+		printer.EndRow()
+	}
+	err = printer.Render()
+	if err != nil {
+		return err
+	}
+
+	if r.dryRun {
+		return nil
+	}
+
+	message := fmt.Sprintf("Drop %s from %s? [y/N]: ", text.Pluralize(len(toDrop), "partition"), tab.String())
+	if !r.yes && !internal.Confirm(message) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	failed := 0
+	for _, spec := range toDrop {
+		entry := internal.AuditEntry{
+			Action:  "drop_partition",
+			Target:  tab.String(),
+			Details: spec.String(),
+			Status:  "dropped",
+		}
+
+		err = internal.Retry(3, func() error {
+			return tabl.DeletePartition(true, spec.String())
+		})
+		if err != nil {
+			failed++
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			fmt.Printf(" ❌ %s: %s\n", spec.String(), err)
+		} else {
+			fmt.Printf(" ✅ %s\n", spec.String())
+		}
+
+		err = internal.WriteAudit(r.cfg, entry)
+		if err != nil {
+			fmt.Printf("[WARN] failed to write audit for %s: %s\n", spec.String(), err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to drop %s", text.Pluralize(failed, "partition"))
+	}
+	return nil
+}
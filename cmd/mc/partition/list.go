@@ -0,0 +1,135 @@
+package partition
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/pool"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+type listCommand struct {
+	cfg *config.Config
+
+	name    string
+	filter  []string
+	format  string
+	workers int
+}
+
+// NewListCommand lists the partitions of a table with their details
+func NewListCommand(cfg *config.Config) *cobra.Command {
+	list := &listCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List partitions of a table with record count and size",
+		Example: `opms mc partitions list -n proj.schema.table
+opms mc partitions list -n proj.schema.table -p "dt=2024-01-*"`,
+		RunE: list.RunE,
+	}
+
+	cmd.Flags().StringVarP(&list.name, "name", "n", "", "Table name")
+	cmd.Flags().StringArrayVarP(&list.filter, "partition", "p", nil, "Only list partitions matching the spec, values can use glob patterns")
+	cmd.Flags().StringVarP(&list.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().IntVarP(&list.workers, "workers", "w", 5, "Number of partitions to load in parallel")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
+	pattern, err := internal.ParsePartitionSpec(r.filter)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	tabl, err := loadTable(provider, tab)
+	if err != nil {
+		return err
+	}
+
+	err = pattern.Validate(tabl.PartitionColumns())
+	if err != nil {
+		return err
+	}
+
+	parts, err := listPartitions(tabl)
+	if err != nil {
+		return err
+	}
+
+	var matched []partitionInfo
+	for _, p := range parts {
+		if p.spec.Matches(pattern) {
+			matched = append(matched, p)
+		}
+	}
+
+	jobs := make(chan pool.Job[partitionInfo], 20)
+	go func() {
+		for _, p := range matched {
+			jobs <- func() pool.JobResult[partitionInfo] {
+				return pool.JobResult[partitionInfo]{Output: p, Err: internal.Retry(3, p.partition.Load)}
+			}
+		}
+		close(jobs)
+	}()
+
+	var results []pool.JobResult[partitionInfo]
+	for res := range pool.StartPool(r.workers, jobs) {
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Output.spec.String() < results[j].Output.spec.String()
+	})
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Partition", "Records", "Size", "Last Modified"})
+
+	for _, res := range results {
+		p := res.Output
+		printer.AddField(p.spec.String())
+		if res.Err != nil {
+			printer.AddField("error: " + res.Err.Error())
+			printer.AddField("")
+			printer.AddField("")
+		} else {
+			printer.AddField(strconv.Itoa(p.partition.RecordNum()))
+			printer.AddField(text.HumanBytes(int64(p.partition.Size())))
+			printer.AddField(p.partition.LastModifiedTime().String())
+		}
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print partitions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package partition
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+)
+
+const dateLayout = "2006-01-02"
+
+// partitionLayouts are the layouts of partition values which are dates or hours
+var partitionLayouts = []string{
+	dateLayout,
+	"20060102",
+	"2006-01-02 15",
+	"2006-01-02T15",
+	"2006010215",
+	"2006-01-02 15:04:05",
+}
+
+var errNotDates = errors.New("partition values are not dates")
+
+// NewPartitionCommand initializes command for partitions
+func NewPartitionCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "partitions",
+		Aliases: []string{"partition"},
+		Short:   "Commands that will let the user to operate on partitions of a table",
+		Example: "opms mc partitions [sub-command]",
+	}
+	cmd.AddCommand(
+		NewListCommand(cfg),
+		NewAddCommand(cfg),
+		NewDropCommand(cfg),
+		NewStatsCommand(cfg),
+	)
+
+	return cmd
+}
+
+type partitionInfo struct {
+	partition *odps.Partition
+	spec      internal.PartitionSpec
+}
+
+func loadTable(provider *mcc.ClientProvider, tab names.Table) (*odps.Table, error) {
+	client, err := provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	tabl := client.Table(tab.TableID)
+	err = internal.Retry(3, tabl.Load)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table %s: %w", tab.String(), err)
+	}
+	if len(tabl.PartitionColumns()) == 0 {
+		return nil, fmt.Errorf("table %s is not partitioned", tab.String())
+	}
+	return tabl, nil
+}
+
+func listPartitions(tabl *odps.Table) ([]partitionInfo, error) {
+	var parts []odps.Partition
+	err := internal.Retry(3, func() error {
+		var err error
+		parts, err = tabl.GetPartitions()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	infos := make([]partitionInfo, 0, len(parts))
+	for i := range parts {
+		spec, err := internal.ParsePartitionSpec([]string{parts[i].Value()})
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, partitionInfo{partition: &parts[i], spec: spec})
+	}
+	return infos, nil
+}
+
+// dateKey returns the partition column used for dates, by default the first partition column
+func dateKey(tabl *odps.Table, key string) (string, error) {
+	cols := tabl.PartitionColumns()
+	if key == "" {
+		return cols[0].Name, nil
+	}
+
+	for _, c := range cols {
+		if strings.EqualFold(c.Name, key) {
+			return c.Name, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a partition column of %s", key, tabl.Name())
+}
+
+// dateRange returns all the days between from and to, both inclusive
+func dateRange(from, to string) ([]time.Time, error) {
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %s: %w", from, err)
+	}
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %s: %w", to, err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("to date %s is before from date %s", to, from)
+	}
+
+	var days []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// detectLayout returns the layout which parses most of the values, values of hourly partitions
+// use a layout with the hour. Values which are not dates, like __HIVE_DEFAULT_PARTITION__, are
+// ignored by the caller as they do not parse with the layout.
+func detectLayout(values []string) (string, error) {
+	if len(values) == 0 {
+		return dateLayout, nil
+	}
+
+	best, bestCount := "", 0
+	for _, layout := range partitionLayouts {
+		count := 0
+		for _, v := range values {
+			if _, err := time.Parse(layout, v); err == nil {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = layout, count
+		}
+	}
+	if bestCount == 0 {
+		return "", errNotDates
+	}
+	return best, nil
+}
+
+func isHourly(layout string) bool {
+	return strings.Contains(layout, "15")
+}
+
+// truncate returns the start of the day or hour of the value for the layout
+func truncate(t time.Time, layout string) time.Time {
+	if isHourly(layout) {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,210 @@
+package partition
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+type statsCommand struct {
+	cfg *config.Config
+
+	name     string
+	fileName string
+	from     string
+	to       string
+	key      string
+	format   string
+
+	allowSkipped bool
+}
+
+// NewStatsCommand reports the missing daily partitions for tables
+func NewStatsCommand(cfg *config.Config) *cobra.Command {
+	stats := &statsCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Report missing daily partitions between two dates",
+		Long: `Checks that every day between --from and --to has a partition in the tables,
+exits with an error when any table has a missing partition. The layout of partition values
+is detected, like 2024-01-02 or 20240102, every hour is expected for hourly partitions like
+2024-01-02 15, values which are not dates like __HIVE_DEFAULT_PARTITION__ are ignored.
+Tables without any date value are skipped and reported as errors unless --allow-skipped is set.`,
+		Example: `opms mc partitions stats -n proj.schema.table --from 2024-01-01 --to 2024-01-31
+opms mc partitions stats -f tables.txt --from 2024-01-01 --to 2024-01-31 --format csv`,
+		RunE: stats.RunE,
+	}
+
+	cmd.Flags().StringVarP(&stats.name, "name", "n", "", "Table name")
+	cmd.Flags().StringVarP(&stats.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
+	cmd.Flags().StringVar(&stats.from, "from", "", "Start date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&stats.to, "to", "", "End date, inclusive (YYYY-MM-DD)")
+	cmd.Flags().StringVarP(&stats.key, "key", "k", "", "Partition column with the date, defaults to the first partition column")
+	cmd.Flags().StringVarP(&stats.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().BoolVar(&stats.allowSkipped, "allow-skipped", false, "Do not fail for tables with partition values which are not dates")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func (r *statsCommand) RunE(_ *cobra.Command, _ []string) error {
+	var tableNames []string
+	if r.name != "" {
+		tableNames = []string{r.name}
+	}
+
+	if r.fileName != "" {
+		lines, err := cmdutil.ReadLines(r.fileName, os.Stdin)
+		if err != nil {
+			return err
+		}
+		tableNames = lines
+	}
+
+	if len(tableNames) == 0 {
+		return errors.New("either --name or --filename is required")
+	}
+
+	days, err := dateRange(r.from, r.to)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Table", "Expected", "Present", "Missing", "Missing Dates"})
+
+	incomplete, skipped := 0, 0
+	for _, name := range tableNames {
+		printer.AddField(name)
+
+		expected, missing, err := r.missing(provider, name, days)
+		switch {
+		case errors.Is(err, errNotDates):
+			skipped++
+			printer.AddField("")
+			printer.AddField("")
+			printer.AddField("")
+			printer.AddField("skipped: " + err.Error())
+			printer.EndRow()
+			continue
+		case err != nil:
+			incomplete++
+			printer.AddField("")
+			printer.AddField("")
+			printer.AddField("")
+			printer.AddField("error: " + err.Error())
+			printer.EndRow()
+			continue
+		}
+
+		if len(missing) > 0 {
+			incomplete++
+		}
+		printer.AddField(strconv.Itoa(expected))
+		printer.AddField(strconv.Itoa(expected - len(missing)))
+		printer.AddField(strconv.Itoa(len(missing)))
+		printer.AddField(strings.Join(missing, ", "))
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print stats: %w", err)
+	}
+
+	if incomplete > 0 {
+		return fmt.Errorf("%s with missing partitions", text.Pluralize(incomplete, "table"))
+	}
+	if skipped > 0 && !r.allowSkipped {
+		return fmt.Errorf("%s skipped as partition values are not dates, use --allow-skipped to ignore them", text.Pluralize(skipped, "table"))
+	}
+	return nil
+}
+
+// missing returns the number of expected partitions and the missing ones in the layout of
+// partition values. Every hour of the days is expected for hourly partitions.
+func (r *statsCommand) missing(provider *mcc.ClientProvider, name string, days []time.Time) (int, []string, error) {
+	tab, err := names.FromTableName(name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	tabl, err := loadTable(provider, tab)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	key, err := dateKey(tabl, r.key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	parts, err := listPartitions(tabl)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var values []string
+	for _, p := range parts {
+		if value, ok := p.spec.Get(key); ok {
+			values = append(values, value)
+		}
+	}
+
+	layout, err := detectLayout(values)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s of %s: %w", key, name, err)
+	}
+
+	present := map[time.Time]bool{}
+	for _, v := range values {
+		parsed, err := time.Parse(layout, v)
+		if err != nil {
+			continue
+		}
+		present[truncate(parsed, layout)] = true
+	}
+
+	expected := days
+	if isHourly(layout) {
+		expected = nil
+		for _, d := range days {
+			for h := 0; h < 24; h++ {
+				expected = append(expected, d.Add(time.Duration(h)*time.Hour))
+			}
+		}
+	}
+
+	var missing []string
+	for _, e := range expected {
+		if !present[e] {
+			missing = append(missing, e.Format(layout))
+		}
+	}
+	return len(expected), missing, nil
+}
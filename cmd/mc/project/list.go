@@ -33,7 +33,12 @@ func NewListCommand(cfg *config.Config) *cobra.Command {
 }
 
 func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient("")
 	if err != nil {
 		return err
 	}
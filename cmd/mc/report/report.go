@@ -0,0 +1,20 @@
+package report
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// NewReportCommand initializes command for reports
+func NewReportCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "report",
+		Short:   "Reports on the usage of a project",
+		Example: "opms mc report [sub-command]",
+	}
+	cmd.AddCommand(
+		NewStorageCommand(cfg),
+	)
+	return cmd
+}
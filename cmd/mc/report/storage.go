@@ -0,0 +1,191 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+const (
+	sortSize     = "size"
+	sortRecords  = "records"
+	sortModified = "modified"
+	sortName     = "name"
+)
+
+type tableUsage struct {
+	schema       string
+	name         string
+	tableType    string
+	size         int64
+	records      int
+	lifecycle    int
+	lastModified time.Time
+}
+
+func (u tableUsage) staleDays(now time.Time) int {
+	return int(now.Sub(u.lastModified).Hours() / 24)
+}
+
+type storageCommand struct {
+	cfg *config.Config
+
+	project   string
+	schema    string
+	minSize   string
+	staleDays int
+	noLife    bool
+	sortBy    string
+	limit     int
+	format    string
+	workers   int
+}
+
+func NewStorageCommand(cfg *config.Config) *cobra.Command {
+	storage := &storageCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "storage",
+		Short: "Report the size, records and lifecycle of tables",
+		Long: `Report the storage of the tables in a project, the thresholds are combined so
+--stale-days 90 --min-size 10GB lists the tables over 10GB not modified in 90 days.`,
+		Example: `opms mc report storage -p proj > storage.csv
+opms mc report storage -p proj -s "*" --stale-days 90 --min-size 10GB --sort size
+opms mc report storage -p proj --no-lifecycle --format table --limit 20`,
+		RunE: storage.RunE,
+	}
+
+	cmd.Flags().StringVarP(&storage.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&storage.schema, "schema", "s", "", "Schema, * for all schemas of the project")
+	cmd.Flags().StringVar(&storage.minSize, "min-size", "", "Only tables with at least this size, eg 500MB, 10GB")
+	cmd.Flags().IntVar(&storage.staleDays, "stale-days", 0, "Only tables not modified in these many days")
+	cmd.Flags().BoolVar(&storage.noLife, "no-lifecycle", false, "Only tables without a lifecycle")
+	cmd.Flags().StringVar(&storage.sortBy, "sort", sortSize, "Sort by: size, records, modified, name")
+	cmd.Flags().IntVarP(&storage.limit, "limit", "l", 0, "Maximum number of tables in report, 0 for all")
+	cmd.Flags().StringVarP(&storage.format, "format", "o", table.FormatCSV, "Output format: table, csv, json, jsonl")
+	cmd.Flags().IntVarP(&storage.workers, "workers", "w", 5, "Number of tables to load in parallel")
+	return cmd
+}
+
+func (r *storageCommand) RunE(_ *cobra.Command, _ []string) error {
+	var minSize int64
+	if r.minSize != "" {
+		size, err := text.ParseBytes(r.minSize)
+		if err != nil {
+			return err
+		}
+		minSize = size
+	}
+
+	less, err := sortFunc(r.sortBy)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	schemas, err := internal.ResolveSchemas(client, proj, r.schema)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var usages []tableUsage
+	var errs []string
+	for _, schema := range schemas {
+		tables, listErrs := internal.LoadTables(client, proj, schema, r.workers)
+		errs = append(errs, listErrs...)
+
+		for _, t := range tables {
+			u := tableUsage{
+				schema:       schema,
+				name:         t.Name(),
+				tableType:    t.Type().String(),
+				size:         t.Size(),
+				records:      t.RecordNum(),
+				lifecycle:    t.Lifecycle(),
+				lastModified: t.LastModifiedTime(),
+			}
+			if u.size < minSize || (r.staleDays > 0 && u.staleDays(now) < r.staleDays) || (r.noLife && u.lifecycle > 0) {
+				continue
+			}
+			usages = append(usages, u)
+		}
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool {
+		return less(usages[i], usages[j])
+	})
+	if r.limit > 0 && len(usages) > r.limit {
+		usages = usages[:r.limit]
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Project", "Schema", "Table", "Type", "Size Bytes", "Size", "Records", "Lifecycle", "Last Modified", "Days Since Modified"})
+
+	for _, u := range usages {
+		printer.AddField(proj)
+		printer.AddField(u.schema)
+		printer.AddField(u.name)
+		printer.AddField(u.tableType)
+		printer.AddField(strconv.FormatInt(u.size, 10))
+		printer.AddField(text.HumanBytes(u.size))
+		printer.AddField(strconv.Itoa(u.records))
+		printer.AddField(strconv.Itoa(u.lifecycle))
+		printer.AddField(u.lastModified.Format(time.DateTime))
+		printer.AddField(strconv.Itoa(u.staleDays(now)))
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print report: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to load some tables: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// sortFunc returns the ordering for the report, largest, most rows and oldest come first
+func sortFunc(sortBy string) (func(a, b tableUsage) bool, error) {
+	switch strings.ToLower(sortBy) {
+	case sortSize:
+		return func(a, b tableUsage) bool { return a.size > b.size }, nil
+	case sortRecords:
+		return func(a, b tableUsage) bool { return a.records > b.records }, nil
+	case sortModified:
+		return func(a, b tableUsage) bool { return a.lastModified.Before(b.lastModified) }, nil
+	case sortName:
+		return func(a, b tableUsage) bool { return a.schema+"."+a.name < b.schema+"."+b.name }, nil
+	}
+	return nil, fmt.Errorf("unknown sort %s, use size, records, modified or name", sortBy)
+}
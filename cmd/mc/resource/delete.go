@@ -0,0 +1,92 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	"github.com/sbchaos/opms/lib/config"
+)
+
+type deleteCommand struct {
+	cfg *config.Config
+
+	project string
+	schema  string
+
+	names []string
+	yes   bool
+}
+
+func NewDeleteCommand(cfg *config.Config) *cobra.Command {
+	del := &deleteCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete the resources",
+		Long: `Delete the resources after confirmation, every delete is recorded in
+the audit file under the cache directory.`,
+		Example: "opms mc resource delete -n old_udf.jar,old_lib.py",
+		RunE:    del.RunE,
+	}
+
+	cmd.Flags().StringVarP(&del.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&del.schema, "schema", "s", "", "Schema")
+
+	cmd.Flags().StringSliceVarP(&del.names, "name", "n", nil, "Resource names, comma separated")
+	cmd.Flags().BoolVarP(&del.yes, "yes", "y", false, "Delete without asking for confirmation")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *deleteCommand) RunE(_ *cobra.Command, _ []string) error {
+	client, err := newClient(r.cfg, r.project, r.schema)
+	if err != nil {
+		return err
+	}
+
+	proj := client.DefaultProjectName()
+	if !r.yes {
+		msg := fmt.Sprintf("Delete %d resources from %s: %s? [y/N] ", len(r.names), proj, strings.Join(r.names, ", "))
+		if !internal.Confirm(msg) {
+			fmt.Println("Aborted")
+			return nil
+		}
+	}
+
+	res := odps.NewResources(client)
+
+	var failed []string
+	var entries []internal.AuditEntry
+	for _, name := range r.names {
+		entry := internal.AuditEntry{
+			Action: "delete_resource",
+			Target: proj + "." + client.CurrentSchemaName() + "." + name,
+			Status: "success",
+		}
+
+		err = res.Delete(name)
+		if err != nil {
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			failed = append(failed, fmt.Sprintf("%s: %s", name, err))
+		} else {
+			fmt.Printf("Deleted %s\n", name)
+		}
+		entries = append(entries, entry)
+	}
+
+	err = internal.WriteAudit(r.cfg, entries...)
+	if err != nil {
+		fmt.Printf("failed to write audit log: %s\n", err)
+	}
+
+	if len(failed) > 0 {
+		return errors.New("failed to delete resources:\n" + strings.Join(failed, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package resource
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/common"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+type downloadCommand struct {
+	cfg *config.Config
+
+	project string
+	schema  string
+
+	name  string
+	out   string
+	force bool
+}
+
+func NewDownloadCommand(cfg *config.Config) *cobra.Command {
+	download := &downloadCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "download",
+		Short: "Download the content of a file resource",
+		Long: `Download the content of a file resource. The content is written to a temporary file
+next to the output, which is renamed on success, an existing file is only replaced with --force.`,
+		Example: "opms mc resource download -n udf.jar -o ./udf.jar --force",
+		RunE:    download.RunE,
+	}
+
+	cmd.Flags().StringVarP(&download.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&download.schema, "schema", "s", "", "Schema")
+
+	cmd.Flags().StringVarP(&download.name, "name", "n", "", "Resource name")
+	cmd.Flags().StringVarP(&download.out, "out", "o", "", "File to write, defaults to resource name")
+	cmd.Flags().BoolVar(&download.force, "force", false, "Overwrite the file if it exists")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *downloadCommand) RunE(_ *cobra.Command, _ []string) error {
+	client, err := newClient(r.cfg, r.project, r.schema)
+	if err != nil {
+		return err
+	}
+
+	out := r.out
+	if out == "" {
+		out = r.name
+	}
+	if _, err := os.Stat(out); err == nil && !r.force {
+		return fmt.Errorf("file %s already exists, use --force to overwrite", out)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// removing the temp file fails after it is renamed
+	defer os.Remove(f.Name())
+
+	// temp files are only readable by the owner
+	err = f.Chmod(0o644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	rb := common.NewResourceBuilder(client.DefaultProjectName())
+	query := url.Values{}
+	query.Set("curr_schema", client.CurrentSchemaName())
+
+	written := int64(0)
+	restClient := client.RestClient()
+	err = restClient.GetWithParseFunc(rb.Resource(r.name), query, func(res *http.Response) error {
+		n, err := io.Copy(f, res.Body)
+		written = n
+		return err
+	})
+	closeErr := f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to download resource %s: %w", r.name, err)
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	err = os.Rename(f.Name(), out)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Downloaded %s into %s, %d bytes\n", r.name, out, written)
+	return nil
+}
@@ -0,0 +1,85 @@
+package resource
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+type infoCommand struct {
+	cfg *config.Config
+
+	project string
+	schema  string
+
+	names  []string
+	format string
+}
+
+func NewInfoCommand(cfg *config.Config) *cobra.Command {
+	info := &infoCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "info",
+		Short:   "Show the details of resources",
+		Example: "opms mc resource info -n udf.jar,lib.py",
+		RunE:    info.RunE,
+	}
+
+	cmd.Flags().StringVarP(&info.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&info.schema, "schema", "s", "", "Schema")
+
+	cmd.Flags().StringSliceVarP(&info.names, "name", "n", nil, "Resource names, comma separated")
+	cmd.Flags().StringVarP(&info.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *infoCommand) RunE(_ *cobra.Command, _ []string) error {
+	client, err := newClient(r.cfg, r.project, r.schema)
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Name", "Type", "Owner", "Size", "Created", "Last Modified", "Checksum", "Comment"})
+
+	res := odps.NewResources(client)
+	for _, name := range r.names {
+		resource := res.Get(name)
+		err = resource.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load resource %s: %w", name, err)
+		}
+
+		printer.AddField(resource.Name())
+		printer.AddField(odps.ResourceTypeToStr(resource.ResourceType()))
+		printer.AddField(resource.Owner())
+		printer.AddField(text.HumanBytes(int64(resource.Size())))
+		printer.AddField(resource.CreatedTime().Format(time.DateTime))
+		printer.AddField(resource.LastModifiedTime().Format(time.DateTime))
+		printer.AddField(resource.ContentMD5())
+		printer.AddField(resource.Comment())
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print resources: %w", err)
+	}
+	return nil
+}
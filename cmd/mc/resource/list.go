@@ -7,6 +7,7 @@ import (
 	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/mc/internal"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/config"
 	"github.com/sbchaos/opms/lib/printers/table"
@@ -33,32 +34,29 @@ func NewListCommand(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&list.project, "project", "p", "", "Project")
-	cmd.Flags().StringVarP(&list.schema, "schema", "s", "", "Schema")
+	cmd.Flags().StringVarP(&list.schema, "schema", "s", "", "Schema, * for all schemas of the project")
 
 	cmd.Flags().StringVarP(&list.namePrefix, "prefix", "n", "", "Resource name prefix")
 	return cmd
 }
 
 func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	provider, err := mcc.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
 	}
 
-	schema := "default"
-	if r.schema != "" {
-		schema = r.schema
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
 	}
-
 	proj := client.DefaultProjectName()
-	if r.project != "" {
-		proj = r.project
-	}
 
-	client.SetDefaultProjectName(proj)
-	client.SetCurrentSchemaName(schema)
+	schemas, err := internal.ResolveSchemas(client, proj, r.schema)
+	if err != nil {
+		return err
+	}
 
-	res := odps.NewResources(client)
 	var filters []odps.RFileFunc = nil
 
 	if r.namePrefix != "" {
@@ -70,9 +68,17 @@ func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
 	size, _ := t.Size(120)
 
 	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	fn := setupPrinter(printer)
+	withSchema := r.schema == internal.AllSchemas
+	if withSchema {
+		printer.AddHeader([]string{"Schema", "Resource Name", "Type"})
+	} else {
+		printer.AddHeader([]string{"Resource Name", "Type"})
+	}
 
-	res.List(fn, filters...)
+	for _, schema := range schemas {
+		res := odps.NewResources(client, proj, schema)
+		res.List(setupPrinter(printer, schema, withSchema), filters...)
+	}
 
 	err = printer.Render()
 	if err != nil {
@@ -81,15 +87,16 @@ func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func setupPrinter(printer table.Printer) func(*odps.Resource, error) {
-	printer.AddHeader([]string{"Resource Name", "Type"})
-
+func setupPrinter(printer table.Printer, schema string, withSchema bool) func(*odps.Resource, error) {
 	return func(r *odps.Resource, err error) {
 		if err != nil {
 			fmt.Printf("[ERROR] %s\n", err)
 			return
 		}
 
+		if withSchema {
+			printer.AddField(schema)
+		}
 		printer.AddField(r.Name())
 		printer.AddField(odps.ResourceTypeToStr(r.ResourceType()))
 		printer.EndRow()
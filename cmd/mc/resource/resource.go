@@ -1,11 +1,22 @@
 package resource
 
 import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
 
+	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/config"
 )
 
+const defaultSchema = "default"
+
 // NewResourceCommand initializes command for resource
 func NewResourceCommand(cfg *config.Config) *cobra.Command {
 	cmd := &cobra.Command{
@@ -15,6 +26,81 @@ func NewResourceCommand(cfg *config.Config) *cobra.Command {
 	}
 	cmd.AddCommand(
 		NewListCommand(cfg),
+		NewUploadCommand(cfg),
+		NewDownloadCommand(cfg),
+		NewDeleteCommand(cfg),
+		NewInfoCommand(cfg),
+		NewSyncCommand(cfg),
 	)
 	return cmd
 }
+
+// newClient returns the client with the project and schema set, defaults are used when empty
+func newClient(cfg *config.Config, project, schema string) (*odps.Odps, error) {
+	provider, err := mcc.NewClientProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := provider.GetClient(project)
+	if err != nil {
+		return nil, err
+	}
+	if schema == "" {
+		schema = defaultSchema
+	}
+	client.SetCurrentSchemaName(schema)
+	return client, nil
+}
+
+// typeFromFile guesses the resource type from the extension of the file
+func typeFromFile(fileName string) odps.ResourceType {
+	name := strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(name, ".jar"):
+		return odps.ResourceTypeJar
+	case strings.HasSuffix(name, ".py"):
+		return odps.ResourceTypePy
+	case strings.HasSuffix(name, ".zip"), strings.HasSuffix(name, ".tgz"),
+		strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tar"):
+		return odps.ResourceTypeArchive
+	}
+	return odps.ResourceTypeFile
+}
+
+func uploadFile(client *odps.Odps, fileName, name, comment string, resType odps.ResourceType, overwrite bool) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if name == "" {
+		name = filepath.Base(fileName)
+	}
+
+	fr := odps.NewFileResource(name, resType)
+	fr.SetReader(f)
+	if comment != "" {
+		fr.SetComment(comment)
+	}
+
+	res := odps.NewResources(client)
+	return res.CreateFileResource(client.DefaultProjectName(), client.CurrentSchemaName(), fr, overwrite)
+}
+
+// fileMD5 returns the checksum of the file in the same format as the content md5 of resource
+func fileMD5(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,129 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+type syncCommand struct {
+	cfg *config.Config
+
+	project string
+	schema  string
+
+	dir    string
+	dryRun bool
+}
+
+func NewSyncCommand(cfg *config.Config) *cobra.Command {
+	sync := &syncCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Upload the files of a directory as resources when changed",
+		Long: `Upload every file of the directory as a resource named after the file, a file is
+uploaded only when the resource does not exist or its checksum is different.`,
+		Example: "opms mc resource sync -d ./resources --dry-run",
+		RunE:    sync.RunE,
+	}
+
+	cmd.Flags().StringVarP(&sync.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&sync.schema, "schema", "s", "", "Schema")
+
+	cmd.Flags().StringVarP(&sync.dir, "dir", "d", "", "Directory with the resource files")
+	cmd.Flags().BoolVar(&sync.dryRun, "dry-run", false, "Only show the resources which will be uploaded")
+	cmd.MarkFlagRequired("dir")
+	return cmd
+}
+
+func (r *syncCommand) RunE(_ *cobra.Command, _ []string) error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(r.cfg, r.project, r.schema)
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Resource", "Status"})
+
+	var failed []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		fileName := filepath.Join(r.dir, entry.Name())
+		status, err := r.sync(client, fileName, entry.Name())
+		if err != nil {
+			status = "failed: " + err.Error()
+			failed = append(failed, entry.Name())
+		}
+
+		printer.AddField(entry.Name())
+		printer.AddField(status)
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print resources: %w", err)
+	}
+
+	if len(failed) > 0 {
+		return errors.New("failed to sync resources: " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (r *syncCommand) sync(client *odps.Odps, fileName, name string) (string, error) {
+	checksum, err := fileMD5(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	res := odps.NewResources(client)
+	resource := res.Get(name)
+	exists, err := resource.Exist()
+	if err != nil {
+		return "", err
+	}
+
+	status := "created"
+	if exists {
+		err = resource.Load()
+		if err != nil {
+			return "", err
+		}
+		if strings.EqualFold(resource.ContentMD5(), checksum) {
+			return "unchanged", nil
+		}
+		status = "updated"
+	}
+
+	if r.dryRun {
+		return "to be " + status, nil
+	}
+
+	err = uploadFile(client, fileName, name, "", typeFromFile(name), exists)
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
@@ -0,0 +1,120 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+)
+
+type uploadCommand struct {
+	cfg *config.Config
+
+	project string
+	schema  string
+
+	fileName   string
+	name       string
+	resType    string
+	table      string
+	partitions []string
+	comment    string
+	overwrite  bool
+}
+
+func NewUploadCommand(cfg *config.Config) *cobra.Command {
+	upload := &uploadCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "upload",
+		Short: "Upload a file or table as resource",
+		Example: `opms mc resource upload -f udf.jar
+opms mc resource upload -f lib.py -n my_lib.py --overwrite
+opms mc resource upload --table proj.default.table --partition dt=2024-01-01 -n lookup_table`,
+		RunE: upload.RunE,
+	}
+
+	cmd.Flags().StringVarP(&upload.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&upload.schema, "schema", "s", "", "Schema")
+
+	cmd.Flags().StringVarP(&upload.fileName, "file", "f", "", "File to upload")
+	cmd.Flags().StringVarP(&upload.name, "name", "n", "", "Resource name, defaults to name of file")
+	cmd.Flags().StringVarP(&upload.resType, "type", "t", "", "Resource type: file, jar, py, archive, table, detected from file by default")
+	cmd.Flags().StringVar(&upload.table, "table", "", "Table for a table resource, only in the default schema")
+	cmd.Flags().StringArrayVar(&upload.partitions, "partition", nil, "Partition of the table resource as key=value")
+	cmd.Flags().StringVarP(&upload.comment, "comment", "c", "", "Comment for the resource")
+	cmd.Flags().BoolVar(&upload.overwrite, "overwrite", false, "Overwrite the resource if it exists")
+	return cmd
+}
+
+func (r *uploadCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.table != "" || strings.EqualFold(r.resType, "table") {
+		return r.uploadTable()
+	}
+
+	if r.fileName == "" {
+		return errors.New("either --file or --table is required")
+	}
+
+	resType := typeFromFile(r.fileName)
+	if r.resType != "" {
+		resType = odps.ResourceTypeFromStr(strings.ToLower(r.resType))
+		if resType == odps.ResourceTypeUnknown {
+			return fmt.Errorf("unknown resource type %s, use file, jar, py, archive or table", r.resType)
+		}
+	}
+
+	client, err := newClient(r.cfg, r.project, r.schema)
+	if err != nil {
+		return err
+	}
+
+	err = uploadFile(client, r.fileName, r.name, r.comment, resType, r.overwrite)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", r.fileName, err)
+	}
+
+	fmt.Printf("Uploaded %s as %s resource\n", r.fileName, odps.ResourceTypeToStr(resType))
+	return nil
+}
+
+func (r *uploadCommand) uploadTable() error {
+	if r.table == "" || r.name == "" {
+		return errors.New("--table and --name are required for a table resource")
+	}
+
+	tab, err := names.FromTableName(r.table)
+	if err != nil {
+		return err
+	}
+	// the table resource refers to the table by project and name only, it can not keep the schema
+	if !strings.EqualFold(tab.Schema.SchemaID, defaultSchema) {
+		return fmt.Errorf("table resource requires a table in the %s schema, got %s", defaultSchema, r.table)
+	}
+
+	spec, err := internal.ParsePartitionSpec(r.partitions)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(r.cfg, r.project, r.schema)
+	if err != nil {
+		return err
+	}
+
+	tr := odps.NewTableResource(r.name, tab.Schema.ProjectID, tab.TableID, spec.String())
+	res := odps.NewResources(client)
+	err = res.CreateTableResource(client.DefaultProjectName(), client.CurrentSchemaName(), tr, r.overwrite)
+	if err != nil {
+		return fmt.Errorf("failed to create table resource %s: %w", r.name, err)
+	}
+
+	fmt.Printf("Created table resource %s for %s\n", r.name, r.table)
+	return nil
+}
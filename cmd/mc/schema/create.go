@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+)
+
+type createCommand struct {
+	cfg *config.Config
+
+	project string
+	name    string
+	comment string
+}
+
+func NewCreateCommand(cfg *config.Config) *cobra.Command {
+	create := &createCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "create",
+		Short:   "Create a schema in the project",
+		Example: "opms mc schema create -p proj -n staging -c \"staging tables\"",
+		RunE:    create.RunE,
+	}
+
+	cmd.Flags().StringVarP(&create.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&create.name, "name", "n", "", "Schema name")
+	cmd.Flags().StringVarP(&create.comment, "comment", "c", "", "Comment for the schema")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *createCommand) RunE(_ *cobra.Command, _ []string) error {
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	err = odps.NewSchemas(client, proj).Create(r.name, true, r.comment)
+	if err != nil {
+		return fmt.Errorf("failed to create schema %s.%s: %w", proj, r.name, err)
+	}
+
+	fmt.Printf("Created schema %s.%s\n", proj, r.name)
+	return nil
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+type descCommand struct {
+	cfg *config.Config
+
+	project string
+	name    string
+	format  string
+	workers int
+}
+
+type schemaSummary struct {
+	name         string
+	tables       int
+	size         int64
+	lastModified *odps.Table
+	errs         []string
+}
+
+func NewDescCommand(cfg *config.Config) *cobra.Command {
+	desc := &descCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "desc",
+		Short: "Summarize the tables of schemas",
+		Long:  "Show the number of tables, total size and the last modified table of a schema, or of every schema with --name *",
+		Example: `opms mc schema desc -p proj -n staging
+opms mc schema desc -p proj -n "*" --format csv`,
+		RunE: desc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&desc.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&desc.name, "name", "n", internal.AllSchemas, "Schema name, * for all schemas")
+	cmd.Flags().StringVarP(&desc.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().IntVarP(&desc.workers, "workers", "w", 5, "Number of tables to load in parallel")
+	return cmd
+}
+
+func (r *descCommand) RunE(_ *cobra.Command, _ []string) error {
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	schemas, err := internal.ResolveSchemas(client, proj, r.name)
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Schema", "Tables", "Size", "Last Modified Table", "Last Modified"})
+
+	var errs []string
+	for _, schema := range schemas {
+		summary := summarize(client, proj, schema, r.workers)
+		errs = append(errs, summary.errs...)
+
+		printer.AddField(summary.name)
+		printer.AddField(strconv.Itoa(summary.tables))
+		printer.AddField(text.HumanBytes(summary.size))
+		if summary.lastModified != nil {
+			printer.AddField(summary.lastModified.Name())
+			printer.AddField(summary.lastModified.LastModifiedTime().Format(time.DateTime))
+		} else {
+			printer.AddField("")
+			printer.AddField("")
+		}
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print schemas: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to load some tables: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func summarize(client *odps.Odps, proj, schema string, workers int) schemaSummary {
+	tables, errs := internal.LoadTables(client, proj, schema, workers)
+	summary := schemaSummary{name: schema, errs: errs}
+	for _, t := range tables {
+		summary.tables++
+		summary.size += t.Size()
+		if summary.lastModified == nil || t.LastModifiedTime().After(summary.lastModified.LastModifiedTime()) {
+			summary.lastModified = t
+		}
+	}
+	return summary
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+)
+
+type dropCommand struct {
+	cfg *config.Config
+
+	project string
+	name    string
+	yes     bool
+}
+
+func NewDropCommand(cfg *config.Config) *cobra.Command {
+	drop := &dropCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "drop",
+		Short: "Drop an empty schema from the project",
+		Long: `Drop a schema after confirmation, only a schema without tables can be dropped.
+The drop is recorded in the audit file under the cache directory.`,
+		Example: "opms mc schema drop -p proj -n staging",
+		RunE:    drop.RunE,
+	}
+
+	cmd.Flags().StringVarP(&drop.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&drop.name, "name", "n", "", "Schema name")
+	cmd.Flags().BoolVarP(&drop.yes, "yes", "y", false, "Drop without asking for confirmation")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (r *dropCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.name == "default" {
+		return errors.New("default schema cannot be dropped")
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	tables := 0
+	var errs []string
+	odps.NewTables(client, proj, r.name).List(func(_ *odps.Table, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		tables++
+	})
+	if len(errs) > 0 {
+		// an empty schema cannot be confirmed when listing failed, so it is not dropped
+		return fmt.Errorf("failed to list tables of schema %s.%s: %s", proj, r.name, strings.Join(errs, ", "))
+	}
+	if tables > 0 {
+		return fmt.Errorf("schema %s.%s has %d tables, drop them first", proj, r.name, tables)
+	}
+
+	if !r.yes && !internal.Confirm(fmt.Sprintf("Drop schema %s.%s? [y/N] ", proj, r.name)) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	entry := internal.AuditEntry{Action: "drop_schema", Target: proj + "." + r.name, Status: "success"}
+	_, err = odps.NewSchemas(client, proj).Delete(r.name)
+	if err != nil {
+		entry.Status = "failed"
+		entry.Error = err.Error()
+	}
+
+	errAudit := internal.WriteAudit(r.cfg, entry)
+	if errAudit != nil {
+		fmt.Printf("failed to write audit log: %s\n", errAudit)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to drop schema %s.%s: %w", proj, r.name, err)
+	}
+
+	fmt.Printf("Dropped schema %s.%s\n", proj, r.name)
+	return nil
+}
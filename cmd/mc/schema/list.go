@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+type listCommand struct {
+	cfg *config.Config
+
+	project    string
+	namePrefix string
+	format     string
+}
+
+func NewListCommand(cfg *config.Config) *cobra.Command {
+	list := &listCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the schemas of a project",
+		Example: "opms mc schema list -p proj",
+		RunE:    list.RunE,
+	}
+
+	cmd.Flags().StringVarP(&list.project, "project", "p", "", "Project")
+	cmd.Flags().StringVarP(&list.namePrefix, "prefix", "n", "", "Schema name prefix")
+	cmd.Flags().StringVarP(&list.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	return cmd
+}
+
+func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
+	}
+	proj := client.DefaultProjectName()
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Schema", "Owner", "Created", "Comment"})
+
+	var errs []string
+	odps.NewSchemas(client, proj).List(func(s *odps.Schema, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		if !strings.HasPrefix(s.Name(), r.namePrefix) {
+			return
+		}
+
+		printer.AddField(s.Name())
+		printer.AddField(s.Owner())
+		printer.AddField(s.CreateTime().Format(time.DateTime))
+		printer.AddField(s.Comment())
+		printer.EndRow()
+	})
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print schemas: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to list schemas: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package schema
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// NewSchemaCommand initializes command for schema
+func NewSchemaCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "schema",
+		Short:   "Commands that will let the user to operate on schemas of a project",
+		Example: "opms mc schema [sub-command]",
+	}
+	cmd.AddCommand(
+		NewListCommand(cfg),
+		NewCreateCommand(cfg),
+		NewDropCommand(cfg),
+		NewDescCommand(cfg),
+	)
+	return cmd
+}
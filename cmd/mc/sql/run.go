@@ -20,8 +20,11 @@ import (
 type runSQL struct {
 	cfg *config.Config
 
-	query   string
-	sqlFile string
+	project   string
+	query     string
+	sqlFile   string
+	timezone  string
+	precision int
 }
 
 func NewRunSQLCommand(cfg *config.Config) *cobra.Command {
@@ -34,8 +37,11 @@ func NewRunSQLCommand(cfg *config.Config) *cobra.Command {
 		RunE:    ec.RunE,
 	}
 
+	cmd.Flags().StringVarP(&ec.project, "project", "p", "", "Project to run the query in")
 	cmd.Flags().StringVarP(&ec.query, "query", "q", "", "Query to run")
 	cmd.Flags().StringVarP(&ec.sqlFile, "file", "f", "", "Query filename to run")
+	cmd.Flags().StringVar(&ec.timezone, "timezone", "", "Timezone for DATETIME and TIMESTAMP values, eg Asia/Jakarta")
+	cmd.Flags().IntVar(&ec.precision, "precision", -1, "Number of decimals for FLOAT and DOUBLE values, -1 for exact value")
 	return cmd
 }
 
@@ -43,7 +49,17 @@ func (r *runSQL) RunE(_ *cobra.Command, _ []string) error {
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
-	client, err := mcc.NewSQLClientFromConfig(r.cfg)
+	formatter, err := internal.NewFormatter(r.timezone, r.precision)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetSQLClient(r.project)
 	if err != nil {
 		return err
 	}
@@ -62,7 +78,7 @@ func (r *runSQL) RunE(_ *cobra.Command, _ []string) error {
 	}
 
 	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	err = runQuery(client, query, printer)
+	err = runQuery(client, query, printer, formatter)
 	if err != nil {
 		return err
 	}
@@ -70,7 +86,7 @@ func (r *runSQL) RunE(_ *cobra.Command, _ []string) error {
 	return printer.Render()
 }
 
-func runQuery(client *sql.DB, query string, printer table.Printer) error {
+func runQuery(client *sql.DB, query string, printer table.Printer, formatter internal.Formatter) error {
 	rows, err := client.Query(query)
 	if err != nil {
 		return err
@@ -103,8 +119,7 @@ func runQuery(client *sql.DB, query string, printer table.Printer) error {
 
 		printer.AddField(strconv.Itoa(rowNum))
 		for _, r := range record {
-			str := internal.ToString(r)
-			printer.AddField(str)
+			printer.AddField(formatter.ToString(r))
 		}
 		printer.EndRow()
 		rowNum++
@@ -1,11 +1,16 @@
 package tables
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 
-	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
+	"github.com/sbchaos/opms/cmd/mc/internal"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/config"
 	"github.com/sbchaos/opms/lib/names"
@@ -14,7 +19,8 @@ import (
 type descCommand struct {
 	cfg *config.Config
 
-	name string
+	name   string
+	format string
 }
 
 // NewDescCommand returns data from the table
@@ -22,29 +28,39 @@ func NewDescCommand(cfg *config.Config) *cobra.Command {
 	ec := &descCommand{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "desc",
-		Short:   "Describe details of a table in maxcompute",
-		Example: "opms mc tables desc",
-		RunE:    ec.RunE,
+		Use:   "desc",
+		Short: "Describe details of a table in maxcompute",
+		Example: `opms mc tables desc -n proj.schema.table
+opms mc tables desc -n proj.schema.table --format yaml`,
+		RunE: ec.RunE,
 	}
 
 	cmd.Flags().StringVarP(&ec.name, "name", "n", "", "Table name")
+	cmd.Flags().StringVarP(&ec.format, "format", "o", "text", "Output format: text, json, yaml")
 	cmd.MarkFlagRequired("name")
 	return cmd
 }
 
 func (r *descCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	format := strings.ToLower(r.format)
+	if format != "text" && format != "json" && format != "yaml" {
+		return fmt.Errorf("unknown format %s, supported: text, json, yaml", r.format)
+	}
+
+	tab, err := names.FromTableName(r.name)
 	if err != nil {
 		return err
 	}
 
-	tab, err := names.FromTableName(r.name)
+	provider, err := mcc.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
 	}
 
-	client.SetDefaultProjectName(tab.Schema.ProjectID)
+	client, err := provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return err
+	}
 	client.SetCurrentSchemaName(tab.Schema.SchemaID)
 
 	tabl := client.Table(tab.TableID)
@@ -53,21 +69,87 @@ func (r *descCommand) RunE(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to load table: %w", err)
 	}
 
-	printTable(tab, tabl)
+	info := internal.NewTableInfo(tab.String(), tabl)
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		return encoder.Encode(info)
+	}
+
+	printTable(info)
 	return nil
 }
 
-func printTable(ps names.Table, t *odps.Table) {
-	fmt.Printf("Name:\t%s\n", ps.String())
-	fmt.Printf("Type:\t%s\n", t.Type())
-	fmt.Printf("Comment:\t%s\n", t.Comment())
-	fmt.Printf("Last Changed Time:\t%s\n", t.LastModifiedTime())
-	fmt.Printf("Size:\t%d\n", t.Size())
-	fmt.Printf("Record Num:\t%d\n", t.RecordNum())
-	if len(t.PartitionColumns()) > 0 {
+func printTable(t internal.TableInfo) {
+	fmt.Printf("Name:\t%s\n", t.Name)
+	fmt.Printf("Type:\t%s\n", t.Type)
+	fmt.Printf("Comment:\t%s\n", t.Comment)
+	fmt.Printf("Owner:\t%s\n", t.Owner)
+	fmt.Printf("Created Time:\t%s\n", t.CreatedTime)
+	fmt.Printf("Last Changed Time:\t%s\n", t.LastModifiedTime)
+	fmt.Printf("Lifecycle:\t%d\n", t.Lifecycle)
+	fmt.Printf("Size:\t%d\n", t.Size)
+	fmt.Printf("Record Num:\t%d\n", t.RecordNum)
+
+	fmt.Printf("Columns:\n")
+	printColumns(t.Columns)
+	if len(t.PartitionColumns) > 0 {
 		fmt.Printf("Partition Columns:\n")
-		for _, c1 := range t.PartitionColumns() {
-			fmt.Printf(" %s\n", c1.Name)
+		printColumns(t.PartitionColumns)
+	}
+
+	if t.Cluster != nil {
+		fmt.Printf("Cluster:\n")
+		fmt.Printf(" Type:\t%s\n", t.Cluster.Type)
+		fmt.Printf(" Columns:\t%s\n", strings.Join(t.Cluster.Columns, ", "))
+		if len(t.Cluster.SortCols) > 0 {
+			fmt.Printf(" Sort Columns:\t%s\n", strings.Join(t.Cluster.SortCols, ", "))
+		}
+		if t.Cluster.BucketNum > 0 {
+			fmt.Printf(" Buckets:\t%d\n", t.Cluster.BucketNum)
+		}
+	}
+
+	if t.External != nil {
+		fmt.Printf("Storage Handler:\t%s\n", t.External.StorageHandler)
+		fmt.Printf("Location:\t%s\n", t.External.Location)
+		printProperties("SerDe Properties", t.External.SerDeProperties)
+	}
+	printProperties("Table Properties", t.TblProperties)
+
+	if t.ViewText != "" {
+		fmt.Printf("View Text:\n%s\n", t.ViewText)
+	}
+}
+
+func printColumns(cols []internal.ColumnInfo) {
+	for _, c := range cols {
+		nullable := "NOT NULL"
+		if c.Nullable {
+			nullable = "NULL"
 		}
+		fmt.Printf(" %s\t%s\t%s\t%s\n", c.Name, c.Type, nullable, c.Comment)
+	}
+}
+
+func printProperties(title string, props map[string]string) {
+	if len(props) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("%s:\n", title)
+	for _, k := range keys {
+		fmt.Printf(" %s=%s\n", k, props[k])
 	}
 }
@@ -0,0 +1,587 @@
+package tables
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tunnel"
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/pool"
+	"github.com/sbchaos/opms/lib/progress"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+const (
+	downloadCSV     = "csv"
+	downloadJSONL   = "jsonl"
+	downloadParquet = "parquet"
+)
+
+type downloadCommand struct {
+	cfg *config.Config
+
+	name       string
+	partitions []string
+	columns    []string
+	format     string
+	out        string
+	workers    int
+	blockSize  int
+	timezone   string
+	precision  int
+	restart    bool
+
+	formatter internal.Formatter
+}
+
+// checkpoint keeps the state of a download, blocks are saved in part files next to it
+type checkpoint struct {
+	SessionID   string   `json:"session_id"`
+	Table       string   `json:"table"`
+	Partition   string   `json:"partition,omitempty"`
+	Columns     []string `json:"columns"`
+	RecordCount int      `json:"record_count"`
+	BlockSize   int      `json:"block_size"`
+	Completed   []int    `json:"completed"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// NewDownloadCommand downloads a table or partition into a local file using the tunnel
+func NewDownloadCommand(cfg *config.Config) *cobra.Command {
+	dc := &downloadCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "download",
+		Short: "Download a table or partition to a local file",
+		Long: `Download the rows of a table or partition using the tunnel.
+Rows are read in blocks in parallel, completed blocks are kept in the cache directory
+and an interrupted download resumes from them when run again with the same arguments.
+Parquet keeps the type of columns and every block is a row group of the file, DATETIME and
+TIMESTAMP are written in UTC and ARRAY, MAP, STRUCT and JSON columns are written as json strings,
+--timezone and --precision only apply to csv and jsonl.`,
+		Example: `opms mc table download -n proj.schema.table -p dt=2024-01-01 --out data.csv
+opms mc table download -n proj.schema.table -p dt=2024-01-01 -c id,name --format jsonl --out data.jsonl -w 8
+opms mc table download -n proj.schema.table -p dt=2024-01-01 --format parquet --out data.parquet`,
+		RunE: dc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&dc.name, "name", "n", "", "Table name")
+	cmd.Flags().StringArrayVarP(&dc.partitions, "partition", "p", nil, "Partition to download as key=value, can be repeated")
+	cmd.Flags().StringSliceVarP(&dc.columns, "columns", "c", nil, "Columns to download, comma separated")
+	cmd.Flags().StringVarP(&dc.format, "format", "o", downloadCSV, "Format of the file: csv, jsonl, parquet")
+	cmd.Flags().StringVar(&dc.out, "out", "", "File to write the rows")
+	cmd.Flags().IntVarP(&dc.workers, "workers", "w", 4, "Number of blocks downloaded in parallel")
+	cmd.Flags().IntVar(&dc.blockSize, "block-size", 100000, "Number of rows in a block")
+	cmd.Flags().StringVar(&dc.timezone, "timezone", "", "Timezone for DATETIME and TIMESTAMP values, eg Asia/Jakarta")
+	cmd.Flags().IntVar(&dc.precision, "precision", -1, "Number of decimals for FLOAT and DOUBLE values, -1 for exact value")
+	cmd.Flags().BoolVar(&dc.restart, "restart", false, "Ignore the checkpoint of a previous download")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("out")
+	return cmd
+}
+
+func (r *downloadCommand) RunE(_ *cobra.Command, _ []string) error {
+	switch strings.ToLower(r.format) {
+	case downloadCSV, downloadJSONL, downloadParquet:
+		r.format = strings.ToLower(r.format)
+	default:
+		return fmt.Errorf("unknown format %s, use csv, jsonl or parquet", r.format)
+	}
+	if r.blockSize <= 0 {
+		return errors.New("--block-size should be more than 0")
+	}
+
+	formatter, err := internal.NewFormatter(r.timezone, r.precision)
+	if err != nil {
+		return err
+	}
+	r.formatter = formatter
+
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
+	spec, err := internal.ParsePartitionSpec(r.partitions)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return err
+	}
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	tabl := client.Table(tab.TableID)
+	err = tabl.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load table: %w", err)
+	}
+
+	partitionCols := tabl.PartitionColumns()
+	err = spec.Validate(partitionCols)
+	if err != nil {
+		return err
+	}
+	if len(partitionCols) != len(spec) {
+		return fmt.Errorf("table is partitioned by %d columns, provide all of them with --partition", len(partitionCols))
+	}
+
+	tun, err := mcc.NewTunnel(client, tab.Schema.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to create tunnel: %w", err)
+	}
+
+	opts := []tunnel.Option{tunnel.SessionCfg.WithSchemaName(tab.Schema.SchemaID)}
+	if len(spec) > 0 {
+		opts = append(opts, tunnel.SessionCfg.WithPartitionKey(spec.String()))
+	}
+
+	cp := r.loadCheckpoint(tab, spec)
+	var session *tunnel.DownloadSession
+	if cp.SessionID != "" {
+		session, err = tun.AttachToExistedDownloadSession(tab.Schema.ProjectID, tab.TableID, cp.SessionID, opts...)
+		if err != nil || session.RecordCount() != cp.RecordCount {
+			fmt.Fprintln(os.Stderr, "Previous download session has expired, starting again")
+			cp.reset()
+		} else {
+			fmt.Fprintf(os.Stderr, "Resuming download, %d blocks already completed\n", len(cp.Completed))
+		}
+	}
+	if cp.SessionID == "" {
+		session, err = tun.CreateDownloadSession(tab.Schema.ProjectID, tab.TableID, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to create download session: %w", err)
+		}
+		cp.SessionID = session.Id
+		cp.RecordCount = session.RecordCount()
+		cp.BlockSize = r.blockSize
+		err = cp.save()
+		if err != nil {
+			return err
+		}
+	}
+
+	headers := r.columns
+	if len(headers) == 0 {
+		for _, c := range session.Schema().Columns {
+			headers = append(headers, c.Name)
+		}
+	}
+
+	var schema *arrow.Schema
+	if r.format == downloadParquet {
+		schema, err = internal.ArrowSchema(session.Schema().Columns, headers)
+		if err != nil {
+			return err
+		}
+	}
+
+	blocks := (cp.RecordCount + cp.BlockSize - 1) / cp.BlockSize
+	bar := progress.New(os.Stderr, "Downloaded rows", int64(cp.RecordCount), term.IsTerminal(os.Stderr))
+	bar.Add(int64(cp.completedRows()))
+
+	jobs := make(chan pool.Job[int], 20)
+	go func() {
+		for i := 0; i < blocks; i++ {
+			if cp.isCompleted(i) {
+				continue
+			}
+			block := i
+			jobs <- func() pool.JobResult[int] {
+				err := r.downloadBlock(session, cp, block, headers, schema, bar)
+				return pool.JobResult[int]{Output: block, Err: err}
+			}
+		}
+		close(jobs)
+	}()
+
+	var failed []pool.JobResult[int]
+	for res := range pool.StartPool(r.workers, jobs) {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	bar.Done()
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Output < failed[j].Output
+	})
+
+	if len(failed) > 0 {
+		msgs := make([]string, len(failed))
+		for i, res := range failed {
+			msgs[i] = fmt.Sprintf("block %d: %s", res.Output, res.Err)
+		}
+		return fmt.Errorf("failed to download %d blocks, run again to resume:\n%s", len(failed), strings.Join(msgs, "\n"))
+	}
+
+	err = r.merge(cp, blocks, headers, schema)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Downloaded %d rows of %s into %s\n", cp.RecordCount, targetName(tab, spec), r.out)
+	return os.RemoveAll(cp.partsDir())
+}
+
+func (r *downloadCommand) downloadBlock(session *tunnel.DownloadSession, cp *checkpoint, block int, headers []string,
+	schema *arrow.Schema, bar *progress.Bar) error {
+	start := block * cp.BlockSize
+	count := min(cp.BlockSize, cp.RecordCount-start)
+
+	reader, err := session.OpenRecordReader(start, count, r.columns)
+	if err != nil {
+		return fmt.Errorf("failed to open reader: %w", err)
+	}
+	defer reader.Close()
+
+	partFile := cp.partFile(block)
+	f, err := os.Create(partFile + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := newRowWriter(f, r.format, headers, schema, r.formatter)
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		err = w.write(record)
+		if err != nil {
+			return err
+		}
+		bar.Add(1)
+	}
+
+	err = w.flush()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// the part is renamed only when complete, so that a partial block is downloaded again on resume
+	err = os.Rename(partFile+".tmp", partFile)
+	if err != nil {
+		return err
+	}
+	return cp.complete(block)
+}
+
+func (r *downloadCommand) merge(cp *checkpoint, blocks int, headers []string, schema *arrow.Schema) error {
+	out, err := os.Create(r.out)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", r.out, err)
+	}
+	defer out.Close()
+
+	if r.format == downloadParquet {
+		err = mergeParquet(out, cp, blocks, schema)
+		if err != nil {
+			return err
+		}
+		return out.Close()
+	}
+
+	if r.format == downloadCSV {
+		w := csv.NewWriter(out)
+		w.Write(headers)
+		w.Flush()
+		if w.Error() != nil {
+			return w.Error()
+		}
+	}
+
+	for i := 0; i < blocks; i++ {
+		err = appendFile(out, cp.partFile(i))
+		if err != nil {
+			return fmt.Errorf("failed to merge block %d: %w", i, err)
+		}
+	}
+	return out.Close()
+}
+
+// mergeParquet writes the arrow records of the parts into one parquet file, every block is a row group
+func mergeParquet(out io.Writer, cp *checkpoint, blocks int, schema *arrow.Schema) error {
+	buf := bufio.NewWriter(out)
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	w, err := pqarrow.NewFileWriter(schema, buf, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < blocks; i++ {
+		err = copyRecords(w, schema, cp.partFile(i))
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("failed to merge block %d: %w", i, err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func copyRecords(w *pqarrow.FileWriter, schema *arrow.Schema, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := ipc.NewReader(bufio.NewReader(f), ipc.WithSchema(schema))
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	for reader.Next() {
+		err = w.Write(reader.Record())
+		if err != nil {
+			return err
+		}
+	}
+	return reader.Err()
+}
+
+func appendFile(out io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(out, f)
+	return err
+}
+
+// rowWriter writes the records of tunnel as csv rows or json lines, nulls are empty in csv. Records of
+// parquet are kept in arrow and written to the part in the ipc format, which is merged without decoding.
+type rowWriter struct {
+	format    string
+	headers   []string
+	formatter internal.Formatter
+	buf       *bufio.Writer
+	csv       *csv.Writer
+	arrow     *internal.RecordBuilder
+	ipc       *ipc.Writer
+}
+
+func newRowWriter(w io.Writer, format string, headers []string, schema *arrow.Schema, formatter internal.Formatter) (*rowWriter, error) {
+	buf := bufio.NewWriter(w)
+	rw := &rowWriter{
+		format:    format,
+		headers:   headers,
+		formatter: formatter,
+		buf:       buf,
+		csv:       csv.NewWriter(buf),
+	}
+	if format == downloadParquet {
+		rw.arrow = internal.NewRecordBuilder(schema, formatter)
+		rw.ipc = ipc.NewWriter(buf, ipc.WithSchema(schema))
+	}
+	return rw, nil
+}
+
+func (w *rowWriter) write(record data.Record) error {
+	switch w.format {
+	case downloadJSONL:
+		return w.writeJSON(record)
+	case downloadParquet:
+		return w.arrow.Append(record)
+	}
+
+	row := make([]string, len(record))
+	for i, d := range record {
+		if !isNull(d) {
+			row[i] = w.formatter.ToString(d)
+		}
+	}
+	return w.csv.Write(row)
+}
+
+// writeJSON keeps the order of columns in the line, which a map based encoding would lose
+func (w *rowWriter) writeJSON(record data.Record) error {
+	w.buf.WriteString("{")
+	for i, d := range record {
+		if i > 0 {
+			w.buf.WriteString(",")
+		}
+
+		var value any
+		if !isNull(d) {
+			value = w.formatter.ToValue(d)
+		}
+		k, _ := json.Marshal(w.headers[i])
+		v, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", w.headers[i], err)
+		}
+		w.buf.Write(k)
+		w.buf.WriteString(":")
+		w.buf.Write(v)
+	}
+	_, err := w.buf.WriteString("}\n")
+	return err
+}
+
+func (w *rowWriter) flush() error {
+	if w.arrow != nil {
+		err := w.writeRecord()
+		if err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// writeRecord writes the rows of the block as one record
+func (w *rowWriter) writeRecord() error {
+	defer w.arrow.Release()
+	rec := w.arrow.NewRecord()
+	defer rec.Release()
+
+	err := w.ipc.Write(rec)
+	if err != nil {
+		return err
+	}
+	return w.ipc.Close()
+}
+
+func isNull(d data.Data) bool {
+	if d == nil {
+		return true
+	}
+	_, ok := d.(data.NullData)
+	return ok
+}
+
+// loadCheckpoint returns the checkpoint of a previous download of same table, partition and columns
+func (r *downloadCommand) loadCheckpoint(tab names.Table, spec internal.PartitionSpec) *checkpoint {
+	key := strings.Join([]string{tab.String(), spec.String(), strings.Join(r.columns, ","), r.format, r.out,
+		r.timezone, strconv.Itoa(r.precision)}, "|")
+	sum := sha1.Sum([]byte(key))
+	path := filepath.Join(config.CacheDir(), "download", hex.EncodeToString(sum[:8]), "checkpoint.json")
+
+	cp := &checkpoint{path: path}
+	if !r.restart {
+		content, err := os.ReadFile(path)
+		if err == nil && json.Unmarshal(content, cp) == nil {
+			return cp
+		}
+	}
+
+	cp.reset()
+	cp.Table = tab.String()
+	cp.Partition = spec.String()
+	cp.Columns = r.columns
+	return cp
+}
+
+func (c *checkpoint) reset() {
+	os.RemoveAll(c.partsDir())
+	c.SessionID = ""
+	c.Completed = nil
+}
+
+func (c *checkpoint) partsDir() string {
+	return filepath.Dir(c.path)
+}
+
+func (c *checkpoint) partFile(block int) string {
+	return filepath.Join(c.partsDir(), fmt.Sprintf("part-%06d", block))
+}
+
+func (c *checkpoint) isCompleted(block int) bool {
+	for _, b := range c.Completed {
+		if b == block {
+			_, err := os.Stat(c.partFile(block))
+			return err == nil
+		}
+	}
+	return false
+}
+
+func (c *checkpoint) completedRows() int {
+	rows := 0
+	for _, b := range c.Completed {
+		rows += min(c.BlockSize, c.RecordCount-b*c.BlockSize)
+	}
+	return rows
+}
+
+func (c *checkpoint) complete(block int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Completed = append(c.Completed, block)
+	return c.saveLocked()
+}
+
+func (c *checkpoint) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+func (c *checkpoint) saveLocked() error {
+	err := os.MkdirAll(c.partsDir(), 0o755)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, content, 0o644)
+}
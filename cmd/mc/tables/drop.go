@@ -1,20 +1,25 @@
 package tables
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"os"
+	"path"
+	"strconv"
 	"strings"
-	"time"
 
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/mc/internal"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/cmdutil"
 	"github.com/sbchaos/opms/lib/config"
 	"github.com/sbchaos/opms/lib/names"
 	"github.com/sbchaos/opms/lib/printers/table"
 	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
 )
 
 type dropCommand struct {
@@ -22,21 +27,45 @@ type dropCommand struct {
 
 	name     string
 	fileName string
+	dryRun   bool
+	yes      bool
+	backupTo string
+	retries  int
 }
 
-// NewDropCommand checks if the tables exist
+type dropPlan struct {
+	schema  names.Schema
+	tableID string
+	info    internal.TableInfo
+	skip    string
+}
+
+func (d dropPlan) name() string {
+	return d.schema.TableName(d.tableID)
+}
+
+// NewDropCommand drops the tables after showing what will be dropped
 func NewDropCommand(cfg *config.Config) *cobra.Command {
 	ec := &dropCommand{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "drop",
-		Short:   "Drop if a table exists in maxcompute",
-		Example: "opms mc tables drop",
-		RunE:    ec.RunE,
+		Use:   "drop",
+		Short: "Drop if a table exists in maxcompute",
+		Long: `Drop the tables after confirmation, tables matching the profile variable
+drop:protected_tables (a list of project.schema.table names or patterns like proj.schema.*)
+are never dropped. With --backup-to the drop fails when the backup table already exists.
+Every drop is recorded in the audit file under the cache directory.`,
+		Example: `opms mc tables drop -n proj.schema.table --dry-run
+opms mc tables drop -f tables.txt --backup-to proj.backup_schema`,
+		RunE: ec.RunE,
 	}
 
 	cmd.Flags().StringVarP(&ec.name, "name", "n", "", "Table name")
 	cmd.Flags().StringVarP(&ec.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
+	cmd.Flags().BoolVar(&ec.dryRun, "dry-run", false, "Only show the tables which will be dropped")
+	cmd.Flags().BoolVarP(&ec.yes, "yes", "y", false, "Drop without asking for confirmation")
+	cmd.Flags().StringVar(&ec.backupTo, "backup-to", "", "Clone the tables into project.schema before dropping")
+	cmd.Flags().IntVar(&ec.retries, "retries", 3, "Number of attempts for a drop on connection errors")
 	return cmd
 }
 
@@ -44,13 +73,7 @@ func (r *dropCommand) RunE(_ *cobra.Command, _ []string) error {
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
-	client, err := mcc.NewClientFromConfig(r.cfg)
-	if err != nil {
-		return err
-	}
-
 	var tableNames []string
-	var mapping map[names.Schema][]string
 	if r.name != "" {
 		tableNames = append(tableNames, r.name)
 
@@ -66,47 +89,260 @@ func (r *dropCommand) RunE(_ *cobra.Command, _ []string) error {
 		}
 		tableNames = lines
 	}
-	mapping = names.GroupTableNames(tableNames)
+
+	if len(tableNames) == 0 {
+		return errors.New("either --name or --filename is required")
+	}
+
+	if r.fileName == "-" && !r.yes && !r.dryRun {
+		return errors.New("--yes is required when reading table names from stdin")
+	}
+
+	var backupSchema names.Schema
+	if r.backupTo != "" {
+		s, err := names.FromSchemaName(r.backupTo)
+		if err != nil {
+			return fmt.Errorf("invalid --backup-to: %w", err)
+		}
+		backupSchema = s
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	plans := r.buildPlan(provider, names.GroupTableNames(tableNames))
 
 	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Table", "Type", "Rows", "Size", "Action"})
+	toDrop := 0
+	for _, p := range plans {
+		printer.AddField(p.name())
+		printer.AddField(p.info.Type)
+		printer.AddField(strconv.Itoa(p.info.RecordNum))
+		printer.AddField(text.HumanBytes(p.info.Size))
+		if p.skip != "" {
+			printer.AddField("skip: " + p.skip)
+		} else {
+			printer.AddField("drop")
+			toDrop++
+		}
+		printer.EndRow()
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+
+	if r.dryRun || toDrop == 0 {
+		return nil
+	}
+
+	if !r.yes && !internal.Confirm(fmt.Sprintf("Drop %s? [y/N]: ", text.Pluralize(toDrop, "table"))) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	result := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	result.AddHeader([]string{"Status", "Table", "Reason"})
+
+	failed := 0
+	for _, p := range plans {
+		if p.skip != "" {
+			continue
+		}
+
+		entry := internal.AuditEntry{
+			Action:  "drop_table",
+			Target:  p.name(),
+			Details: fmt.Sprintf("rows=%d size=%d", p.info.RecordNum, p.info.Size),
+		}
+
+		err = r.drop(provider, backupSchema, p)
+		if err != nil {
+			failed++
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			dropFailure(result, p.schema, p.tableID, err.Error())
+		} else {
+			entry.Status = "dropped"
+			if r.backupTo != "" {
+				entry.Details += " backup=" + backupSchema.TableName(p.tableID)
+			}
+			dropSuccess(result, p.schema, p.tableID)
+		}
+
+		err = internal.WriteAudit(r.cfg, entry)
+		if err != nil {
+			fmt.Printf("[WARN] failed to write audit for %s: %s\n", p.name(), err)
+		}
+	}
+
+	err = result.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to drop %s", text.Pluralize(failed, "table"))
+	}
+	return nil
+}
 
+func (r *dropCommand) buildPlan(provider *mcc.ClientProvider, mapping map[names.Schema][]string) []dropPlan {
+	var plans []dropPlan
 	for ps, tables := range mapping {
-		client.SetDefaultProjectName(ps.ProjectID)
-		client.SetCurrentSchemaName(ps.SchemaID)
+		protected, protectErr := protectedTables(r.cfg, ps.ProjectID)
+
+		client, errClient := provider.GetClient(ps.ProjectID)
+		if errClient == nil {
+			client.SetCurrentSchemaName(ps.SchemaID)
+		}
 
 		for _, t1 := range tables {
-			for {
-				err = client.Tables().Delete(t1, true)
-				if err != nil {
-					if strings.Contains(err.Error(), connErr) {
-						time.Sleep(500 * time.Millisecond)
-						continue
-					}
-
-					dropFailure(printer, ps, t1)
-				} else {
-					dropSuccess(printer, ps, t1)
-				}
-				break
+			p := dropPlan{schema: ps, tableID: t1}
+			if protectErr != nil {
+				p.skip = protectErr.Error()
+				plans = append(plans, p)
+				continue
+			}
+			if isProtected(protected, p.name()) {
+				p.skip = "protected"
+				plans = append(plans, p)
+				continue
+			}
+
+			if errClient != nil {
+				p.skip = "failed to load: " + errClient.Error()
+				plans = append(plans, p)
+				continue
 			}
+
+			tabl := client.Table(t1)
+			err := internal.Retry(r.retries, tabl.Load)
+			if err != nil {
+				p.skip = "failed to load: " + err.Error()
+			} else {
+				p.info = internal.NewTableInfo(p.name(), tabl)
+			}
+			plans = append(plans, p)
 		}
 	}
+	return plans
+}
 
-	err = printer.Render()
+func (r *dropCommand) drop(provider *mcc.ClientProvider, backupSchema names.Schema, p dropPlan) error {
+	if r.backupTo != "" {
+		err := backup(provider, backupSchema, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	client, err := provider.GetClient(p.schema.ProjectID)
 	if err != nil {
-		return fmt.Errorf("failed to print table: %w", err)
+		return err
+	}
+	client.SetCurrentSchemaName(p.schema.SchemaID)
+	return internal.Retry(r.retries, func() error {
+		return client.Tables().Delete(p.tableID, true)
+	})
+}
+
+// backup copies the table to the backup schema, it runs in the project of the backup
+func backup(provider *mcc.ClientProvider, backupSchema names.Schema, p dropPlan) error {
+	client, err := provider.GetClient(backupSchema.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to check backup table, table not dropped: %w", err)
+	}
+
+	exists, err := odps.NewTable(client, backupSchema.ProjectID, backupSchema.SchemaID, p.tableID).Exists()
+	if err != nil {
+		return fmt.Errorf("failed to check backup table, table not dropped: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("backup table %s already exists, table not dropped", backupSchema.TableName(p.tableID))
+	}
+
+	sqlClient, err := provider.GetSQLClient(backupSchema.ProjectID)
+	if err != nil {
+		return fmt.Errorf("backup failed, table not dropped: %w", err)
+	}
+
+	err = backupTable(sqlClient, backupSchema.TableName(p.tableID), p.info)
+	if err != nil {
+		return fmt.Errorf("backup failed, table not dropped: %w", err)
 	}
 	return nil
 }
 
-func dropFailure(printer table.Printer, n1 names.Schema, n2 string) {
+// backupTable clones the data of managed tables, views and external tables own no data
+// so only their definition is recreated. The target must not exist, a backup left from an
+// earlier run fails the statement instead of being kept or overwritten.
+func backupTable(client *sql.DB, target string, info internal.TableInfo) error {
+	query := fmt.Sprintf("CLONE TABLE %s TO %s;", info.Name, target)
+	if info.ViewText != "" || info.External != nil {
+		info.Name = target
+		query = strings.Replace(internal.GenerateDDL(info), " IF NOT EXISTS", "", 1)
+	}
+
+	_, err := client.Exec(query)
+	return err
+}
+
+// protectedTables returns the lower cased names or patterns of protected tables, every entry
+// has to be a fully qualified name as the variable can be shared across projects
+func protectedTables(cfg *config.Config, proj string) ([]string, error) {
+	value, err := cmdutil.GetArgFromVar[any](cfg, "drop", proj, "protected_tables")
+	if err != nil {
+		// variable is not set
+		return nil, nil
+	}
+
+	values, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid protected_tables of type %T, use a list of project.schema.table", value)
+	}
+
+	protected := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid protected table %v of type %T, use project.schema.table", v, v)
+		}
+		if strings.Count(s, ".") != 2 {
+			return nil, fmt.Errorf("invalid protected table %s, use project.schema.table", s)
+		}
+		protected = append(protected, strings.ToLower(s))
+	}
+	return protected, nil
+}
+
+func isProtected(patterns []string, fullName string) bool {
+	fullName = strings.ToLower(fullName)
+	for _, p := range patterns {
+		if p == fullName {
+			return true
+		}
+		if ok, _ := path.Match(p, fullName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func dropFailure(printer table.Printer, n1 names.Schema, n2 string, reason string) {
 	printer.AddField(" ❌ ")
 	printer.AddField(n1.TableName(n2))
+	printer.AddField(reason)
 	printer.EndRow()
 }
 
 func dropSuccess(printer table.Printer, n1 names.Schema, n2 string) {
 	printer.AddField(" ✅ ")
 	printer.AddField(n1.TableName(n2))
+	printer.AddField("")
 	printer.EndRow()
 }
@@ -5,12 +5,13 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/mc/internal"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/cmdutil"
 	"github.com/sbchaos/opms/lib/config"
@@ -18,18 +19,19 @@ import (
 	"github.com/sbchaos/opms/lib/names"
 	"github.com/sbchaos/opms/lib/printers/table"
 	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
 )
 
-var (
-	connErr            = "connection reset by peer"
-	notExistErrorRegex = regexp.MustCompile(`Table (\S*) does not exist`)
-)
+var notExistErrorRegex = regexp.MustCompile(`Table (\S*) does not exist`)
 
 type existsCommand struct {
 	cfg *config.Config
 
-	name     string
-	fileName string
+	name        string
+	fileName    string
+	format      string
+	missingOnly bool
+	retries     int
 }
 
 // NewExistsCommand checks if the tables exist
@@ -37,26 +39,55 @@ func NewExistsCommand(cfg *config.Config) *cobra.Command {
 	ec := &existsCommand{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "exists",
-		Short:   "Show if the table exists in maxcompute",
-		Example: "opms mc tables exists",
-		RunE:    ec.RunE,
+		Use:   "exists",
+		Short: "Show if the table exists in maxcompute",
+		Long:  "Show if the tables exist in maxcompute, exits with an error when any table is missing",
+		Example: `opms mc tables exists -n proj.schema.table
+opms mc tables exists -f tables.txt --missing-only --format csv`,
+		RunE: ec.RunE,
 	}
 
 	cmd.Flags().StringVarP(&ec.name, "name", "n", "", "Table name")
 	cmd.Flags().StringVarP(&ec.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
+	cmd.Flags().StringVarP(&ec.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().BoolVar(&ec.missingOnly, "missing-only", false, "Only show the tables which are missing")
+	cmd.Flags().IntVar(&ec.retries, "retries", 3, "Number of attempts for a batch on connection errors")
 	return cmd
 }
 
+type existsPrinter struct {
+	printer     table.Printer
+	symbols     bool
+	missingOnly bool
+	missing     int
+}
+
+// add prints the row of table, missing is counted only for the printed rows so that the
+// summary matches the output
+func (e *existsPrinter) add(name string, exists bool) {
+	if exists && e.missingOnly {
+		return
+	}
+	if !exists {
+		e.missing++
+	}
+
+	switch {
+	case e.symbols && exists:
+		e.printer.AddField(" ✅ ")
+	case e.symbols:
+		e.printer.AddField(" ❌ ")
+	default:
+		e.printer.AddField(strconv.FormatBool(exists))
+	}
+	e.printer.AddField(name)
+	e.printer.EndRow()
+}
+
 func (r *existsCommand) RunE(_ *cobra.Command, _ []string) error {
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
-	client, err := mcc.NewClientFromConfig(r.cfg)
-	if err != nil {
-		return err
-	}
-
 	var tableNames []string
 	var mapping map[names.Schema][]string
 	if r.name != "" {
@@ -76,75 +107,100 @@ func (r *existsCommand) RunE(_ *cobra.Command, _ []string) error {
 	}
 	mapping = names.GroupTableNames(tableNames)
 
-	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"Exists", "Table Name"})
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	ep := &existsPrinter{
+		printer:     printer,
+		symbols:     strings.EqualFold(r.format, table.FormatTable) || r.format == "",
+		missingOnly: r.missingOnly,
+	}
 
 	var errs []error
 	for ps, tables := range mapping {
-		client.SetDefaultProjectName(ps.ProjectID)
+		client, err := provider.GetClient(ps.ProjectID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ps.ProjectID, err))
+			continue
+		}
 		client.SetCurrentSchemaName(ps.SchemaID)
 
-		lenTables := len(tables)
-		for i := 0; i < lenTables; i = i + 100 {
-			end := min(i+100, lenTables)
-			err := forN(100, printer, client.Tables(), ps, tables[i:end])
-			if err != nil {
-				errs = append(errs, err)
-			}
+		err = forN(100, r.retries, ep, client.Tables(), ps, tables)
+		if err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	printer.Render()
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
 	for _, er := range errs {
-		fmt.Printf("Error: %s\n", er)
+		fmt.Fprintf(os.Stderr, "Error: %s\n", er)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to check tables for %s", text.Pluralize(len(errs), "schema"))
+	}
+	if ep.missing > 0 {
+		return fmt.Errorf("%s missing", text.Pluralize(ep.missing, "table"))
 	}
 	return nil
 }
 
-func forN(step int, printer table.Printer, tabs *odps.Tables, ps names.Schema, tables []string) error {
+func forN(step int, retries int, ep *existsPrinter, tabs *odps.Tables, ps names.Schema, tables []string) error {
 	lenTables := len(tables)
 	for i := 0; i < lenTables; i = i + step {
 		end := min(i+step, lenTables)
 		batch := tables[i:end]
 
-		for {
-			loadTables, err := tabs.BatchLoadTables(batch)
+		for len(batch) > 0 {
+			var loadTables []*odps.Table
+			err := internal.Retry(retries, func() error {
+				var err error
+				loadTables, err = tabs.BatchLoadTables(batch)
+				return err
+			})
 			if err != nil {
-				if strings.Contains(err.Error(), connErr) {
-					time.Sleep(500 * time.Millisecond)
-					continue
+				submatch := notExistErrorRegex.FindStringSubmatch(err.Error())
+				if len(submatch) == 0 || len(submatch[1]) == 0 {
+					return err
 				}
 
-				submatch := notExistErrorRegex.FindStringSubmatch(err.Error())
-				if len(submatch) > 0 && len(submatch[0]) > 0 {
-					failureStatus(printer, ps, submatch[1])
-					batch = list.Remove(batch, submatch[1])
-					if len(batch) == 0 {
-						break
-					}
-					continue
+				// the error can have the qualified name, only a table of the batch is marked missing
+				missing := missingTable(batch, submatch[1])
+				if missing == "" {
+					return err
 				}
-				return err
-			} else {
-				successStatus(printer, ps, loadTables)
-				break
+				ep.add(ps.TableName(missing), false)
+				batch = list.Remove(batch, missing)
+				continue
+			}
+
+			for _, t1 := range loadTables {
+				ep.add(ps.TableName(t1.Name()), true)
 			}
+			break
 		}
 	}
 	return nil
 }
 
-func failureStatus(printer table.Printer, ps names.Schema, table string) {
-	printer.AddField(" ❌ ")
-	printer.AddField(ps.TableName(table))
-	printer.EndRow()
-}
-
-func successStatus(printer table.Printer, ps names.Schema, tables []*odps.Table) {
-	printer.AddHeader([]string{"Exists", "Table Name"})
-
-	for _, t1 := range tables {
-		printer.AddField(" ✅ ")
-		printer.AddField(ps.TableName(t1.Name()))
-		printer.EndRow()
+func missingTable(batch []string, name string) string {
+	name = strings.ToLower(name)
+	for _, t := range batch {
+		lower := strings.ToLower(t)
+		if lower == name || strings.HasSuffix(name, "."+lower) {
+			return t
+		}
 	}
+	return ""
 }
@@ -0,0 +1,133 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+type fetchDDLCommand struct {
+	cfg *config.Config
+
+	name      string
+	fileName  string
+	outputDir string
+}
+
+// NewFetchDDLCommand writes the create statements of tables to files
+func NewFetchDDLCommand(cfg *config.Config) *cobra.Command {
+	fetch := &fetchDDLCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "fetch-ddl",
+		Short: "Fetch DDL for the tables and write them to <name>.sql files",
+		Example: `opms mc tables fetch-ddl -n proj.schema.table
+opms mc tables fetch-ddl -f tables.txt -o ddl/`,
+		RunE: fetch.RunE,
+	}
+
+	cmd.Flags().StringVarP(&fetch.name, "name", "n", "", "Table name")
+	cmd.Flags().StringVarP(&fetch.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
+	cmd.Flags().StringVarP(&fetch.outputDir, "output-dir", "o", "", "Output directory")
+	return cmd
+}
+
+func (r *fetchDDLCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.name == "" && r.fileName == "" {
+		return errors.New("either --name or --filename is required")
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	var tableNames []string
+	if r.name != "" {
+		tableNames = []string{r.name}
+	}
+
+	if r.fileName != "" {
+		lines, err := cmdutil.ReadLines(r.fileName, os.Stdin)
+		if err != nil {
+			return err
+		}
+		tableNames = lines
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Table", "Status", "Error"})
+
+	failed := 0
+	for ps, tables := range names.GroupTableNames(tableNames) {
+		client, errClient := provider.GetClient(ps.ProjectID)
+		if errClient == nil {
+			client.SetCurrentSchemaName(ps.SchemaID)
+		}
+
+		for _, t1 := range tables {
+			name := ps.TableName(t1)
+			printer.AddField(name)
+
+			err = errClient
+			if err == nil {
+				err = r.writeDDL(client, name, t1)
+			}
+			if err != nil {
+				failed++
+				printer.AddField("failed")
+				printer.AddField(err.Error())
+			} else {
+				printer.AddField("success")
+				printer.AddField("")
+			}
+			printer.EndRow()
+		}
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to fetch ddl for %d tables", failed)
+	}
+	return nil
+}
+
+func (r *fetchDDLCommand) writeDDL(client *odps.Odps, name, tableID string) error {
+	tabl := client.Table(tableID)
+	err := tabl.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load table: %w", err)
+	}
+
+	ddl := internal.GenerateDDL(internal.NewTableInfo(name, tabl))
+
+	toWritePath := name + ".sql"
+	if r.outputDir != "" {
+		toWritePath = path.Join(r.outputDir, toWritePath)
+	}
+
+	err = cmdutil.WriteFileAndDir(toWritePath, []byte(ddl))
+	if err != nil {
+		return fmt.Errorf("failure in write file: %w", err)
+	}
+	return nil
+}
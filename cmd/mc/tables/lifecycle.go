@@ -0,0 +1,202 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
+)
+
+// NewLifecycleCommand groups the commands for lifecycle of tables
+func NewLifecycleCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "lifecycle",
+		Short:   "Manage the lifecycle of tables",
+		Example: "opms mc table lifecycle set -f tables.txt --days 30",
+	}
+	cmd.AddCommand(NewLifecycleSetCommand(cfg))
+	return cmd
+}
+
+type lifecycleSetCommand struct {
+	cfg *config.Config
+
+	name     string
+	fileName string
+	days     int
+	dryRun   bool
+	yes      bool
+	retries  int
+}
+
+// NewLifecycleSetCommand sets the lifecycle for a list of tables
+func NewLifecycleSetCommand(cfg *config.Config) *cobra.Command {
+	lc := &lifecycleSetCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set the lifecycle in days for tables",
+		Long: `Set the lifecycle of tables after confirmation, data not modified for the lifecycle
+days is removed by maxcompute. Every change is recorded in the audit file under the cache directory.`,
+		Example: `opms mc table lifecycle set -n proj.schema.table --days 30
+opms mc table lifecycle set -f stale.txt --days 7 --dry-run`,
+		RunE: lc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&lc.name, "name", "n", "", "Table name")
+	cmd.Flags().StringVarP(&lc.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
+	cmd.Flags().IntVar(&lc.days, "days", 0, "Lifecycle in days")
+	cmd.Flags().BoolVar(&lc.dryRun, "dry-run", false, "Only show the changes")
+	cmd.Flags().BoolVarP(&lc.yes, "yes", "y", false, "Apply without asking for confirmation")
+	cmd.Flags().IntVar(&lc.retries, "retries", 3, "Number of attempts on connection errors")
+	cmd.MarkFlagRequired("days")
+	return cmd
+}
+
+func (r *lifecycleSetCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.days <= 0 {
+		return errors.New("--days should be more than 0")
+	}
+
+	var tableNames []string
+	if r.name != "" {
+		if r.fileName != "" {
+			return errors.New("--filename flag cannot be used along with name")
+		}
+		tableNames = append(tableNames, r.name)
+	}
+
+	if r.fileName != "" {
+		lines, err := cmdutil.ReadLines(r.fileName, os.Stdin)
+		if err != nil {
+			return err
+		}
+		tableNames = lines
+	}
+
+	if len(tableNames) == 0 {
+		return errors.New("either --name or --filename is required")
+	}
+	if r.fileName == "-" && !r.yes && !r.dryRun {
+		return errors.New("--yes is required when reading table names from stdin")
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Table", "Current", "New", "Action"})
+
+	type change struct {
+		schema  names.Schema
+		tableID string
+		current int
+	}
+
+	var changes []change
+	for ps, tables := range names.GroupTableNames(tableNames) {
+		client, errClient := provider.GetClient(ps.ProjectID)
+		if errClient == nil {
+			client.SetCurrentSchemaName(ps.SchemaID)
+		}
+
+		for _, tableID := range tables {
+			printer.AddField(ps.TableName(tableID))
+
+			var tabl *odps.Table
+			err = errClient
+			if err == nil {
+				tabl = client.Table(tableID)
+				err = internal.Retry(r.retries, tabl.Load)
+			}
+			if err != nil {
+				printer.AddField("")
+				printer.AddField(strconv.Itoa(r.days))
+				printer.AddField("skip: failed to load: " + err.Error())
+				printer.EndRow()
+				continue
+			}
+
+			current := tabl.Lifecycle()
+			printer.AddField(strconv.Itoa(current))
+			printer.AddField(strconv.Itoa(r.days))
+			if current == r.days {
+				printer.AddField("unchanged")
+			} else {
+				printer.AddField("set")
+				changes = append(changes, change{schema: ps, tableID: tableID, current: current})
+			}
+			printer.EndRow()
+		}
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+
+	if r.dryRun || len(changes) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Set lifecycle of %s to %d days? [y/N]: ", text.Pluralize(len(changes), "table"), r.days)
+	if !r.yes && !internal.Confirm(msg) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	failed := 0
+	for _, c := range changes {
+		client, err := provider.GetClient(c.schema.ProjectID)
+		if err != nil {
+			return err
+		}
+		client.SetCurrentSchemaName(c.schema.SchemaID)
+
+		entry := internal.AuditEntry{
+			Action:  "set_lifecycle",
+			Target:  c.schema.TableName(c.tableID),
+			Details: fmt.Sprintf("lifecycle=%d previous=%d", r.days, c.current),
+			Status:  "success",
+		}
+
+		tabl := client.Table(c.tableID)
+		err = internal.Retry(r.retries, func() error {
+			return tabl.SetLifeCycle(r.days)
+		})
+		if err != nil {
+			failed++
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			fmt.Printf("failed to set lifecycle of %s: %s\n", entry.Target, err)
+		}
+
+		err = internal.WriteAudit(r.cfg, entry)
+		if err != nil {
+			fmt.Printf("[WARN] failed to write audit for %s: %s\n", entry.Target, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to set lifecycle of %s", text.Pluralize(failed, "table"))
+	}
+	fmt.Printf("Lifecycle set for %s\n", text.Pluralize(len(changes), "table"))
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/mc/internal"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/config"
 	"github.com/sbchaos/opms/lib/printers/table"
@@ -36,7 +37,7 @@ func NewListCommand(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&list.project, "project", "p", "", "Project")
-	cmd.Flags().StringVarP(&list.schema, "schema", "s", "", "Schema")
+	cmd.Flags().StringVarP(&list.schema, "schema", "s", "", "Schema, * for all schemas of the project")
 
 	cmd.Flags().StringVarP(&list.namePrefix, "prefix", "n", "", "Table name prefix")
 	cmd.Flags().StringVarP(&list.tableType, "type", "t", "", "Table type to query, eg MANAGED_TABLE, VIRTUAL_TABLE, EXTERNAL_TABLE")
@@ -44,22 +45,22 @@ func NewListCommand(cfg *config.Config) *cobra.Command {
 }
 
 func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
-	client, err := mcc.NewClientFromConfig(r.cfg)
+	provider, err := mcc.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
 	}
 
-	schema := "default"
-	if r.schema != "" {
-		schema = r.schema
+	client, err := provider.GetClient(r.project)
+	if err != nil {
+		return err
 	}
-
 	proj := client.DefaultProjectName()
-	if r.project != "" {
-		proj = r.project
+
+	schemas, err := internal.ResolveSchemas(client, proj, r.schema)
+	if err != nil {
+		return err
 	}
 
-	tables := odps.NewTables(client, proj, schema)
 	var filters []odps.TFilterFunc = nil
 
 	if r.namePrefix != "" {
@@ -78,9 +79,17 @@ func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
 	size, _ := t.Size(120)
 
 	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	fn := setupPrinter(printer)
+	withSchema := r.schema == internal.AllSchemas
+	if withSchema {
+		printer.AddHeader([]string{"Schema", "Table Name", "Type", "Last Update"})
+	} else {
+		printer.AddHeader([]string{"Table Name", "Type", "Last Update"})
+	}
 
-	tables.List(fn, filters...)
+	for _, schema := range schemas {
+		tables := odps.NewTables(client, proj, schema)
+		tables.List(setupPrinter(printer, schema, withSchema), filters...)
+	}
 
 	err = printer.Render()
 	if err != nil {
@@ -89,15 +98,16 @@ func (r *listCommand) RunE(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func setupPrinter(printer table.Printer) func(*odps.Table, error) {
-	printer.AddHeader([]string{"Table Name", "Type", "Last Update"})
-
+func setupPrinter(printer table.Printer, schema string, withSchema bool) func(*odps.Table, error) {
 	return func(table *odps.Table, err error) {
 		if err != nil {
 			fmt.Printf("[ERROR] %s\n", err)
 			return
 		}
 
+		if withSchema {
+			printer.AddField(schema)
+		}
 		printer.AddField(table.Name())
 		printer.AddField(table.Type().String())
 		printer.AddField(table.LastModifiedTime().String())
@@ -2,56 +2,133 @@ package tables
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strconv"
+	"strings"
 
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tunnel"
 	"github.com/spf13/cobra"
 
 	"github.com/sbchaos/opms/cmd/mc/internal"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
 	"github.com/sbchaos/opms/lib/printers/table"
 	"github.com/sbchaos/opms/lib/term"
 )
 
-var (
-	queryFields = `SELECT * FROM `
-)
-
 type readTable struct {
 	cfg *config.Config
 
-	name string
+	name       string
+	limit      int
+	columns    []string
+	where      string
+	partitions []string
+	sample     float64
+	format     string
+	timezone   string
+	precision  int
+	useTunnel  bool
+
+	formatter internal.Formatter
 }
 
-// NewReadTableCommand checks if the tables exist
+// NewReadTableCommand reads the rows from a table
 func NewReadTableCommand(cfg *config.Config) *cobra.Command {
 	ec := &readTable{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "read",
-		Short:   "Read data from a table",
-		Example: "opms mc table read",
-		RunE:    ec.RunE,
+		Use:   "read",
+		Short: "Read data from a table",
+		Example: `opms mc table read -n proj.schema.table --partition dt=2024-01-01 --limit 10
+opms mc table read -n proj.schema.table -p dt=2024-01-01 -c id,name --where "id > 10" --format csv
+opms mc table read -n proj.schema.table -p dt=2024-01-01 --tunnel --limit 0 --format csv`,
+		RunE: ec.RunE,
 	}
 
 	cmd.Flags().StringVarP(&ec.name, "name", "n", "", "Table name")
+	cmd.Flags().IntVarP(&ec.limit, "limit", "l", 100, "Maximum number of rows to read, 0 for no limit")
+	cmd.Flags().StringSliceVarP(&ec.columns, "columns", "c", nil, "Columns to read, comma separated")
+	cmd.Flags().StringVarP(&ec.where, "where", "w", "", "Filter condition for the rows")
+	cmd.Flags().StringArrayVarP(&ec.partitions, "partition", "p", nil, "Partition to read as key=value, can be repeated")
+	cmd.Flags().Float64VarP(&ec.sample, "sample", "s", 0, "Read a random sample with the fraction of rows, eg 0.01")
+	cmd.Flags().StringVarP(&ec.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().StringVar(&ec.timezone, "timezone", "", "Timezone for DATETIME and TIMESTAMP values, eg Asia/Jakarta")
+	cmd.Flags().IntVar(&ec.precision, "precision", -1, "Number of decimals for FLOAT and DOUBLE values, -1 for exact value")
+	cmd.Flags().BoolVarP(&ec.useTunnel, "tunnel", "t", false, "Read using the tunnel instead of SQL, for large extracts")
 	cmd.MarkFlagRequired("name")
 	return cmd
 }
 
 func (r *readTable) RunE(_ *cobra.Command, _ []string) error {
+	if r.sample < 0 || r.sample >= 1 {
+		return errors.New("--sample should be a fraction between 0 and 1")
+	}
+	if r.useTunnel && (r.where != "" || r.sample > 0) {
+		return errors.New("--where and --sample are not supported with --tunnel")
+	}
+
+	formatter, err := internal.NewFormatter(r.timezone, r.precision)
+	if err != nil {
+		return err
+	}
+	r.formatter = formatter
+
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
+	spec, err := internal.ParsePartitionSpec(r.partitions)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return err
+	}
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	tabl := client.Table(tab.TableID)
+	err = tabl.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load table: %w", err)
+	}
+
+	err = r.validate(tabl, spec)
+	if err != nil {
+		return err
+	}
+
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
-	client, err := mcc.NewSQLClientFromConfig(r.cfg)
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
 	if err != nil {
 		return err
 	}
 
-	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	err = runQuery(client, r.name, printer)
+	if r.useTunnel {
+		err = r.readWithTunnel(client, tab, spec, printer)
+	} else {
+		sqlClient, errClient := provider.GetSQLClient(tab.Schema.ProjectID)
+		if errClient != nil {
+			return errClient
+		}
+		err = runQuery(sqlClient, r.buildQuery(tab, spec), printer, r.formatter, r.withRowNum())
+	}
 	if err != nil {
 		return err
 	}
@@ -59,20 +136,148 @@ func (r *readTable) RunE(_ *cobra.Command, _ []string) error {
 	return printer.Render()
 }
 
-func runQuery(client *sql.DB, name string, printer table.Printer) error {
-	query := queryFields + name + ";"
+func (r *readTable) validate(t *odps.Table, spec internal.PartitionSpec) error {
+	partitionCols := t.PartitionColumns()
+	err := spec.Validate(partitionCols)
+	if err != nil {
+		return err
+	}
+
+	if len(partitionCols) > 0 && len(spec) == 0 && r.where == "" {
+		cols := make([]string, len(partitionCols))
+		for i, c := range partitionCols {
+			cols[i] = c.Name
+		}
+		return fmt.Errorf("table is partitioned by %s, provide --partition or a --where on them", strings.Join(cols, ", "))
+	}
+
+	available := map[string]bool{}
+	for _, c := range t.Schema().Columns {
+		available[strings.ToLower(c.Name)] = true
+	}
+	for _, c := range partitionCols {
+		available[strings.ToLower(c.Name)] = true
+	}
+
+	for _, c := range r.columns {
+		if !available[strings.ToLower(c)] {
+			return fmt.Errorf("column %s not found in table", c)
+		}
+	}
+	return nil
+}
+
+func (r *readTable) withRowNum() bool {
+	return strings.EqualFold(r.format, table.FormatTable) || r.format == ""
+}
+
+func (r *readTable) buildQuery(tab names.Table, spec internal.PartitionSpec) string {
+	cols := "*"
+	if len(r.columns) > 0 {
+		cols = strings.Join(r.columns, ", ")
+	}
+
+	var conditions []string
+	if len(spec) > 0 {
+		conditions = append(conditions, spec.Condition())
+	}
+	if r.where != "" {
+		conditions = append(conditions, "("+r.where+")")
+	}
+	if r.sample > 0 {
+		conditions = append(conditions, "rand() < "+strconv.FormatFloat(r.sample, 'f', -1, 64))
+	}
+
+	query := "SELECT " + cols + " FROM " + tab.String()
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if r.limit > 0 {
+		query += " LIMIT " + strconv.Itoa(r.limit)
+	}
+	return query + ";"
+}
+
+func (r *readTable) readWithTunnel(client *odps.Odps, tab names.Table, spec internal.PartitionSpec, printer table.Printer) error {
+	tun, err := mcc.NewTunnel(client, tab.Schema.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to create tunnel: %w", err)
+	}
+
+	opts := []tunnel.Option{tunnel.SessionCfg.WithSchemaName(tab.Schema.SchemaID)}
+	if len(spec) > 0 {
+		opts = append(opts, tunnel.SessionCfg.WithPartitionKey(spec.String()))
+	}
+
+	session, err := tun.CreateDownloadSession(tab.Schema.ProjectID, tab.TableID, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create download session: %w", err)
+	}
+
+	count := session.RecordCount()
+	if r.limit > 0 && r.limit < count {
+		count = r.limit
+	}
+
+	headers := r.columns
+	if len(headers) == 0 {
+		for _, c := range session.Schema().Columns {
+			headers = append(headers, c.Name)
+		}
+	}
+
+	withRowNum := r.withRowNum()
+	if withRowNum {
+		printer.AddHeader(append([]string{"Row"}, headers...))
+	} else {
+		printer.AddHeader(headers)
+	}
+
+	reader, err := session.OpenRecordReader(0, count, r.columns)
+	if err != nil {
+		return fmt.Errorf("failed to open reader: %w", err)
+	}
+	defer reader.Close()
+
+	rowNum := 1
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if withRowNum {
+			printer.AddField(strconv.Itoa(rowNum))
+		}
+		for _, d := range record {
+			r.formatter.AddField(printer, d)
+		}
+		printer.EndRow()
+		rowNum++
+	}
+	return nil
+}
+
+func runQuery(client *sql.DB, query string, printer table.Printer, formatter internal.Formatter, withRowNum bool) error {
 	rows, err := client.Query(query)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	cols, err := rows.Columns()
 	if err != nil {
 		return err
 	}
 
-	headers := append([]string{"Row"}, cols...)
-	printer.AddHeader(headers)
+	if withRowNum {
+		printer.AddHeader(append([]string{"Row"}, cols...))
+	} else {
+		printer.AddHeader(cols)
+	}
 
 	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
@@ -91,14 +296,15 @@ func runQuery(client *sql.DB, name string, printer table.Printer) error {
 			return err
 		}
 
-		printer.AddField(strconv.Itoa(rowNum))
+		if withRowNum {
+			printer.AddField(strconv.Itoa(rowNum))
+		}
 		for _, r := range record {
-			str := internal.ToString(r)
-			printer.AddField(str)
+			formatter.AddField(printer, r)
 		}
 		printer.EndRow()
 		rowNum++
 	}
 
-	return nil
+	return rows.Err()
 }
@@ -19,6 +19,10 @@ func NewTableCommand(cfg *config.Config) *cobra.Command {
 		NewDropCommand(cfg),
 		NewDescCommand(cfg),
 		NewReadTableCommand(cfg),
+		NewFetchDDLCommand(cfg),
+		NewUploadCommand(cfg),
+		NewDownloadCommand(cfg),
+		NewLifecycleCommand(cfg),
 	)
 
 	return cmd
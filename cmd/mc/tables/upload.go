@@ -0,0 +1,489 @@
+package tables
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/data"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tableschema"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tunnel"
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	pqfile "github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/progress"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+const (
+	inputCSV     = "csv"
+	inputTSV     = "tsv"
+	inputParquet = "parquet"
+)
+
+type uploadCommand struct {
+	cfg *config.Config
+
+	name          string
+	fileName      string
+	partitions    []string
+	inputFormat   string
+	columns       []string
+	noHeader      bool
+	nullValue     string
+	maxBadRecords int
+	overwrite     bool
+}
+
+// NewUploadCommand loads a local file into a table using the tunnel
+func NewUploadCommand(cfg *config.Config) *cobra.Command {
+	uc := &uploadCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "upload",
+		Short: "Upload a csv, tsv or parquet file into a table",
+		Long: `Upload the rows of a csv, tsv or parquet file into a table using the tunnel.
+Columns are matched to the header of the file by name, columns of file not present in the table are ignored.
+Parquet files use the names of the columns in the file, nested and repeated columns are not supported.
+Timestamps of parquet without timezone are taken as local time, like the values of text files.
+Values are converted to the type of the column, rows which cannot be converted are counted as bad records.
+Output of opms gsheet read and opms oss csv with --format csv can be piped with --file -.`,
+		Example: `opms mc table upload -n proj.schema.table -f data.csv -p dt=2024-01-01
+opms mc table upload -n proj.schema.table -f data.tsv --no-header --columns id,name --overwrite
+opms mc table upload -n proj.schema.table -f data.parquet -p dt=2024-01-01
+opms gsheet read -s <sheet_url> --format csv | opms mc table upload -n proj.schema.table -f -`,
+		RunE: uc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&uc.name, "name", "n", "", "Table name")
+	cmd.Flags().StringVarP(&uc.fileName, "file", "f", "", "File to upload, - for stdin")
+	cmd.Flags().StringArrayVarP(&uc.partitions, "partition", "p", nil, "Partition to upload into as key=value, can be repeated")
+	cmd.Flags().StringVar(&uc.inputFormat, "input-format", "", "Format of the file: csv, tsv or parquet, detected from extension by default")
+	cmd.Flags().StringSliceVarP(&uc.columns, "columns", "c", nil, "Columns of the file, used instead of the header")
+	cmd.Flags().BoolVar(&uc.noHeader, "no-header", false, "File does not have a header row")
+	cmd.Flags().StringVar(&uc.nullValue, "null-value", "", "Value of text files to be loaded as NULL")
+	cmd.Flags().IntVar(&uc.maxBadRecords, "max-bad-records", 0, "Number of rows which can fail conversion before aborting")
+	cmd.Flags().BoolVar(&uc.overwrite, "overwrite", false, "Overwrite the table or partition instead of appending")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func (r *uploadCommand) RunE(_ *cobra.Command, _ []string) error {
+	format, err := r.detectFormat()
+	if err != nil {
+		return err
+	}
+
+	tab, err := names.FromTableName(r.name)
+	if err != nil {
+		return err
+	}
+
+	spec, err := internal.ParsePartitionSpec(r.partitions)
+	if err != nil {
+		return err
+	}
+
+	provider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := provider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return err
+	}
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	tabl := client.Table(tab.TableID)
+	err = tabl.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load table: %w", err)
+	}
+
+	partitionCols := tabl.PartitionColumns()
+	err = spec.Validate(partitionCols)
+	if err != nil {
+		return err
+	}
+	if len(partitionCols) != len(spec) {
+		return fmt.Errorf("table is partitioned by %d columns, provide all of them with --partition", len(partitionCols))
+	}
+
+	src, err := r.openSource(format)
+	if err != nil {
+		return err
+	}
+	defer src.close()
+
+	header := src.header()
+	if len(header) == 0 {
+		return errors.New("file does not have a header, provide --columns")
+	}
+
+	tableCols := tabl.Schema().Columns
+	positions, err := mapColumns(header, tableCols)
+	if err != nil {
+		return err
+	}
+
+	if len(spec) > 0 {
+		err = tabl.AddPartition(true, spec.String())
+		if err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", spec.String(), err)
+		}
+	}
+
+	tun, err := mcc.NewTunnel(client, tab.Schema.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to create tunnel: %w", err)
+	}
+
+	opts := []tunnel.Option{tunnel.SessionCfg.WithSchemaName(tab.Schema.SchemaID)}
+	if len(spec) > 0 {
+		opts = append(opts, tunnel.SessionCfg.WithPartitionKey(spec.String()))
+	}
+	if r.overwrite {
+		opts = append(opts, tunnel.SessionCfg.Overwrite())
+	}
+
+	session, err := tun.CreateUploadSession(tab.Schema.ProjectID, tab.TableID, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	writer, err := session.OpenRecordWriter(0)
+	if err != nil {
+		return fmt.Errorf("failed to open record writer: %w", err)
+	}
+
+	written, bad := 0, 0
+	for n := src.first(); ; n++ {
+		row, err := src.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writer.Close()
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+
+		record, err := r.toRecord(row, positions, tableCols)
+		if err != nil {
+			bad++
+			fmt.Fprintf(os.Stderr, "Bad record at %s %d: %s\n", src.unit(), n, err)
+			if bad > r.maxBadRecords {
+				writer.Close()
+				return fmt.Errorf("aborting upload, found %d bad records more than --max-bad-records %d", bad, r.maxBadRecords)
+			}
+			continue
+		}
+
+		err = writer.Write(record)
+		if err != nil {
+			writer.Close()
+			return fmt.Errorf("failed to write record at %s %d: %w", src.unit(), n, err)
+		}
+		written++
+	}
+	src.done()
+
+	err = writer.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close record writer: %w", err)
+	}
+
+	err = session.Commit([]int{0})
+	if err != nil {
+		return fmt.Errorf("failed to commit upload: %w", err)
+	}
+
+	fmt.Printf("Uploaded %d rows into %s, skipped %d bad records\n", written, targetName(tab, spec), bad)
+	return nil
+}
+
+func (r *uploadCommand) detectFormat() (string, error) {
+	format := strings.ToLower(r.inputFormat)
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(r.fileName)) {
+		case ".tsv", ".tab":
+			format = inputTSV
+		case ".parquet":
+			format = inputParquet
+		default:
+			format = inputCSV
+		}
+	}
+
+	switch format {
+	case inputCSV, inputTSV, inputParquet:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown input format %s, use csv, tsv or parquet", r.inputFormat)
+}
+
+// open returns the input with its size in bytes, size is 0 when not known
+func (r *uploadCommand) open() (io.ReadCloser, int64, error) {
+	if r.fileName == "-" {
+		return io.NopCloser(os.Stdin), 0, nil
+	}
+
+	f, err := os.Open(r.fileName)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("failed to read file info: %w", err)
+	}
+	return f, stat.Size(), nil
+}
+
+// rowSource reads the rows of the file, values are strings for text files and typed for parquet
+type rowSource interface {
+	header() []string
+	next() ([]any, error)
+	// first is the number of the first row and unit what it counts, used in errors
+	first() int
+	unit() string
+	done()
+	close() error
+}
+
+func (r *uploadCommand) openSource(format string) (rowSource, error) {
+	in, size, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	if format == inputParquet {
+		src, err := r.parquetSource(in)
+		if err != nil {
+			in.Close()
+			return nil, err
+		}
+		return src, nil
+	}
+
+	bar := progress.New(os.Stderr, "Uploaded bytes", size, term.IsTerminal(os.Stderr))
+	reader := csv.NewReader(&progress.Reader{Reader: in, Bar: bar})
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	if format == inputTSV {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	src := &csvSource{in: in, reader: reader, bar: bar, columns: r.columns, nullValue: r.nullValue, line: 1}
+	if !r.noHeader {
+		row, err := reader.Read()
+		if err != nil {
+			in.Close()
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		if len(src.columns) == 0 {
+			src.columns = append([]string{}, row...)
+		}
+		src.line++
+	}
+	return src, nil
+}
+
+func (r *uploadCommand) parquetSource(in io.ReadCloser) (rowSource, error) {
+	file, ok := in.(parquet.ReaderAtSeeker)
+	if !ok {
+		// parquet metadata is at the end, stdin is read in memory
+		content, err := io.ReadAll(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		file = bytes.NewReader(content)
+	}
+
+	pf, err := pqfile.NewParquetReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: 10000}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+	schema, err := reader.Schema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet schema: %w", err)
+	}
+	err = internal.CheckArrowSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]string, schema.NumFields())
+	for i, f := range schema.Fields() {
+		header[i] = f.Name
+	}
+	if len(r.columns) > 0 {
+		if len(r.columns) != len(header) {
+			return nil, fmt.Errorf("file has %d columns, --columns has %d", len(header), len(r.columns))
+		}
+		header = r.columns
+	}
+
+	records, err := reader.GetRecordReader(context.Background(), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet file: %w", err)
+	}
+
+	bar := progress.New(os.Stderr, "Uploaded rows", pf.NumRows(), term.IsTerminal(os.Stderr))
+	return &parquetSource{in: in, records: records, bar: bar, names: header, local: internal.LocalTimestamps(pf)}, nil
+}
+
+type csvSource struct {
+	in        io.Closer
+	reader    *csv.Reader
+	bar       *progress.Bar
+	columns   []string
+	nullValue string
+	line      int
+}
+
+func (s *csvSource) header() []string { return s.columns }
+func (s *csvSource) first() int       { return s.line }
+func (s *csvSource) unit() string     { return "line" }
+func (s *csvSource) done()            { s.bar.Done() }
+func (s *csvSource) close() error     { return s.in.Close() }
+
+func (s *csvSource) next() ([]any, error) {
+	row, err := s.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(row))
+	for i, v := range row {
+		if v != s.nullValue {
+			values[i] = v
+		}
+	}
+	return values, nil
+}
+
+type parquetSource struct {
+	in      io.Closer
+	records pqarrow.RecordReader
+	bar     *progress.Bar
+	names   []string
+	local   []bool
+	record  arrow.Record
+	row     int
+}
+
+func (s *parquetSource) header() []string { return s.names }
+func (s *parquetSource) first() int       { return 1 }
+func (s *parquetSource) unit() string     { return "row" }
+func (s *parquetSource) done()            { s.bar.Done() }
+
+func (s *parquetSource) close() error {
+	s.records.Release()
+	return s.in.Close()
+}
+
+func (s *parquetSource) next() ([]any, error) {
+	for s.record == nil || s.row >= int(s.record.NumRows()) {
+		if !s.records.Next() {
+			if err := s.records.Err(); err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		s.record, s.row = s.records.Record(), 0
+	}
+
+	row := make([]any, s.record.NumCols())
+	for i, col := range s.record.Columns() {
+		row[i] = internal.ArrowValue(col, s.row)
+		if t, ok := row[i].(time.Time); ok && s.local[i] {
+			// wall clock of timestamps without timezone is in local time, like the text values
+			row[i] = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+		}
+	}
+	s.row++
+	s.bar.Add(1)
+	return row, nil
+}
+
+func (r *uploadCommand) toRecord(row []any, positions []int, cols []tableschema.Column) (data.Record, error) {
+	record := data.NewRecord(len(cols))
+	for i, col := range cols {
+		pos := positions[i]
+		if pos < 0 || pos >= len(row) || row[pos] == nil {
+			if !col.IsNullable {
+				return nil, fmt.Errorf("missing value for column %s", col.Name)
+			}
+			continue
+		}
+
+		value, err := internal.FromValue(col.Type, row[pos])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		record[i] = value
+	}
+	return record, nil
+}
+
+// mapColumns returns the position in header for every column of the table, -1 when not present
+func mapColumns(header []string, cols []tableschema.Column) ([]int, error) {
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	positions := make([]int, len(cols))
+	matched := map[string]bool{}
+	for i, c := range cols {
+		name := strings.ToLower(c.Name)
+		pos, ok := index[name]
+		if !ok {
+			pos = -1
+		}
+		positions[i] = pos
+		matched[name] = ok
+	}
+
+	var ignored []string
+	for _, h := range header {
+		if !matched[strings.ToLower(strings.TrimSpace(h))] {
+			ignored = append(ignored, h)
+		}
+	}
+	if len(ignored) == len(header) {
+		return nil, errors.New("none of the columns of file are present in the table")
+	}
+	if len(ignored) > 0 {
+		fmt.Fprintf(os.Stderr, "Ignoring columns not present in table: %s\n", strings.Join(ignored, ", "))
+	}
+	return positions, nil
+}
+
+func targetName(tab names.Table, spec internal.PartitionSpec) string {
+	if len(spec) == 0 {
+		return tab.String()
+	}
+	return tab.String() + " (" + spec.String() + ")"
+}
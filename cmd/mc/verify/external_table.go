@@ -6,16 +6,23 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/spf13/cobra"
 
+	"github.com/sbchaos/opms/cmd/mc/internal"
+	"github.com/sbchaos/opms/external/gcp"
+	"github.com/sbchaos/opms/external/gsheet"
 	mcc "github.com/sbchaos/opms/external/mc"
 	"github.com/sbchaos/opms/lib/cmdutil"
 	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
 	"github.com/sbchaos/opms/lib/pool"
 	"github.com/sbchaos/opms/lib/printers/table"
 	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/text"
 )
 
 var (
@@ -25,14 +32,30 @@ var (
 	queryFields = `SELECT * FROM `
 )
 
+// categories of the problems found in an external table
+const (
+	categoryQuery  = "query_failed"
+	categoryCount  = "count_mismatch"
+	categorySource = "source_unavailable"
+)
+
 type externalTableCommand struct {
 	cfg *config.Config
 
 	mu      *sync.Mutex
 	workers int
-
-	name     string
-	fileName string
+	invalid map[string]bool
+
+	name        string
+	fileName    string
+	resourceDir string
+	sheetURL    string
+	sheetRange  string
+	gcpProject  string
+	sampleRows  int
+
+	mc       *mcc.ClientProvider
+	provider *gcp.ClientProvider
 }
 
 // NewExternalTableCommand checks if the tables exist
@@ -40,27 +63,49 @@ func NewExternalTableCommand(cfg *config.Config) *cobra.Command {
 	ec := &externalTableCommand{cfg: cfg}
 
 	cmd := &cobra.Command{
-		Use:     "externalTable",
-		Short:   "Verify the externalTable in maxcompute",
-		Example: "opms mc verify externalTable",
-		RunE:    ec.RunE,
+		Use:   "externalTable",
+		Short: "Verify the externalTable in maxcompute",
+		Long: `Verify the external tables by comparing count(*) with the rows read by the client.
+When the google sheet source is known, the header of sheet is compared with the table schema
+and a sample of rows is converted to the column types. The source is read from --sheet and --range,
+or from <resource-dir>/<table>/resource.yaml as generated by opms optimus generate externalTable.
+The sheet is not part of the maxcompute table, without --sheet or --resource-dir only the counts are checked.
+Exits with an error when any table has a problem.`,
+		Example: `opms mc verify externalTable -n proj.schema.table
+opms mc verify externalTable -n proj.schema.table --sheet <sheet_url> --range 'Sheet1!A:D'
+opms mc verify externalTable -f tables.txt -d generated/maxcompute -w 4`,
+		RunE: ec.RunE,
 	}
 
 	cmd.Flags().StringVarP(&ec.name, "name", "n", "", "Table name")
 	cmd.Flags().StringVarP(&ec.fileName, "filename", "f", "", "Filename with list of tables, - for stdin")
 	cmd.Flags().IntVarP(&ec.workers, "workers", "w", 1, "Number of parallel workers")
+	cmd.Flags().StringVarP(&ec.resourceDir, "resource-dir", "d", "", "Directory with the resource specs of tables")
+	cmd.Flags().StringVar(&ec.sheetURL, "sheet", "", "Google sheet URL of the table, only with --name")
+	cmd.Flags().StringVar(&ec.sheetRange, "range", "", "Sheet range of the table, only with --sheet")
+	cmd.Flags().StringVar(&ec.gcpProject, "gcp-project", "", "Project for the google sheets credentials")
+	cmd.Flags().IntVar(&ec.sampleRows, "sample", 100, "Number of sheet rows checked for type conversion")
 	return cmd
 }
 
 func (r *externalTableCommand) RunE(_ *cobra.Command, _ []string) error {
 	r.mu = &sync.Mutex{}
+	r.invalid = map[string]bool{}
 	t := term.FromEnv(0, 0)
 	size, _ := t.Size(120)
 
-	client, err := mcc.NewSQLClientFromConfig(r.cfg)
+	if r.sheetURL != "" && r.name == "" {
+		return errors.New("--sheet can only be used along with --name")
+	}
+	if r.sheetRange != "" && r.sheetURL == "" {
+		return errors.New("--range can only be used along with --sheet")
+	}
+
+	mc, err := mcc.NewClientProvider(r.cfg)
 	if err != nil {
 		return err
 	}
+	r.mc = mc
 
 	tables := make([]string, 0)
 	if r.name != "" {
@@ -79,14 +124,24 @@ func (r *externalTableCommand) RunE(_ *cobra.Command, _ []string) error {
 		tables = append(tables, fields...)
 	}
 
+	if r.sheetURL != "" || r.resourceDir != "" {
+		r.provider, err = gcp.NewClientProvider(r.cfg)
+		if err != nil {
+			return err
+		}
+	}
+
 	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
-	printer.AddHeader([]string{"Status", "Table Name", "COUNT*", "SIZE", "Error"})
+	printer.AddHeader([]string{"Status", "Table Name", "COUNT*", "SIZE", "Problems"})
+
+	problemPrinter := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	problemPrinter.AddHeader([]string{"Table Name", "Category", "Problem"})
 
 	tasks := make([]func() pool.JobResult[string], len(tables))
 	for i, t1 := range tables {
 		t1 := t1
 		tasks[i] = func() pool.JobResult[string] {
-			err = r.Validate(client, printer, t1)
+			err := r.Validate(printer, problemPrinter, t1)
 			return pool.JobResult[string]{
 				Output: t1,
 				Err:    err,
@@ -96,65 +151,180 @@ func (r *externalTableCommand) RunE(_ *cobra.Command, _ []string) error {
 
 	outchan := pool.RunWithWorkers(r.workers, tasks)
 
+	var failed []pool.JobResult[string]
+	for out := range outchan {
+		if out.Err != nil {
+			failed = append(failed, out)
+			r.mu.Lock()
+			r.invalid[out.Output] = true
+			r.mu.Unlock()
+		}
+	}
+
 	err = printer.Render()
 	if err != nil {
 		return fmt.Errorf("failed to print table: %w", err)
 	}
 
 	fmt.Println()
-	fmt.Println()
-	fmt.Fprintln(os.Stderr, "Error(s) encountered:")
-	for out := range outchan {
-		if out.Err != nil {
+	err = problemPrinter.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print problems: %w", err)
+	}
+
+	if r.provider == nil {
+		fmt.Fprintln(os.Stderr, "Sheet checks were skipped, use --sheet or --resource-dir to compare the sheet with the table schema")
+	}
+
+	if len(failed) > 0 {
+		fmt.Println()
+		fmt.Fprintln(os.Stderr, "Error(s) encountered:")
+		for _, out := range failed {
 			fmt.Fprintf(os.Stderr, "Name: %s, Err: %s\n", out.Output, out.Err)
 		}
 	}
 
+	if len(r.invalid) > 0 {
+		return fmt.Errorf("%s with problems", text.Pluralize(len(r.invalid), "table"))
+	}
 	return nil
 }
 
-func (r *externalTableCommand) Validate(client *sql.DB, printer table.Printer, name string) error {
+// Validate checks the counts and the sheet source of table, the problems are added to problemPrinter,
+// tables with problems are kept in invalid and the error of count query is returned
+func (r *externalTableCommand) Validate(printer, problemPrinter table.Printer, name string) error {
+	var problems []internal.Problem
+
 	countStar := int64(-1)
 	countRow := int64(0)
 
+	proj := ""
+	if tab, errName := names.FromTableName(name); errName == nil {
+		proj = tab.Schema.ProjectID
+	}
+
+	client, err := r.mc.GetSQLClient(proj)
+	if err != nil {
+		return err
+	}
+
 	res, err := runCountStar(client, name)
 	if err == nil {
 		countStar = res
+	} else {
+		problems = append(problems, internal.Problem{Category: categoryQuery, Detail: "count(*) failed: " + err.Error()})
 	}
 
 	if countStar > 0 && countStar < maxRecordLimit {
-		res2, err := runCount(client, name)
-		if err == nil {
+		res2, err2 := runCount(client, name)
+		if err2 == nil {
 			countRow = res2
+		} else {
+			err = err2
+			problems = append(problems, internal.Problem{Category: categoryQuery, Detail: "reading rows failed: " + err2.Error()})
 		}
 	}
 
+	if err == nil && countStar < maxRecordLimit && countStar != countRow {
+		problems = append(problems, internal.Problem{Category: categoryCount, Detail: fmt.Sprintf("count(*) is %d but %d rows were read", countStar, countRow)})
+	}
+
+	if r.provider != nil {
+		problems = append(problems, r.validateSource(name)...)
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if countStar == countRow {
-		printer.AddField(" ✅ ")
-	} else if countStar > maxRecordLimit {
+	if len(problems) > 0 {
+		r.invalid[name] = true
+	}
+	if len(problems) == 0 && countStar >= maxRecordLimit {
 		printer.AddField(" ❗ ")
+	} else if len(problems) == 0 {
+		printer.AddField(" ✅ ")
 	} else {
 		printer.AddField(" ❌ ")
 	}
 	printer.AddField(name)
 	printer.AddField(strconv.FormatInt(countStar, 10))
 	printer.AddField(strconv.FormatInt(countRow, 10))
-	if err != nil {
-		errFirstPart := err.Error()[0:20]
-		printer.AddField(errFirstPart)
-	}
+	printer.AddField(categories(problems))
 	printer.EndRow()
+
+	for _, p := range problems {
+		problemPrinter.AddField(name)
+		problemPrinter.AddField(p.Category)
+		problemPrinter.AddField(text.Truncate(120, p.Detail))
+		problemPrinter.EndRow()
+	}
 	return err
 }
 
+// validateSource compares the header of sheet with the table schema and converts a sample of rows
+func (r *externalTableCommand) validateSource(name string) []internal.Problem {
+	source, err := r.source(name)
+	if err != nil {
+		return []internal.Problem{{Category: categorySource, Detail: err.Error()}}
+	}
+
+	tab, err := names.FromTableName(name)
+	if err != nil {
+		return []internal.Problem{{Category: categorySource, Detail: err.Error()}}
+	}
+
+	client, err := r.mc.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return []internal.Problem{{Category: categorySource, Detail: err.Error()}}
+	}
+
+	t := odps.NewTable(client, tab.Schema.ProjectID, tab.Schema.SchemaID, tab.TableID)
+	err = t.Load()
+	if err != nil {
+		return []internal.Problem{{Category: categorySource, Detail: "failed to load table: " + err.Error()}}
+	}
+
+	service, err := r.provider.GetSheetsClient(r.gcpProject)
+	if err != nil {
+		return []internal.Problem{{Category: categorySource, Detail: "failed to create sheets client: " + err.Error()}}
+	}
+
+	content, err := gsheet.GetContent(service, source.URI, source.Range)
+	if err != nil {
+		return []internal.Problem{{Category: categorySource, Detail: "failed to read sheet: " + err.Error()}}
+	}
+	if len(content) == 0 {
+		return []internal.Problem{{Category: categorySource, Detail: "sheet range is empty"}}
+	}
+
+	return internal.CompareSheet(content, t.Schema().Columns, r.sampleRows)
+}
+
+func (r *externalTableCommand) source(name string) (*internal.SheetSource, error) {
+	if r.sheetURL != "" {
+		return &internal.SheetSource{URI: r.sheetURL, Range: r.sheetRange}, nil
+	}
+	return internal.LoadSheetSource(r.resourceDir, name)
+}
+
+func categories(problems []internal.Problem) string {
+	seen := map[string]bool{}
+	var cats []string
+	for _, p := range problems {
+		if !seen[p.Category] {
+			seen[p.Category] = true
+			cats = append(cats, p.Category)
+		}
+	}
+	return strings.Join(cats, ",")
+}
+
 func runCountStar(client *sql.DB, name string) (int64, error) {
 	query := countSQL + name + ";"
 	rows, err := client.Query(query)
 	if err != nil {
 		return -1, err
 	}
+	defer rows.Close()
 
 	rowCount := int64(0)
 	for rows.Next() {
@@ -164,7 +334,7 @@ func runCountStar(client *sql.DB, name string) (int64, error) {
 		}
 	}
 
-	return rowCount, nil
+	return rowCount, rows.Err()
 }
 
 func runCount(client *sql.DB, name string) (int64, error) {
@@ -173,11 +343,12 @@ func runCount(client *sql.DB, name string) (int64, error) {
 	if err != nil {
 		return -1, err
 	}
+	defer rows.Close()
 
 	rowCount := int64(0)
 	for rows.Next() {
 		rowCount++
 	}
 
-	return rowCount, nil
+	return rowCount, rows.Err()
 }
@@ -22,6 +22,10 @@ type YamlSpec struct {
 	Path         string              `yaml:"-"`
 }
 
+func (j YamlSpec) SpecName() string {
+	return j.Name
+}
+
 type JobSpecSchedule struct {
 	StartDate string `yaml:"start_date,omitempty"`
 	EndDate   string `yaml:"end_date,omitempty"`
@@ -0,0 +1,21 @@
+package lineage
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// NewLineageCommand initializes commands for lineage of queries
+func NewLineageCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "lineage",
+		Short:   "Commands for the table and column lineage of queries",
+		Example: "opms lineage [sub-command]",
+	}
+
+	cmd.AddCommand(
+		NewSQLCommand(cfg),
+	)
+	return cmd
+}
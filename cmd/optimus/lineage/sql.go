@@ -0,0 +1,324 @@
+package lineage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	optio "github.com/sbchaos/opms/cmd/optimus/internal/io"
+	"github.com/sbchaos/opms/cmd/optimus/internal/job"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/lineage"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+const (
+	formatJSON = "json"
+	formatDot  = "dot"
+)
+
+type sqlCommand struct {
+	cfg *config.Config
+
+	fileName string
+	dir      string
+	dialect  string
+	format   string
+}
+
+// edge is a dependency of destination on source, created by the query of a job
+type edge struct {
+	Job         string `json:"job,omitempty"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+type jobLineage struct {
+	Name string `json:"name"`
+	// Destination is the table of the task config, written by the select queries of the job
+	Destination string `json:"destination,omitempty"`
+	*lineage.Lineage
+}
+
+// NewSQLCommand extracts the lineage of a query or of the jobs in a directory
+func NewSQLCommand(cfg *config.Config) *cobra.Command {
+	sc := &sqlCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "sql",
+		Short: "Extract source and destination tables of sql queries",
+		Long: `Extract the source and destination tables of INSERT, CREATE, MERGE and SELECT statements,
+with the lineage of columns when it can be resolved from the query.
+With --dir the sql assets of all the optimus jobs are parsed to build the table dependency graph,
+the dialect of a job is detected from its task when --dialect is not set. Select queries of a job
+write to the destination in its task config, from DESTINATION_TABLE_ID or PROJECT, DATASET and TABLE.`,
+		Example: `opms lineage sql -f query.sql --dialect bq
+opms lineage sql -f query.sql --dialect mc -o json
+opms lineage sql -d jobs/ -o dot | dot -Tsvg > lineage.svg`,
+		RunE: sc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&sc.fileName, "file", "f", "", "Query file, - for stdin")
+	cmd.Flags().StringVarP(&sc.dir, "dir", "d", "", "Directory with optimus jobs")
+	cmd.Flags().StringVar(&sc.dialect, "dialect", "", "Dialect of queries: mc, bq")
+	cmd.Flags().StringVarP(&sc.format, "format", "o", table.FormatTable, "Output format: table, json, dot")
+	return cmd
+}
+
+func (r *sqlCommand) RunE(_ *cobra.Command, _ []string) error {
+	if (r.fileName == "") == (r.dir == "") {
+		return errors.New("one of --file or --dir is required")
+	}
+
+	switch strings.ToLower(r.format) {
+	case "", table.FormatTable, formatJSON, formatDot:
+	default:
+		return fmt.Errorf("unknown format %s, use table, json or dot", r.format)
+	}
+
+	var dialect lineage.Dialect
+	if r.dialect != "" {
+		d, err := lineage.ParseDialect(r.dialect)
+		if err != nil {
+			return err
+		}
+		dialect = d
+	}
+
+	if r.dir != "" {
+		return r.jobsLineage(dialect)
+	}
+
+	content, err := cmdutil.ReadFile(r.fileName, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if dialect == "" {
+		dialect = lineage.DialectMaxCompute
+	}
+	l, err := lineage.Parse(string(content), dialect)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", r.fileName, err)
+	}
+
+	switch strings.ToLower(r.format) {
+	case formatJSON:
+		return printJSON(l)
+	case formatDot:
+		return printDot(os.Stdout, edges("", l.Edges("")))
+	}
+	return printLineage(l)
+}
+
+// jobsLineage parses the sql assets of every job spec found under the directory
+func (r *sqlCommand) jobsLineage(dialect lineage.Dialect) error {
+	jobPaths := map[string][]string{}
+	err := optio.Walk[job.YamlSpec](r.dir, jobPaths, map[string][]string{})
+	if err != nil {
+		return fmt.Errorf("unable to walk dir %s: %w", r.dir, err)
+	}
+
+	var jobs []jobLineage
+	for _, paths := range jobPaths {
+		for _, path := range paths {
+			spec, err := optio.ReadSpec[job.YamlSpec](path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping job %s, err: %s\n", path, err)
+				continue
+			}
+
+			j, err := parseJob(path, spec, dialect)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping job %s, err: %s\n", path, err)
+				continue
+			}
+			jobs = append(jobs, j)
+		}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+
+	var all []edge
+	for _, j := range jobs {
+		all = append(all, edges(j.Name, j.Edges(j.Destination))...)
+	}
+
+	switch strings.ToLower(r.format) {
+	case formatJSON:
+		return printJSON(struct {
+			Jobs  []jobLineage `json:"jobs"`
+			Edges []edge       `json:"edges"`
+		}{Jobs: jobs, Edges: all})
+	case formatDot:
+		return printDot(os.Stdout, all)
+	}
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Job", "Source", "Destination"})
+	for _, e := range all {
+		printer.AddField(e.Job)
+		printer.AddField(e.Source)
+		printer.AddField(e.Destination)
+		printer.EndRow()
+	}
+	return printer.Render()
+}
+
+func parseJob(path string, spec job.YamlSpec, dialect lineage.Dialect) (jobLineage, error) {
+	if dialect == "" {
+		dialect = dialectOfTask(spec.Task.Name)
+	}
+
+	assets, err := filepath.Glob(filepath.Join(filepath.Dir(path), "assets", "*.sql"))
+	if err != nil {
+		return jobLineage{}, err
+	}
+
+	j := jobLineage{
+		Name:        spec.Name,
+		Destination: taskDestination(spec.Task.Config),
+		Lineage:     &lineage.Lineage{},
+	}
+	for _, asset := range assets {
+		query, err := os.ReadFile(asset)
+		if err != nil {
+			return j, err
+		}
+
+		l, err := lineage.Parse(string(query), dialect)
+		if err != nil {
+			return j, fmt.Errorf("failed to parse %s: %w", filepath.Base(asset), err)
+		}
+		j.Sources = append(j.Sources, l.Sources...)
+		j.Destinations = append(j.Destinations, l.Destinations...)
+		j.Columns = append(j.Columns, l.Columns...)
+		j.Statements = append(j.Statements, l.Statements...)
+	}
+
+	if j.Destination != "" && !slices.Contains(j.Destinations, j.Destination) {
+		j.Destinations = append(j.Destinations, j.Destination)
+	}
+	return j, nil
+}
+
+// taskDestination reads the destination table from the task config, as the full id in
+// DESTINATION_TABLE_ID or from PROJECT, DATASET or SCHEMA and TABLE like in bq2bq
+func taskDestination(config map[string]string) string {
+	if id := config["DESTINATION_TABLE_ID"]; id != "" {
+		return id
+	}
+
+	table := config["TABLE"]
+	if table == "" {
+		return ""
+	}
+
+	schema := config["DATASET"]
+	if schema == "" {
+		schema = config["SCHEMA"]
+	}
+
+	var parts []string
+	for _, p := range []string{config["PROJECT"], schema, table} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// dialectOfTask detects the dialect from the task name of optimus like bq2bq or mc2mc
+func dialectOfTask(task string) lineage.Dialect {
+	task = strings.ToLower(task)
+	if strings.Contains(task, "bq") || strings.Contains(task, "bigquery") {
+		return lineage.DialectBigQuery
+	}
+	return lineage.DialectMaxCompute
+}
+
+func edges(jobName string, tableEdges []lineage.Edge) []edge {
+	result := make([]edge, 0, len(tableEdges))
+	for _, e := range tableEdges {
+		result = append(result, edge{Job: jobName, Source: e.Source, Destination: e.Destination})
+	}
+	return result
+}
+
+func printLineage(l *lineage.Lineage) error {
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Type", "Table"})
+	for _, s := range l.Sources {
+		printer.AddField("source")
+		printer.AddField(s)
+		printer.EndRow()
+	}
+	for _, d := range l.Destinations {
+		printer.AddField("destination")
+		printer.AddField(d)
+		printer.EndRow()
+	}
+	err := printer.Render()
+	if err != nil {
+		return err
+	}
+
+	if len(l.Columns) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	colPrinter := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	colPrinter.AddHeader([]string{"Column", "Sources"})
+	for _, c := range l.Columns {
+		colPrinter.AddField(c.Target)
+		colPrinter.AddField(strings.Join(c.Sources, ", "))
+		colPrinter.EndRow()
+	}
+	return colPrinter.Render()
+}
+
+func printJSON(v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(content))
+	return nil
+}
+
+// printDot writes the graph in graphviz format, tables are the nodes
+func printDot(w io.Writer, all []edge) error {
+	fmt.Fprintln(w, "digraph lineage {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, e := range all {
+		label := ""
+		if e.Job != "" {
+			label = fmt.Sprintf(" [label=%q]", e.Job)
+		}
+		_, err := fmt.Fprintf(w, "  %q -> %q%s;\n", e.Source, e.Destination, label)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
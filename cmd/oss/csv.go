@@ -32,6 +32,7 @@ type readCommand struct {
 
 	tableName string
 	output    string
+	format    string
 }
 
 func NewReadCSVCommand(cfg *config.Config) *cobra.Command {
@@ -48,6 +49,7 @@ func NewReadCSVCommand(cfg *config.Config) *cobra.Command {
 	cmd.Flags().StringVarP(&read.tableName, "table", "t", "", "Table name")
 	cmd.Flags().StringVarP(&read.bucket, "bucket", "b", "", "Bucket name")
 	cmd.Flags().StringVarP(&read.output, "output", "o", "", "Write CSV before printing")
+	cmd.Flags().StringVar(&read.format, "format", table.FormatTable, "Output format: table, csv, json, jsonl")
 	return cmd
 }
 
@@ -63,7 +65,10 @@ func (r *readCommand) RunE(_ *cobra.Command, _ []string) error {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
 	defer cancelFunc()
 
-	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
 
 	if r.name == "" && r.tableName == "" {
 		return errors.New("either name or table name is required")
@@ -127,10 +132,17 @@ func (r *readCommand) readFileFromBucket(ctx context.Context, client *oss.Client
 		return err
 	}
 
-	printer.AddHeader(append([]string{"row num"}, csvStr[0]...))
+	withRowNum := r.format == "" || strings.EqualFold(r.format, table.FormatTable)
+	if withRowNum {
+		printer.AddHeader(append([]string{"row num"}, csvStr[0]...))
+	} else {
+		printer.AddHeader(csvStr[0])
+	}
 
 	for i, row := range csvStr[1:] {
-		printer.AddField(strconv.Itoa(i + 1))
+		if withRowNum {
+			printer.AddField(strconv.Itoa(i + 1))
+		}
 		for _, col := range row {
 			printer.AddField(col)
 		}
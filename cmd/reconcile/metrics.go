@@ -0,0 +1,247 @@
+package reconcile
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	checkCount  = "count"
+	checkNulls  = "nulls"
+	checkMinMax = "minmax"
+	checkSum    = "sum"
+	checkHash   = "hash"
+
+	nullValue = "NULL"
+)
+
+var allChecks = []string{checkCount, checkNulls, checkMinMax, checkSum, checkHash}
+
+// column is present on both sides, bqType is the standard sql type from bigquery and mcType
+// the type of the maxcompute column
+type column struct {
+	bqName string
+	mcName string
+	bqType string
+	mcType string
+}
+
+func baseType(typ string) string {
+	base, _, _ := strings.Cut(strings.ToUpper(typ), "<")
+	base, _, _ = strings.Cut(base, "(")
+	return strings.TrimSpace(base)
+}
+
+func (c column) baseType() string {
+	return baseType(c.bqType)
+}
+
+func (c column) mcBaseType() string {
+	return baseType(c.mcType)
+}
+
+func (c column) isNumeric() bool {
+	switch c.baseType() {
+	case "INT64", "FLOAT64", "NUMERIC", "BIGNUMERIC":
+		return true
+	}
+	return false
+}
+
+func (c column) isOrderable() bool {
+	switch c.baseType() {
+	case "STRING", "DATE", "DATETIME", "TIMESTAMP":
+		return true
+	}
+	return c.isNumeric()
+}
+
+// isInstant is true when both the columns are points in time, they are compared as epoch seconds
+// as maxcompute formats them in the timezone of project and bigquery in UTC
+func (c column) isInstant() bool {
+	return c.baseType() == "TIMESTAMP" && isMCInstant(c.mcBaseType())
+}
+
+func isMCInstant(mcType string) bool {
+	return mcType == "TIMESTAMP" || mcType == "DATETIME"
+}
+
+// isHashable is true for the types which are cast to the same string in both warehouses,
+// the maxcompute column needs a type of the same family
+func (c column) isHashable() bool {
+	mcType := c.mcBaseType()
+	switch c.baseType() {
+	case "STRING":
+		return mcType == "STRING" || mcType == "VARCHAR" || mcType == "CHAR"
+	case "INT64":
+		return mcType == "BIGINT" || mcType == "INT" || mcType == "SMALLINT" || mcType == "TINYINT"
+	case "BOOL":
+		return mcType == "BOOLEAN"
+	case "DATE":
+		return mcType == "DATE"
+	}
+	return false
+}
+
+// metric is a single aggregate computed on both sides, the expressions always return a string
+type metric struct {
+	name   string
+	column string
+	bq     string
+	mc     string
+	// epoch values are seconds since epoch on both sides, shown as UTC time
+	epoch bool
+}
+
+func buildMetrics(cols []column, checks map[string]bool) []metric {
+	var metrics []metric
+	if checks[checkCount] {
+		metrics = append(metrics, metric{name: checkCount, bq: "CAST(COUNT(*) AS STRING)", mc: "CAST(COUNT(*) AS STRING)"})
+	}
+
+	var hashCols []column
+	for _, c := range cols {
+		bqCol, mcCol := "`"+c.bqName+"`", "`"+c.mcName+"`"
+
+		if checks[checkNulls] {
+			metrics = append(metrics, metric{
+				name:   checkNulls,
+				column: c.bqName,
+				bq:     fmt.Sprintf("CAST(SUM(CASE WHEN %s IS NULL THEN 1 ELSE 0 END) AS STRING)", bqCol),
+				mc:     fmt.Sprintf("CAST(SUM(CASE WHEN %s IS NULL THEN 1 ELSE 0 END) AS STRING)", mcCol),
+			})
+		}
+
+		if checks[checkMinMax] && c.isOrderable() {
+			for _, fn := range []string{"MIN", "MAX"} {
+				metrics = append(metrics, metric{
+					name:   strings.ToLower(fn),
+					column: c.bqName,
+					bq:     bqToString(c, fn+"("+bqCol+")"),
+					mc:     mcToString(c, fn+"("+mcCol+")"),
+					epoch:  c.isInstant(),
+				})
+			}
+		}
+
+		if checks[checkSum] && c.isNumeric() {
+			metrics = append(metrics, metric{
+				name:   checkSum,
+				column: c.bqName,
+				bq:     fmt.Sprintf("CAST(SUM(CAST(%s AS FLOAT64)) AS STRING)", bqCol),
+				mc:     fmt.Sprintf("CAST(SUM(CAST(%s AS DOUBLE)) AS STRING)", mcCol),
+			})
+		}
+
+		if c.isHashable() {
+			hashCols = append(hashCols, c)
+		}
+	}
+
+	if checks[checkHash] && len(hashCols) > 0 {
+		metrics = append(metrics, hashMetric(hashCols))
+	}
+	return metrics
+}
+
+// hashMetric sums the first 32 bits of md5 of every row, the sum does not depend on the order of rows
+// and does not overflow for less than 2^31 rows
+func hashMetric(cols []column) metric {
+	bqParts := make([]string, len(cols))
+	mcParts := make([]string, len(cols))
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		bqParts[i] = fmt.Sprintf("COALESCE(CAST(`%s` AS STRING), '\\\\N')", c.bqName)
+		mcParts[i] = fmt.Sprintf("COALESCE(CAST(`%s` AS STRING), '\\\\N')", c.mcName)
+		names[i] = c.bqName
+	}
+
+	bqRow := fmt.Sprintf("ARRAY_TO_STRING([%s], '|')", strings.Join(bqParts, ", "))
+	mcRow := fmt.Sprintf("CONCAT_WS('|', %s)", strings.Join(mcParts, ", "))
+
+	return metric{
+		name:   checkHash,
+		column: strings.Join(names, ","),
+		bq:     fmt.Sprintf("CAST(SUM(CAST(CONCAT('0x', SUBSTR(TO_HEX(MD5(%s)), 1, 8)) AS INT64)) AS STRING)", bqRow),
+		mc:     fmt.Sprintf("CAST(SUM(CAST(CONV(SUBSTR(MD5(%s), 1, 8), 16, 10) AS BIGINT)) AS STRING)", mcRow),
+	}
+}
+
+// bqToString and mcToString format the dates and times the same way on both sides. Timestamps
+// are compared as epoch seconds when maxcompute has a DATETIME or TIMESTAMP, else as UTC time
+// as TIMESTAMP_NTZ and STRING columns keep the time of bigquery in UTC.
+func bqToString(c column, expr string) string {
+	switch c.baseType() {
+	case "TIMESTAMP":
+		if c.isInstant() {
+			return fmt.Sprintf("CAST(UNIX_SECONDS(%s) AS STRING)", expr)
+		}
+		return fmt.Sprintf("FORMAT_TIMESTAMP('%%Y-%%m-%%d %%H:%%M:%%S', %s, 'UTC')", expr)
+	case "DATETIME":
+		return fmt.Sprintf("FORMAT_DATETIME('%%Y-%%m-%%d %%H:%%M:%%S', %s)", expr)
+	}
+	return fmt.Sprintf("CAST(%s AS STRING)", expr)
+}
+
+func mcToString(c column, expr string) string {
+	switch c.baseType() {
+	case "TIMESTAMP", "DATETIME":
+		switch {
+		case c.isInstant():
+			return fmt.Sprintf("CAST(UNIX_TIMESTAMP(%s) AS STRING)", expr)
+		case isMCInstant(c.mcBaseType()):
+			// datetime of bigquery has no timezone, it is kept as the local time of project
+			return fmt.Sprintf("TO_CHAR(CAST(%s AS DATETIME), 'yyyy-mm-dd hh:mi:ss')", expr)
+		}
+		return fmt.Sprintf("SUBSTR(CAST(%s AS STRING), 1, 19)", expr)
+	}
+	return fmt.Sprintf("CAST(%s AS STRING)", expr)
+}
+
+// formatEpoch shows the seconds since epoch as UTC time, other values are returned as is
+func formatEpoch(value string) string {
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return value
+	}
+	return time.Unix(secs, 0).UTC().Format("2006-01-02 15:04:05")
+}
+
+func buildQuery(metrics []metric, table string, where string, bq bool) string {
+	exprs := make([]string, len(metrics))
+	for i, m := range metrics {
+		expr := m.mc
+		if bq {
+			expr = m.bq
+		}
+		exprs[i] = fmt.Sprintf("%s AS m%d", expr, i)
+	}
+
+	query := "SELECT " + strings.Join(exprs, ",\n  ") + "\nFROM " + table
+	if where != "" {
+		query += "\nWHERE " + where
+	}
+	return query + ";"
+}
+
+// sameValue compares the values as numbers when possible, sums of doubles can differ in the last digits
+func sameValue(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if a == nullValue || b == nullValue {
+		return false
+	}
+
+	f1, err1 := strconv.ParseFloat(a, 64)
+	f2, err2 := strconv.ParseFloat(b, 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	diff := math.Abs(f1 - f2)
+	return diff <= 1e-9*math.Max(math.Abs(f1), math.Abs(f2)) || diff < 1e-9
+}
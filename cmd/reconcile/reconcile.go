@@ -0,0 +1,475 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/spf13/cobra"
+	"google.golang.org/api/iterator"
+
+	"github.com/sbchaos/opms/external/gcp"
+	mcc "github.com/sbchaos/opms/external/mc"
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/pool"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+)
+
+var timeout = time.Minute * 30
+
+type pairResult struct {
+	bq      names.Table
+	mc      names.Table
+	metrics []metric
+	bqVals  []string
+	mcVals  []string
+}
+
+type reconcileCommand struct {
+	cfg *config.Config
+
+	name          string
+	fileName      string
+	mappingJson   string
+	partitionDate string
+	bqPartition   string
+	mcPartition   string
+	checks        []string
+	workers       int
+	format        string
+	mismatchOnly  bool
+
+	provider   *gcp.ClientProvider
+	mcProvider *mcc.ClientProvider
+	mu         sync.Mutex
+}
+
+// NewReconcileCommand compares the data of bigquery tables with the migrated maxcompute tables
+func NewReconcileCommand(cfg *config.Config) *cobra.Command {
+	rc := &reconcileCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile data of bigquery tables with maxcompute tables",
+		Long: `Computes row count, null count per column, min and max, sum of numeric columns and
+an order independent checksum of the rows on both sides and reports the mismatches.
+The maxcompute table is found by applying the project mapping on the bigquery name.
+Timestamps and the day of --partition-date are compared in UTC on both sides.`,
+		Example: `opms reconcile -n proj.dataset.table -m proj_map.json --partition-date 2024-01-01
+opms reconcile -f tables.txt -m proj_map.json -w 4 --checks count,nulls --format csv`,
+		RunE: rc.RunE,
+	}
+
+	cmd.Flags().StringVarP(&rc.name, "name", "n", "", "Bigquery table name")
+	cmd.Flags().StringVarP(&rc.fileName, "filename", "f", "", "Filename with list of bigquery tables, - for stdin")
+	cmd.Flags().StringVarP(&rc.mappingJson, "mapping", "m", "", "Project mapping of bigquery to maxcompute names")
+	cmd.Flags().StringVarP(&rc.partitionDate, "partition-date", "d", "", "Only compare the partition for the date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&rc.bqPartition, "bq-partition", "", "Date or timestamp column of bigquery used with --partition-date, defaults to the partition field")
+	cmd.Flags().StringVar(&rc.mcPartition, "mc-partition", "", "Partition column of maxcompute used with --partition-date, defaults to the first partition column")
+	cmd.Flags().StringSliceVarP(&rc.checks, "checks", "c", allChecks, "Checks to run: "+strings.Join(allChecks, ", "))
+	cmd.Flags().IntVarP(&rc.workers, "workers", "w", 2, "Number of tables to reconcile in parallel")
+	cmd.Flags().StringVarP(&rc.format, "format", "o", table.FormatTable, "Output format: table, csv, json, jsonl")
+	cmd.Flags().BoolVar(&rc.mismatchOnly, "mismatch-only", false, "Only show the metrics which do not match")
+	return cmd
+}
+
+func (r *reconcileCommand) RunE(_ *cobra.Command, _ []string) error {
+	if r.name == "" && r.fileName == "" {
+		return errors.New("either --name or --filename is required")
+	}
+
+	checks := map[string]bool{}
+	for _, c := range r.checks {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !slices.Contains(allChecks, c) {
+			return fmt.Errorf("unknown check %s, supported: %s", c, strings.Join(allChecks, ", "))
+		}
+		checks[c] = true
+	}
+
+	if r.partitionDate != "" {
+		if _, err := time.Parse("2006-01-02", r.partitionDate); err != nil {
+			return fmt.Errorf("invalid partition date %s: %w", r.partitionDate, err)
+		}
+	}
+
+	tableNames := []string{r.name}
+	if r.fileName != "" {
+		lines, err := cmdutil.ReadLines(r.fileName, os.Stdin)
+		if err != nil {
+			return err
+		}
+		tableNames = lines
+	}
+
+	projectMapping := map[string]string{}
+	if r.mappingJson != "" {
+		err := cmdutil.ReadJsonFile(r.mappingJson, os.Stdin, &projectMapping)
+		if err != nil {
+			return err
+		}
+	}
+
+	provider, err := gcp.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+	r.provider = provider
+
+	mcProvider, err := mcc.NewClientProvider(r.cfg)
+	if err != nil {
+		return err
+	}
+	r.mcProvider = mcProvider
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	defer cancelFunc()
+
+	type pair struct {
+		bq names.Table
+		mc names.Table
+	}
+	pairs := make([]pair, 0, len(tableNames))
+	for _, name := range tableNames {
+		bqTable, err := names.FromTableName(name)
+		if err != nil {
+			return err
+		}
+		mcTable, err := names.MapName(projectMapping, name)
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, pair{bq: bqTable, mc: mcTable})
+	}
+
+	jobs := make(chan pool.Job[pairResult], 20)
+	go func() {
+		for _, p := range pairs {
+			jobs <- func() pool.JobResult[pairResult] {
+				res, err := r.reconcile(ctx, p.bq, p.mc, checks)
+				return pool.JobResult[pairResult]{Output: res, Err: err}
+			}
+		}
+		close(jobs)
+	}()
+
+	var results []pool.JobResult[pairResult]
+	for res := range pool.StartPool(r.workers, jobs) {
+		results = append(results, res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Output.bq.String() < results[j].Output.bq.String()
+	})
+
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer, err := table.NewWithFormat(os.Stdout, r.format, t.IsTerminalOutput(), size)
+	if err != nil {
+		return err
+	}
+	printer.AddHeader([]string{"BQ Table", "MC Table", "Check", "Column", "BQ", "MC", "Status"})
+
+	mismatches := 0
+	for _, res := range results {
+		out := res.Output
+		if res.Err != nil {
+			mismatches++
+			addRow(printer, out.bq.String(), out.mc.String(), "error", "", res.Err.Error(), "", "❌")
+			continue
+		}
+
+		for i, m := range out.metrics {
+			match := sameValue(out.bqVals[i], out.mcVals[i])
+			if !match {
+				mismatches++
+			} else if r.mismatchOnly {
+				continue
+			}
+
+			status := "✅"
+			if !match {
+				status = "❌"
+			}
+			addRow(printer, out.bq.String(), out.mc.String(), m.name, m.column, out.bqVals[i], out.mcVals[i], status)
+		}
+	}
+
+	err = printer.Render()
+	if err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("found %d mismatches", mismatches)
+	}
+	return nil
+}
+
+func (r *reconcileCommand) reconcile(ctx context.Context, bqTable, mcTable names.Table, checks map[string]bool) (pairResult, error) {
+	res := pairResult{bq: bqTable, mc: mcTable}
+
+	client, err := r.provider.GetClient(bqTable.Schema.ProjectID)
+	if err != nil {
+		return res, err
+	}
+
+	md, err := client.DatasetInProject(bqTable.Schema.ProjectID, bqTable.Schema.SchemaID).Table(bqTable.TableID).Metadata(ctx)
+	if err != nil {
+		return res, fmt.Errorf("failed to get metadata for %s: %w", bqTable.String(), err)
+	}
+
+	mcCols, mcPartitions, err := r.mcColumns(mcTable)
+	if err != nil {
+		return res, err
+	}
+
+	var cols []column
+	for _, f := range md.Schema {
+		if mc, ok := mcCols[strings.ToLower(f.Name)]; ok {
+			cols = append(cols, column{bqName: f.Name, mcName: mc.name, bqType: gcp.FieldType(f), mcType: mc.typ})
+		}
+	}
+
+	bqWhere, mcWhere, err := r.filters(md, mcCols, mcPartitions)
+	if err != nil {
+		return res, err
+	}
+
+	res.metrics = buildMetrics(cols, checks)
+	if len(res.metrics) == 0 {
+		return res, errors.New("no metrics to compare, check the columns and --checks")
+	}
+
+	bqQuery := buildQuery(res.metrics, "`"+bqTable.String()+"`", bqWhere, true)
+	mcQuery := buildQuery(res.metrics, mcTable.String(), mcWhere, false)
+
+	mcClient, err := r.mcProvider.GetSQLClient(mcTable.Schema.ProjectID)
+	if err != nil {
+		return res, err
+	}
+
+	var wg sync.WaitGroup
+	var bqErr, mcErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		res.bqVals, bqErr = runBQ(ctx, client, bqQuery)
+	}()
+	go func() {
+		defer wg.Done()
+		res.mcVals, mcErr = runMC(mcClient, mcQuery)
+	}()
+	wg.Wait()
+
+	if bqErr != nil {
+		return res, fmt.Errorf("bigquery query failed: %w", bqErr)
+	}
+	if mcErr != nil {
+		return res, fmt.Errorf("maxcompute query failed: %w", mcErr)
+	}
+	if len(res.bqVals) != len(res.metrics) || len(res.mcVals) != len(res.metrics) {
+		return res, errors.New("unexpected number of values in query result")
+	}
+
+	for i, m := range res.metrics {
+		if m.epoch {
+			res.bqVals[i] = formatEpoch(res.bqVals[i])
+			res.mcVals[i] = formatEpoch(res.mcVals[i])
+		}
+	}
+	return res, nil
+}
+
+type mcColumn struct {
+	name string
+	typ  string
+}
+
+// mcColumns loads the maxcompute table with the odps client, it returns the columns by lower case name
+func (r *reconcileCommand) mcColumns(tab names.Table) (map[string]mcColumn, []string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client, err := r.mcProvider.GetClient(tab.Schema.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client.SetCurrentSchemaName(tab.Schema.SchemaID)
+
+	tabl := client.Table(tab.TableID)
+	err = tabl.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load table %s: %w", tab.String(), err)
+	}
+
+	cols := map[string]mcColumn{}
+	for _, c := range tabl.Schema().Columns {
+		cols[strings.ToLower(c.Name)] = mcColumn{name: c.Name, typ: c.Type.String()}
+	}
+
+	var partitions []string
+	for _, c := range tabl.PartitionColumns() {
+		cols[strings.ToLower(c.Name)] = mcColumn{name: c.Name, typ: c.Type.String()}
+		partitions = append(partitions, c.Name)
+	}
+	return cols, partitions, nil
+}
+
+// filters selects the day of --partition-date in UTC on both sides, a column with a point in
+// time in maxcompute is filtered on epoch seconds as it is shown in the timezone of project
+func (r *reconcileCommand) filters(md *bigquery.TableMetadata, mcCols map[string]mcColumn, mcPartitions []string) (string, string, error) {
+	if r.partitionDate == "" {
+		return "", "", nil
+	}
+
+	bqCol := r.bqPartition
+	if bqCol == "" {
+		fields := gcp.PartitionFields(md)
+		if len(fields) == 0 {
+			return "", "", errors.New("bigquery table is not partitioned, provide --bq-partition")
+		}
+		bqCol = fields[0]
+	}
+
+	mcCol := r.mcPartition
+	if mcCol == "" {
+		if len(mcPartitions) == 0 {
+			return "", "", errors.New("maxcompute table is not partitioned, provide --mc-partition")
+		}
+		mcCol = mcPartitions[0]
+	}
+	mc, ok := mcCols[strings.ToLower(mcCol)]
+	if !ok {
+		return "", "", fmt.Errorf("column %s not found in maxcompute table", mcCol)
+	}
+
+	bqType := bqColumnType(md, bqCol)
+	bqWhere := fmt.Sprintf("DATE(%s) = '%s'", bqCol, r.partitionDate)
+	if bqType == "TIMESTAMP" {
+		bqWhere = fmt.Sprintf("DATE(%s, 'UTC') = '%s'", bqCol, r.partitionDate)
+	}
+
+	var mcWhere string
+	switch mcType := baseType(mc.typ); {
+	case isMCInstant(mcType) && bqType == "TIMESTAMP":
+		day, _ := time.Parse("2006-01-02", r.partitionDate)
+		mcWhere = fmt.Sprintf("UNIX_TIMESTAMP(`%s`) >= %d AND UNIX_TIMESTAMP(`%s`) < %d",
+			mc.name, day.Unix(), mc.name, day.AddDate(0, 0, 1).Unix())
+	case isMCInstant(mcType):
+		mcWhere = fmt.Sprintf("TO_CHAR(CAST(`%s` AS DATETIME), 'yyyy-mm-dd') = '%s'", mc.name, r.partitionDate)
+	case mcType == "DATE":
+		mcWhere = fmt.Sprintf("`%s` = CAST('%s' AS DATE)", mc.name, r.partitionDate)
+	case mcType == "TIMESTAMP_NTZ":
+		mcWhere = fmt.Sprintf("SUBSTR(CAST(`%s` AS STRING), 1, 10) = '%s'", mc.name, r.partitionDate)
+	default:
+		mcWhere = fmt.Sprintf("`%s` = '%s'", mc.name, r.partitionDate)
+	}
+	return bqWhere, mcWhere, nil
+}
+
+// bqColumnType returns the base type of the column, including the pseudo columns of partitions
+func bqColumnType(md *bigquery.TableMetadata, name string) string {
+	switch strings.ToUpper(name) {
+	case "_PARTITIONTIME":
+		return "TIMESTAMP"
+	case "_PARTITIONDATE":
+		return "DATE"
+	}
+	for _, f := range md.Schema {
+		if strings.EqualFold(f.Name, name) {
+			return baseType(gcp.FieldType(f))
+		}
+	}
+	return ""
+}
+
+func runBQ(ctx context.Context, client *bigquery.Client, query string) ([]string, error) {
+	it, err := client.Query(query).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var row []bigquery.Value
+	err = it.Next(&row)
+	if errors.Is(err, iterator.Done) {
+		return nil, errors.New("no rows returned")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(row))
+	for i, v := range row {
+		if v == nil {
+			values[i] = nullValue
+			continue
+		}
+		values[i] = fmt.Sprintf("%v", v)
+	}
+	return values, nil
+}
+
+// runMC scans the values without the typed scanners, all the metrics are cast to strings
+func runMC(client *sql.DB, query string) ([]string, error) {
+	rows, err := client.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return nil, rows.Err()
+		}
+		return nil, errors.New("no rows returned")
+	}
+
+	record := make([]any, len(cols))
+	pointers := make([]any, len(cols))
+	for i := range record {
+		pointers[i] = &record[i]
+	}
+
+	err = rows.Scan(pointers...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(record))
+	for i, v := range record {
+		switch val := v.(type) {
+		case nil:
+			values[i] = nullValue
+		case []byte:
+			values[i] = string(val)
+		default:
+			values[i] = fmt.Sprintf("%v", val)
+		}
+	}
+	return values, nil
+}
+
+func addRow(printer table.Printer, fields ...string) {
+	for _, f := range fields {
+		printer.AddField(f)
+	}
+	printer.EndRow()
+}
@@ -0,0 +1,248 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/cmdutil"
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/printers/table"
+	"github.com/sbchaos/opms/lib/term"
+	"github.com/sbchaos/opms/lib/translate"
+)
+
+const (
+	dialectBQ = "bq"
+	dialectMC = "mc"
+)
+
+type ddlCommand struct {
+	cfg *config.Config
+
+	from            string
+	to              string
+	fileName        string
+	dir             string
+	outputDir       string
+	typeMapJson     string
+	projMapJson     string
+	lifecycle       int
+	partitionColumn string
+}
+
+// translated is the maxcompute ddl of a table from the source file
+type translated struct {
+	source string
+	table  string
+	target string
+	ddl    string
+	err    error
+}
+
+// NewDDLCommand translates the ddl of bigquery tables to maxcompute
+func NewDDLCommand(cfg *config.Config) *cobra.Command {
+	dc := &ddlCommand{cfg: cfg}
+
+	cmd := &cobra.Command{
+		Use:   "ddl",
+		Short: "Translate DDL of bigquery tables and views to maxcompute",
+		Long: `Translate the CREATE TABLE and CREATE VIEW statements of bigquery to maxcompute.
+Types are mapped with the type mapping json used by generate external-table, over the default
+mapping of types, and names are mapped with the project mapping. The partition column of a table
+is moved to PARTITIONED BY as a date string, tables partitioned by ingestion time get --partition-column.
+Lifecycle is taken from partition_expiration_days of the table when --lifecycle is not set.
+With --dir all the .sql files are translated, like the output of opms bq tables fetch-ddl, and
+with --output-dir the DDL is written to <output-dir>/<project>/<schema>/<table>.sql.`,
+		Example: `opms translate ddl --from bq --to mc -f table.sql -t type_map.json -p proj_map.json
+opms translate ddl --from bq --to mc -d ddl/ -p proj_map.json -o mc_ddl --lifecycle 365`,
+		RunE: dc.RunE,
+	}
+
+	cmd.Flags().StringVar(&dc.from, "from", dialectBQ, "Dialect of the DDL: bq")
+	cmd.Flags().StringVar(&dc.to, "to", dialectMC, "Dialect to translate to: mc")
+	cmd.Flags().StringVarP(&dc.fileName, "file", "f", "", "File with DDL, - for stdin")
+	cmd.Flags().StringVarP(&dc.dir, "dir", "d", "", "Directory with .sql files of DDL")
+	cmd.Flags().StringVarP(&dc.outputDir, "output-dir", "o", "", "Output directory, prints to stdout when not set")
+	cmd.Flags().StringVarP(&dc.typeMapJson, "type-map", "t", "", "Mapping json of BQ to maxcompute type")
+	cmd.Flags().StringVarP(&dc.projMapJson, "proj-map", "p", "", "Mapping json of BQ to maxcompute projects")
+	cmd.Flags().IntVar(&dc.lifecycle, "lifecycle", 0, "Lifecycle of tables in days")
+	cmd.Flags().StringVar(&dc.partitionColumn, "partition-column", "dt", "Partition column for tables partitioned by ingestion time")
+	return cmd
+}
+
+func (r *ddlCommand) RunE(_ *cobra.Command, _ []string) error {
+	if !strings.EqualFold(r.from, dialectBQ) || !strings.EqualFold(r.to, dialectMC) {
+		return fmt.Errorf("translation from %s to %s is not supported, only bq to mc", r.from, r.to)
+	}
+	if (r.fileName == "") == (r.dir == "") {
+		return errors.New("one of --file or --dir is required")
+	}
+
+	opts, err := r.options()
+	if err != nil {
+		return err
+	}
+
+	files := []string{r.fileName}
+	if r.dir != "" {
+		files, err = sqlFiles(r.dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	var results []translated
+	for _, file := range files {
+		results = append(results, translateFile(file, opts)...)
+	}
+
+	if r.outputDir == "" {
+		return printDDL(results)
+	}
+	return r.writeDDL(results)
+}
+
+func (r *ddlCommand) options() (translate.Options, error) {
+	opts := translate.Options{
+		Lifecycle:       r.lifecycle,
+		PartitionColumn: r.partitionColumn,
+	}
+
+	typeMapping := map[string]string{}
+	if r.typeMapJson != "" {
+		err := cmdutil.ReadJsonFile(r.typeMapJson, os.Stdin, &typeMapping)
+		if err != nil {
+			return opts, err
+		}
+	}
+	opts.TypeMapping = translate.WithDefaultTypes(typeMapping)
+
+	if r.projMapJson != "" {
+		projectMapping := map[string]string{}
+		err := cmdutil.ReadJsonFile(r.projMapJson, os.Stdin, &projectMapping)
+		if err != nil {
+			return opts, err
+		}
+		opts.ProjectMapping = projectMapping
+	}
+	return opts, nil
+}
+
+func translateFile(file string, opts translate.Options) []translated {
+	content, err := cmdutil.ReadFile(file, os.Stdin)
+	if err != nil {
+		return []translated{{source: file, err: err}}
+	}
+
+	tables, err := translate.ParseBigQuery(string(content))
+	if err != nil {
+		return []translated{{source: file, err: fmt.Errorf("failed to parse: %w", err)}}
+	}
+
+	results := make([]translated, 0, len(tables))
+	for _, t := range tables {
+		res := translated{source: file, table: t.Name}
+		res.ddl, res.err = translate.ToMaxCompute(t, opts)
+		if res.err == nil {
+			mapped, err := names.MapName(opts.ProjectMapping, t.Name)
+			if err != nil {
+				res.err = err
+			} else {
+				res.target = mapped.String()
+			}
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func printDDL(results []translated) error {
+	failed, printed := 0, 0
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Skipping %s %s, err: %s\n", res.source, res.table, res.err)
+			continue
+		}
+		if printed > 0 {
+			fmt.Println()
+		}
+		fmt.Print(res.ddl)
+		printed++
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to translate %d statements", failed)
+	}
+	return nil
+}
+
+// writeDDL writes the ddl to <output-dir>/<project>/<schema>/<table>.sql of the maxcompute name
+func (r *ddlCommand) writeDDL(results []translated) error {
+	t := term.FromEnv(0, 0)
+	size, _ := t.Size(120)
+
+	printer := table.New(os.Stdout, t.IsTerminalOutput(), size)
+	printer.AddHeader([]string{"Source", "Table", "Target", "Error"})
+
+	failed := 0
+	for _, res := range results {
+		if res.err == nil {
+			tab, _ := names.FromTableName(res.target)
+			path := filepath.Join(r.outputDir, tab.Schema.ProjectID, tab.Schema.SchemaID, tab.TableID+".sql")
+			err := cmdutil.WriteFileAndDir(path, []byte(res.ddl))
+			if err != nil {
+				res.err = fmt.Errorf("failure in write file: %w", err)
+			}
+		}
+
+		printer.AddField(res.source)
+		printer.AddField(res.table)
+		printer.AddField(res.target)
+		if res.err != nil {
+			failed++
+			printer.AddField(res.err.Error())
+		} else {
+			printer.AddField("")
+		}
+		printer.EndRow()
+	}
+
+	err := printer.Render()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nTranslated: %d, Failed: %d\n", len(results)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("failed to translate %d statements", failed)
+	}
+	return nil
+}
+
+// sqlFiles returns the .sql files under the directory in sorted order
+func sqlFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".sql") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
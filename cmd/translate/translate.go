@@ -0,0 +1,21 @@
+package translate
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sbchaos/opms/lib/config"
+)
+
+// NewTranslateCommand initializes commands for translating between bigquery and maxcompute
+func NewTranslateCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "translate",
+		Short:   "Commands to translate definitions between bigquery and maxcompute",
+		Example: "opms translate [sub-command]",
+	}
+
+	cmd.AddCommand(
+		NewDDLCommand(cfg),
+	)
+	return cmd
+}
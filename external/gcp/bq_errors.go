@@ -0,0 +1,70 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	ErrNotFound      = "not found"
+	ErrAccessDenied  = "access denied"
+	ErrQuotaExceeded = "quota exceeded"
+	ErrInvalidQuery  = "invalid query"
+	ErrTimeout       = "timeout"
+	ErrBackend       = "backend error"
+	ErrFailed        = "failed"
+)
+
+// ClassifyError returns the category of the error returned by bigquery api or a job
+func ClassifyError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	var reasons []string
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, item := range apiErr.Errors {
+			reasons = append(reasons, item.Reason)
+		}
+	}
+	var jobErr *bigquery.Error
+	if errors.As(err, &jobErr) {
+		reasons = append(reasons, jobErr.Reason)
+	}
+
+	for _, reason := range reasons {
+		switch reason {
+		case "notFound":
+			return ErrNotFound
+		case "accessDenied":
+			return ErrAccessDenied
+		case "quotaExceeded", "rateLimitExceeded":
+			return ErrQuotaExceeded
+		case "invalidQuery", "invalid":
+			return ErrInvalidQuery
+		case "timeout":
+			return ErrTimeout
+		case "backendError", "jobBackendError", "internalError":
+			return ErrBackend
+		}
+	}
+
+	if apiErr != nil {
+		switch apiErr.Code {
+		case http.StatusNotFound:
+			return ErrNotFound
+		case http.StatusForbidden, http.StatusUnauthorized:
+			return ErrAccessDenied
+		case http.StatusTooManyRequests:
+			return ErrQuotaExceeded
+		case http.StatusBadRequest:
+			return ErrInvalidQuery
+		}
+	}
+	return ErrFailed
+}
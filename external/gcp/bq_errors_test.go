@@ -0,0 +1,36 @@
+package gcp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+
+	"github.com/sbchaos/opms/external/gcp"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{name: "not found reason", err: &googleapi.Error{Code: http.StatusNotFound, Errors: []googleapi.ErrorItem{{Reason: "notFound"}}}, expected: gcp.ErrNotFound},
+		{name: "access denied code", err: &googleapi.Error{Code: http.StatusForbidden}, expected: gcp.ErrAccessDenied},
+		{name: "quota reason over code", err: &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}}, expected: gcp.ErrQuotaExceeded},
+		{name: "wrapped job error", err: fmt.Errorf("read: %w", &bigquery.Error{Reason: "invalidQuery"}), expected: gcp.ErrInvalidQuery},
+		{name: "backend job error", err: &bigquery.Error{Reason: "jobBackendError"}, expected: gcp.ErrBackend},
+		{name: "deadline", err: fmt.Errorf("read: %w", context.DeadlineExceeded), expected: gcp.ErrTimeout},
+		{name: "other", err: errors.New("boom"), expected: gcp.ErrFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, gcp.ClassifyError(tt.err))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+)
+
+// ListDatasets returns the sorted names of datasets in the project which start with prefix
+func ListDatasets(ctx context.Context, client *bigquery.Client, project, prefix string) ([]string, error) {
+	var names []string
+	it := client.DatasetsInProject(ctx, project)
+	for {
+		ds, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return names, err
+		}
+		if strings.HasPrefix(ds.DatasetID, prefix) {
+			names = append(names, ds.DatasetID)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListTables returns the sorted names of tables in the dataset which start with prefix
+func ListTables(ctx context.Context, client *bigquery.Client, project, dataset, prefix string) ([]string, error) {
+	var names []string
+	it := client.DatasetInProject(project, dataset).Tables(ctx)
+	for {
+		t, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return names, err
+		}
+		if strings.HasPrefix(t.TableID, prefix) {
+			names = append(names, t.TableID)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
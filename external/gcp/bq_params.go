@@ -0,0 +1,62 @@
+package gcp
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+// ParseQueryParameter parses a named parameter as name=value or name:TYPE=value, the
+// value is a STRING when type is not given, eg dt:DATE=2024-01-01 or limit:INT64=10
+func ParseQueryParameter(s string) (bigquery.QueryParameter, error) {
+	key, value, found := strings.Cut(s, "=")
+	if !found {
+		return bigquery.QueryParameter{}, fmt.Errorf("invalid parameter %s, expected name=value or name:TYPE=value", s)
+	}
+
+	name, typ, _ := strings.Cut(key, ":")
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if name == "" {
+		return bigquery.QueryParameter{}, fmt.Errorf("invalid parameter %s, name is empty", s)
+	}
+
+	v, err := parseParamValue(strings.ToUpper(strings.TrimSpace(typ)), value)
+	if err != nil {
+		return bigquery.QueryParameter{}, fmt.Errorf("invalid value for parameter %s: %w", name, err)
+	}
+	return bigquery.QueryParameter{Name: name, Value: v}, nil
+}
+
+func parseParamValue(typ, value string) (any, error) {
+	switch typ {
+	case "", "STRING":
+		return value, nil
+	case "INT64", "INTEGER", "INT":
+		return strconv.ParseInt(value, 10, 64)
+	case "FLOAT64", "FLOAT":
+		return strconv.ParseFloat(value, 64)
+	case "BOOL", "BOOLEAN":
+		return strconv.ParseBool(value)
+	case "NUMERIC":
+		r, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a numeric", value)
+		}
+		return r, nil
+	case "DATE":
+		return civil.ParseDate(value)
+	case "DATETIME":
+		return civil.ParseDateTime(strings.Replace(value, " ", "T", 1))
+	case "TIMESTAMP":
+		return time.Parse(time.RFC3339Nano, value)
+	case "BYTES":
+		return []byte(value), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s, use STRING, INT64, FLOAT64, BOOL, NUMERIC, DATE, DATETIME, TIMESTAMP or BYTES", typ)
+	}
+}
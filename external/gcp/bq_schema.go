@@ -0,0 +1,81 @@
+package gcp
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+var legacyTypes = map[bigquery.FieldType]string{
+	bigquery.IntegerFieldType: "INT64",
+	bigquery.FloatFieldType:   "FLOAT64",
+	bigquery.BooleanFieldType: "BOOL",
+	bigquery.RecordFieldType:  "STRUCT",
+}
+
+// FieldType returns the standard sql type of the field, eg ARRAY<STRUCT<a:INT64,b:STRING>>
+func FieldType(f *bigquery.FieldSchema) string {
+	typ, ok := legacyTypes[f.Type]
+	if !ok {
+		typ = string(f.Type)
+	}
+
+	switch {
+	case f.Type == bigquery.RecordFieldType:
+		fields := make([]string, len(f.Schema))
+		for i, nested := range f.Schema {
+			fields[i] = nested.Name + ":" + FieldType(nested)
+		}
+		typ = "STRUCT<" + strings.Join(fields, ",") + ">"
+	case f.Precision > 0 && f.Scale > 0:
+		typ = fmt.Sprintf("%s(%d,%d)", typ, f.Precision, f.Scale)
+	case f.Precision > 0:
+		typ = fmt.Sprintf("%s(%d)", typ, f.Precision)
+	case f.MaxLength > 0:
+		typ = fmt.Sprintf("%s(%d)", typ, f.MaxLength)
+	}
+
+	if f.Repeated {
+		return "ARRAY<" + typ + ">"
+	}
+	return typ
+}
+
+// PartitionFields returns the field used for partitioning the table, ingestion time
+// partitioned tables return the pseudo column _PARTITIONTIME
+func PartitionFields(md *bigquery.TableMetadata) []string {
+	if md.TimePartitioning != nil {
+		if md.TimePartitioning.Field == "" {
+			return []string{"_PARTITIONTIME"}
+		}
+		return []string{md.TimePartitioning.Field}
+	}
+	if md.RangePartitioning != nil {
+		return []string{md.RangePartitioning.Field}
+	}
+	return nil
+}
+
+// Partitioning describes the partitioning of the table, eg DAY(event_date) or RANGE(id)
+func Partitioning(md *bigquery.TableMetadata) string {
+	if md.TimePartitioning != nil {
+		field := md.TimePartitioning.Field
+		if field == "" {
+			field = "_PARTITIONTIME"
+		}
+		return fmt.Sprintf("%s(%s)", md.TimePartitioning.Type, field)
+	}
+	if md.RangePartitioning != nil {
+		return fmt.Sprintf("RANGE(%s)", md.RangePartitioning.Field)
+	}
+	return ""
+}
+
+// Clustering returns the comma separated clustering fields of the table
+func Clustering(md *bigquery.TableMetadata) string {
+	if md.Clustering == nil {
+		return ""
+	}
+	return strings.Join(md.Clustering.Fields, ",")
+}
@@ -0,0 +1,30 @@
+package gcp_test
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/external/gcp"
+)
+
+func TestPartitioning(t *testing.T) {
+	assert.Equal(t, "DAY(event_date)", gcp.Partitioning(&bigquery.TableMetadata{
+		TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType, Field: "event_date"},
+	}))
+	assert.Equal(t, "HOUR(_PARTITIONTIME)", gcp.Partitioning(&bigquery.TableMetadata{
+		TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.HourPartitioningType},
+	}))
+	assert.Equal(t, "RANGE(id)", gcp.Partitioning(&bigquery.TableMetadata{
+		RangePartitioning: &bigquery.RangePartitioning{Field: "id"},
+	}))
+	assert.Empty(t, gcp.Partitioning(&bigquery.TableMetadata{}))
+}
+
+func TestClustering(t *testing.T) {
+	assert.Equal(t, "country,city", gcp.Clustering(&bigquery.TableMetadata{
+		Clustering: &bigquery.Clustering{Fields: []string{"country", "city"}},
+	}))
+	assert.Empty(t, gcp.Clustering(&bigquery.TableMetadata{}))
+}
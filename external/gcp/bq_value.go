@@ -0,0 +1,143 @@
+package gcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+const NullString = "NULL"
+
+// ValueString formats a value read from bigquery for display, RECORD and REPEATED
+// fields are written as json with the names of the nested fields
+func ValueString(v bigquery.Value, f *bigquery.FieldSchema) string {
+	if v == nil {
+		return NullString
+	}
+
+	switch val := JSONValue(v, f).(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case json.RawMessage:
+		return string(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []any, map[string]any:
+		content, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(content)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// JSONValue converts a value read from bigquery to a json compatible value. Records are
+// returned as maps keyed by the field names, numerics as json.Number to keep the precision.
+func JSONValue(v bigquery.Value, f *bigquery.FieldSchema) any {
+	if v == nil {
+		return nil
+	}
+
+	if f != nil && f.Repeated {
+		values, ok := v.([]bigquery.Value)
+		if ok {
+			item := *f
+			item.Repeated = false
+
+			list := make([]any, len(values))
+			for i, value := range values {
+				list[i] = JSONValue(value, &item)
+			}
+			return list
+		}
+	}
+
+	switch val := v.(type) {
+	case []bigquery.Value:
+		return recordValue(val, f)
+	case map[string]bigquery.Value:
+		m := make(map[string]any, len(val))
+		for k, value := range val {
+			m[k] = JSONValue(value, nestedField(f, k))
+		}
+		return m
+	case bool, int64, float64:
+		return val
+	case string:
+		if f != nil && f.Type == bigquery.JSONFieldType && json.Valid([]byte(val)) {
+			return json.RawMessage(val)
+		}
+		return val
+	case []byte:
+		return base64.StdEncoding.EncodeToString(val)
+	case *big.Rat:
+		if f != nil && f.Type == bigquery.BigNumericFieldType {
+			return json.Number(trimZeros(bigquery.BigNumericString(val)))
+		}
+		return json.Number(trimZeros(bigquery.NumericString(val)))
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano)
+	case civil.Date:
+		return val.String()
+	case civil.Time:
+		return bigquery.CivilTimeString(val)
+	case civil.DateTime:
+		return bigquery.CivilDateTimeString(val)
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// recordValue maps the values of a RECORD to the names of the nested fields of schema
+func recordValue(values []bigquery.Value, f *bigquery.FieldSchema) any {
+	if f == nil || len(f.Schema) != len(values) {
+		list := make([]any, len(values))
+		for i, value := range values {
+			list[i] = JSONValue(value, nil)
+		}
+		return list
+	}
+
+	m := make(map[string]any, len(values))
+	for i, value := range values {
+		m[f.Schema[i].Name] = JSONValue(value, f.Schema[i])
+	}
+	return m
+}
+
+func nestedField(f *bigquery.FieldSchema, name string) *bigquery.FieldSchema {
+	if f == nil {
+		return nil
+	}
+	for _, nested := range f.Schema {
+		if nested.Name == name {
+			return nested
+		}
+	}
+	return nil
+}
+
+// trimZeros removes the trailing zeros of the fixed scale used for numerics, 1.500000000 is 1.5
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,113 @@
+package gcp_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/external/gcp"
+)
+
+func TestValueString(t *testing.T) {
+	address := &bigquery.FieldSchema{
+		Name: "address",
+		Type: bigquery.RecordFieldType,
+		Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "zip", Type: bigquery.IntegerFieldType},
+		},
+	}
+	tags := &bigquery.FieldSchema{Name: "tags", Type: bigquery.StringFieldType, Repeated: true}
+	items := &bigquery.FieldSchema{
+		Name:     "items",
+		Type:     bigquery.RecordFieldType,
+		Repeated: true,
+		Schema: bigquery.Schema{
+			{Name: "id", Type: bigquery.IntegerFieldType},
+			{Name: "price", Type: bigquery.NumericFieldType},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		value    bigquery.Value
+		field    *bigquery.FieldSchema
+		expected string
+	}{
+		{name: "null", value: nil, field: tags, expected: "NULL"},
+		{name: "string", value: "abc", field: &bigquery.FieldSchema{Type: bigquery.StringFieldType}, expected: "abc"},
+		{name: "float", value: 0.1, field: &bigquery.FieldSchema{Type: bigquery.FloatFieldType}, expected: "0.1"},
+		{name: "numeric", value: big.NewRat(3, 2), field: &bigquery.FieldSchema{Type: bigquery.NumericFieldType}, expected: "1.5"},
+		{name: "timestamp", value: time.Date(2024, 1, 2, 10, 0, 0, 0, time.FixedZone("WIB", 7*3600)), field: nil, expected: "2024-01-02T03:00:00Z"},
+		{name: "date", value: civil.Date{Year: 2024, Month: 1, Day: 2}, field: nil, expected: "2024-01-02"},
+		{name: "bytes", value: []byte("hi"), field: nil, expected: "aGk="},
+		{name: "record", value: []bigquery.Value{"Jakarta", int64(10110)}, field: address, expected: `{"city":"Jakarta","zip":10110}`},
+		{name: "repeated", value: []bigquery.Value{"a", "b"}, field: tags, expected: `["a","b"]`},
+		{
+			name:     "repeated record",
+			value:    []bigquery.Value{[]bigquery.Value{int64(1), big.NewRat(25, 10)}, []bigquery.Value{int64(2), nil}},
+			field:    items,
+			expected: `[{"id":1,"price":2.5},{"id":2,"price":null}]`,
+		},
+		{name: "json", value: `{"a": 1}`, field: &bigquery.FieldSchema{Type: bigquery.JSONFieldType}, expected: `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, gcp.ValueString(tt.value, tt.field))
+		})
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	field := &bigquery.FieldSchema{
+		Name: "r",
+		Type: bigquery.RecordFieldType,
+		Schema: bigquery.Schema{
+			{Name: "n", Type: bigquery.BigNumericFieldType},
+			{Name: "ok", Type: bigquery.BooleanFieldType},
+		},
+	}
+
+	v := gcp.JSONValue([]bigquery.Value{big.NewRat(1, 4), true}, field)
+	assert.Equal(t, map[string]any{"n": json.Number("0.25"), "ok": true}, v)
+
+	content, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"n":0.25,"ok":true}`, string(content))
+}
+
+func TestParseQueryParameter(t *testing.T) {
+	t.Run("parses string without type", func(t *testing.T) {
+		p, err := gcp.ParseQueryParameter("name=a=b")
+		assert.NoError(t, err)
+		assert.Equal(t, bigquery.QueryParameter{Name: "name", Value: "a=b"}, p)
+	})
+	t.Run("parses typed values", func(t *testing.T) {
+		p, err := gcp.ParseQueryParameter("@limit:int64=10")
+		assert.NoError(t, err)
+		assert.Equal(t, bigquery.QueryParameter{Name: "limit", Value: int64(10)}, p)
+
+		p, err = gcp.ParseQueryParameter("dt:DATE=2024-01-02")
+		assert.NoError(t, err)
+		assert.Equal(t, civil.Date{Year: 2024, Month: 1, Day: 2}, p.Value)
+
+		p, err = gcp.ParseQueryParameter("amount:NUMERIC=1.25")
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(5, 4), p.Value)
+	})
+	t.Run("returns error for invalid parameter", func(t *testing.T) {
+		_, err := gcp.ParseQueryParameter("name")
+		assert.ErrorContains(t, err, "expected name=value")
+
+		_, err = gcp.ParseQueryParameter("n:INT64=abc")
+		assert.ErrorContains(t, err, "invalid value for parameter n")
+
+		_, err = gcp.ParseQueryParameter("n:GEOGRAPHY=POINT(1 2)")
+		assert.ErrorContains(t, err, "unsupported type GEOGRAPHY")
+	})
+}
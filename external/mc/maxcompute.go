@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
+	"time"
 
 	"github.com/aliyun/aliyun-odps-go-sdk/odps"
 	"github.com/aliyun/aliyun-odps-go-sdk/odps/account"
@@ -24,6 +25,10 @@ type maxComputeCredentials struct {
 	OSSEndpoint   string `json:"oss_endpoint"`
 	Region        string `json:"region"`
 	SecurityToken string `json:"security_token"`
+
+	// Expiration of the sts token, the credential process is run to get new credentials
+	Expiration        *time.Time `json:"expiration,omitempty"`
+	CredentialProcess string     `json:"credential_process,omitempty"`
 }
 
 func NewClientFromConfig(cfg *config.Config) (*odps.Odps, error) {
@@ -52,9 +57,22 @@ func NewClient(creds string) (*odps.Odps, error) {
 		return nil, err
 	}
 
-	aliAccount := account.NewAliyunAccount(c1.AccessID, c1.AccessKey)
-	odpsIns := odps.NewOdps(aliAccount, c1.McEndpoint)
-	odpsIns.SetDefaultProjectName(c1.ProjectName)
+	if c1.needsRefresh() {
+		if err := c1.refresh(); err != nil {
+			return nil, err
+		}
+	}
+
+	return newOdps(c1), nil
+}
+
+func newOdps(c1 maxComputeCredentials) *odps.Odps {
+	var acc account.Account = account.NewAliyunAccount(c1.AccessID, c1.AccessKey)
+	if c1.SecurityToken != "" {
+		acc = account.NewStsAccount(c1.AccessID, c1.AccessKey, c1.SecurityToken)
+	}
 
-	return odpsIns, nil
+	odpsIns := odps.NewOdps(acc, c1.McEndpoint)
+	odpsIns.SetDefaultProjectName(c1.ProjectName)
+	return odpsIns
 }
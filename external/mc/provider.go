@@ -0,0 +1,223 @@
+package mc
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+
+	"github.com/sbchaos/opms/lib/config"
+	"github.com/sbchaos/opms/lib/keyring"
+)
+
+// refreshBefore is the time before expiry when the sts credentials are refreshed
+const refreshBefore = 5 * time.Minute
+
+type cachedClient struct {
+	creds maxComputeCredentials
+	sql   *sql.DB
+}
+
+// ClientProvider returns the maxcompute clients for a project, clients are cached per project.
+// With a dynamic profile the credentials of a project are looked up with the key <project>_mc
+// in the profile creds, otherwise the account of profile is used for all projects.
+type ClientProvider struct {
+	static     bool
+	staticCred string
+
+	profile *config.Profile
+	clients map[string]*cachedClient
+	mu      sync.Mutex
+}
+
+func NewClientProvider(cfg *config.Config) (*ClientProvider, error) {
+	profile := cfg.GetCurrentProfile()
+	provider := &ClientProvider{
+		profile: profile,
+		clients: make(map[string]*cachedClient),
+	}
+
+	acc := os.Getenv(MaxcomputeAccount)
+	if acc != "" {
+		provider.static = true
+		provider.staticCred = acc
+		return provider, nil
+	}
+
+	if !profile.Dynamic {
+		key := profile.MCCred
+		if key == "" {
+			return nil, errors.New("key not found for Maxcompute account")
+		}
+
+		acc, err := keyring.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if acc == "" {
+			return nil, errors.New("empty value for account")
+		}
+
+		provider.static = true
+		provider.staticCred = acc
+	}
+	return provider, nil
+}
+
+// GetClient returns a new client with the default project set to proj. Callers set the current
+// schema on the client, so it is not shared between calls, only the credentials are cached.
+func (p *ClientProvider) GetClient(proj string) (*odps.Odps, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cached, err := p.get(proj)
+	if err != nil {
+		return nil, err
+	}
+
+	client := newOdps(cached.creds)
+	if proj != "" {
+		client.SetDefaultProjectName(proj)
+	}
+	return client, nil
+}
+
+// GetSQLClient returns the sql client for the project, the client is shared and safe for concurrent use
+func (p *ClientProvider) GetSQLClient(proj string) (*sql.DB, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cached, err := p.get(proj)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached.sql == nil {
+		creds := cached.creds
+		if proj != "" {
+			creds.ProjectName = proj
+		}
+
+		db, err := newSQLDB(creds)
+		if err != nil {
+			return nil, err
+		}
+		cached.sql = db
+	}
+	return cached.sql, nil
+}
+
+// get returns the cached credentials of the project, refreshing them when expiring. It is called with the lock held.
+func (p *ClientProvider) get(proj string) (*cachedClient, error) {
+	key := proj + "_mc"
+
+	cached, ok := p.clients[key]
+	if ok && !cached.creds.expiring() {
+		return cached, nil
+	}
+	if !ok && !p.static && proj == "" {
+		return nil, errors.New("project is required to find the maxcompute account of a dynamic profile")
+	}
+
+	var creds maxComputeCredentials
+	if ok {
+		// only the sts token has expired, the new clients use the refreshed credentials
+		creds = cached.creds
+	} else {
+		raw, err := p.rawCreds(key)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(raw), &creds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read maxcompute account: %w", err)
+		}
+	}
+
+	if creds.needsRefresh() {
+		err := creds.refresh()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the old sql client is not closed as other goroutines may still be running queries with it,
+	// new calls get a client with the refreshed credentials
+	cached = &cachedClient{creds: creds}
+	p.clients[key] = cached
+	return cached, nil
+}
+
+func (p *ClientProvider) rawCreds(key string) (string, error) {
+	if p.static {
+		return p.staticCred, nil
+	}
+
+	credKey, err := p.profile.GetCred(key)
+	if err != nil {
+		return "", err
+	}
+	return keyring.Get(credKey)
+}
+
+// expiring is true when the sts token expires in less than refreshBefore, credentials
+// without an expiration never expire
+func (c maxComputeCredentials) expiring() bool {
+	if c.Expiration == nil {
+		return false
+	}
+	return time.Until(*c.Expiration) < refreshBefore
+}
+
+// needsRefresh is true when the token is expiring or the account only has a credential process
+func (c maxComputeCredentials) needsRefresh() bool {
+	return c.expiring() || (c.CredentialProcess != "" && c.AccessID == "")
+}
+
+// refresh runs the credential process, the output is a json with the same fields as the
+// account, fields not present in the output are kept from the account
+func (c *maxComputeCredentials) refresh() error {
+	if c.CredentialProcess == "" {
+		return errors.New("sts credentials have expired and no credential_process is configured")
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	stderr := bytes.Buffer{}
+	cmd := exec.Command(shell, flag, c.CredentialProcess)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("credential process failed: %w: %s", err, stderr.String())
+	}
+
+	var fresh maxComputeCredentials
+	err = json.Unmarshal(out, &fresh)
+	if err != nil {
+		return fmt.Errorf("failed to read output of credential process: %w", err)
+	}
+	if fresh.AccessID == "" || fresh.AccessKey == "" {
+		return errors.New("credential process did not return access_id and access_key")
+	}
+
+	c.AccessID = fresh.AccessID
+	c.AccessKey = fresh.AccessKey
+	c.SecurityToken = fresh.SecurityToken
+	c.Expiration = fresh.Expiration
+	if fresh.McEndpoint != "" {
+		c.McEndpoint = fresh.McEndpoint
+	}
+	return nil
+}
@@ -39,10 +39,20 @@ func NewSQLClient(creds string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	if c1.needsRefresh() {
+		if err := c1.refresh(); err != nil {
+			return nil, err
+		}
+	}
+
+	return newSQLDB(c1)
+}
+
+func newSQLDB(c1 maxComputeCredentials) (*sql.DB, error) {
 	conf := sqldriver.Config{
 		AccessId:             c1.AccessID,
 		AccessKey:            c1.AccessKey,
-		StsToken:             "",
+		StsToken:             c1.SecurityToken,
 		Endpoint:             c1.McEndpoint,
 		ProjectName:          c1.ProjectName,
 		HttpTimeout:          0,
@@ -0,0 +1,11 @@
+package mc
+
+import (
+	"github.com/aliyun/aliyun-odps-go-sdk/odps"
+	"github.com/aliyun/aliyun-odps-go-sdk/odps/tunnel"
+)
+
+// NewTunnel creates the tunnel for the project, tunnel endpoint is discovered from the project
+func NewTunnel(client *odps.Odps, projectName string) (*tunnel.Tunnel, error) {
+	return tunnel.NewTunnelFromProject(client.Project(projectName))
+}
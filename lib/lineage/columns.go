@@ -0,0 +1,537 @@
+package lineage
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sbchaos/opms/lib/sqltoken"
+)
+
+// column of a query result with the qualified source columns it is computed from
+type column struct {
+	name    string
+	sources []string
+}
+
+// relation is a table, cte or subquery in a FROM clause
+type relation struct {
+	name    string
+	alias   string
+	columns []column
+	derived bool
+	unknown bool
+}
+
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "ARRAY": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true,
+	"CASE": true, "CLUSTER": true, "CREATE": true, "CROSS": true, "CUBE": true, "CURRENT": true, "DELETE": true,
+	"DESC": true, "DISTINCT": true, "DISTRIBUTE": true, "ELSE": true, "END": true, "ESCAPE": true, "EXCEPT": true,
+	"EXISTS": true, "FALSE": true, "FETCH": true, "FOLLOWING": true, "FOR": true, "FROM": true, "FULL": true,
+	"GROUP": true, "GROUPING": true, "HAVING": true, "IF": true, "IN": true, "INNER": true, "INSERT": true,
+	"INTERSECT": true, "INTERVAL": true, "INTO": true, "IS": true, "JOIN": true, "LATERAL": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "MATCHED": true, "MERGE": true, "NATURAL": true, "NOT": true, "NULL": true,
+	"NULLS": true, "OF": true, "ON": true, "OR": true, "ORDER": true, "OUTER": true, "OVER": true,
+	"OVERWRITE": true, "PARTITION": true, "PRECEDING": true, "QUALIFY": true, "RANGE": true, "RECURSIVE": true,
+	"REGEXP": true, "RIGHT": true, "RLIKE": true, "ROLLUP": true, "ROW": true, "ROWS": true, "SELECT": true,
+	"SET": true, "SOME": true, "SORT": true, "STRUCT": true, "TABLE": true, "TABLESAMPLE": true, "THEN": true,
+	"TO": true, "TRUE": true, "UNBOUNDED": true, "UNION": true, "UNNEST": true, "UPDATE": true, "USING": true,
+	"VALUES": true, "VIEW": true, "WHEN": true, "WHERE": true, "WINDOW": true, "WITH": true,
+}
+
+// datePart are the units used as arguments of date functions, DATE_TRUNC(dt, MONTH)
+var dateParts = map[string]bool{
+	"MICROSECOND": true, "MILLISECOND": true, "SECOND": true, "MINUTE": true, "HOUR": true, "DAY": true,
+	"DAYOFWEEK": true, "DAYOFYEAR": true, "WEEK": true, "ISOWEEK": true, "MONTH": true, "QUARTER": true,
+	"YEAR": true, "ISOYEAR": true, "DATE": true, "TIME": true,
+}
+
+// clauseEnd are the keywords which end a select list or a FROM clause
+var clauseEnd = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "QUALIFY": true, "WINDOW": true, "ORDER": true,
+	"LIMIT": true, "UNION": true, "INTERSECT": true, "DISTRIBUTE": true, "SORT": true, "CLUSTER": true,
+}
+
+func isKeyword(t sqltoken.Token) bool {
+	return t.Kind == sqltoken.Word && keywords[strings.ToUpper(t.Text)]
+}
+
+// destinationColumns resolves the columns of the query after the destination of INSERT or CREATE
+func destinationColumns(tokens []sqltoken.Token, dest string, insertCols []string, dialect Dialect) []ColumnLineage {
+	start := -1
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.IsPunct("(") && depth == 0 && i+1 < len(tokens) && (tokens[i+1].Is("SELECT") || tokens[i+1].Is("WITH")):
+			start = i
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth == 0 && (t.Is("SELECT") || t.Is("WITH")):
+			start = i
+		}
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	cols := analyzeQuery(tokens[start:], map[string][]column{}, dialect)
+	lineage := make([]ColumnLineage, 0, len(cols))
+	for i, c := range cols {
+		name := c.name
+		if i < len(insertCols) {
+			name = insertCols[i]
+		}
+
+		sources := append([]string{}, c.sources...)
+		sort.Strings(sources)
+		lineage = append(lineage, ColumnLineage{Target: dest + "." + name, Sources: sources})
+	}
+	return lineage
+}
+
+// analyzeQuery returns the columns of a query with CTEs and set operations, env has the columns
+// of the CTEs visible to the query
+func analyzeQuery(tokens []sqltoken.Token, env map[string][]column, dialect Dialect) []column {
+	if len(tokens) == 0 {
+		return nil
+	}
+	if tokens[0].IsPunct("(") && sqltoken.Matching(tokens, 0) == len(tokens)-1 {
+		return analyzeQuery(tokens[1:len(tokens)-1], env, dialect)
+	}
+
+	if tokens[0].Is("WITH") {
+		ctes, end := readCTEs(tokens, 0, dialect)
+		local := make(map[string][]column, len(env)+len(ctes))
+		for k, v := range env {
+			local[k] = v
+		}
+		for _, c := range ctes {
+			cols := analyzeQuery(c.body, local, dialect)
+			for i := range cols {
+				if i < len(c.columns) {
+					cols[i].name = c.columns[i]
+				}
+			}
+			local[strings.ToLower(c.name)] = cols
+		}
+		env = local
+		tokens = tokens[end:]
+	}
+
+	var result []column
+	for i, branch := range splitSetOperations(tokens) {
+		cols := analyzeSelect(branch, env, dialect)
+		if i == 0 {
+			result = cols
+			continue
+		}
+		for j := range result {
+			if j < len(cols) {
+				result[j].sources = appendUnique(result[j].sources, cols[j].sources...)
+			}
+		}
+	}
+	return result
+}
+
+func splitSetOperations(tokens []sqltoken.Token) [][]sqltoken.Token {
+	var branches [][]sqltoken.Token
+	depth, start := 0, 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth == 0 && isSetOperation(tokens, i):
+			branches = append(branches, tokens[start:i])
+			i = skipWords(tokens, i+1, "ALL", "DISTINCT") - 1
+			start = i + 1
+		}
+	}
+	return append(branches, tokens[start:])
+}
+
+// isSetOperation is true for UNION, INTERSECT and EXCEPT, other than the EXCEPT of SELECT * EXCEPT (col)
+func isSetOperation(tokens []sqltoken.Token, i int) bool {
+	t := tokens[i]
+	if t.Is("UNION") || t.Is("INTERSECT") {
+		return true
+	}
+	return t.Is("EXCEPT") && i+1 < len(tokens) &&
+		(tokens[i+1].Is("ALL") || tokens[i+1].Is("DISTINCT") || tokens[i+1].Is("SELECT"))
+}
+
+// analyzeSelect returns the columns of a single SELECT
+func analyzeSelect(tokens []sqltoken.Token, env map[string][]column, dialect Dialect) []column {
+	if len(tokens) == 0 {
+		return nil
+	}
+	if tokens[0].IsPunct("(") {
+		end := sqltoken.Matching(tokens, 0)
+		if end > 0 {
+			return analyzeQuery(tokens[1:end], env, dialect)
+		}
+	}
+	if !tokens[0].Is("SELECT") {
+		return nil
+	}
+
+	start := skipWords(tokens, 1, "DISTINCT", "ALL")
+	if start+1 < len(tokens) && tokens[start].Is("AS") && (tokens[start+1].Is("STRUCT") || tokens[start+1].Is("VALUE")) {
+		start += 2
+	}
+
+	end := len(tokens)
+	depth := 0
+	for i := start; i < len(tokens) && end == len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth == 0 && (t.Is("FROM") || t.Kind == sqltoken.Word && clauseEnd[strings.ToUpper(t.Text)]):
+			end = i
+		}
+	}
+
+	var rels []relation
+	if end < len(tokens) && tokens[end].Is("FROM") {
+		rels = readRelations(tokens, end+1, env, dialect)
+	}
+
+	var cols []column
+	for i, item := range splitTopLevel(tokens[start:end], ",") {
+		cols = append(cols, selectItem(item, i, rels)...)
+	}
+	return cols
+}
+
+// readRelations reads the tables, ctes and subqueries joined in a FROM clause
+func readRelations(tokens []sqltoken.Token, i int, env map[string][]column, dialect Dialect) []relation {
+	var rels []relation
+	for i < len(tokens) {
+		var rel relation
+		switch {
+		case tokens[i].IsPunct("("):
+			end := sqltoken.Matching(tokens, i)
+			if end < 0 {
+				return rels
+			}
+			rel = relation{derived: true, columns: analyzeQuery(tokens[i+1:end], env, dialect)}
+			i = end + 1
+
+		case tokens[i].Kind == sqltoken.Word && i+1 < len(tokens) && tokens[i+1].IsPunct("("):
+			// table functions like UNNEST(array_column)
+			rel = relation{name: tokens[i].Text, unknown: true}
+			i = sqltoken.Matching(tokens, i+1) + 1
+			if i == 0 {
+				return rels
+			}
+
+		default:
+			name, next, ok := readName(tokens, i, dialect)
+			if !ok {
+				return rels
+			}
+			rel = relation{name: name}
+			if cols, found := env[strings.ToLower(name)]; found && !strings.Contains(name, ".") {
+				rel.derived = true
+				rel.columns = cols
+			}
+			i = next
+		}
+
+		rel.alias, i = readAlias(tokens, i)
+		if rel.alias == "" && rel.name != "" {
+			rel.alias = rel.name[strings.LastIndex(rel.name, ".")+1:]
+		}
+		rels = append(rels, rel)
+
+		// skip the join conditions up to the next relation
+		next := false
+		for i < len(tokens) && !next {
+			t := tokens[i]
+			switch {
+			case t.IsPunct("("):
+				i = sqltoken.Matching(tokens, i)
+				if i < 0 {
+					return rels
+				}
+			case t.IsPunct(",") || t.Is("JOIN"):
+				next = true
+			case t.Kind == sqltoken.Word && clauseEnd[strings.ToUpper(t.Text)]:
+				return rels
+			}
+			i++
+		}
+		if !next {
+			return rels
+		}
+	}
+	return rels
+}
+
+// selectItem returns the columns of an item of select list, * is expanded when the columns are known
+func selectItem(item []sqltoken.Token, pos int, rels []relation) []column {
+	if len(item) == 0 {
+		return nil
+	}
+
+	if star, qualifier, except := starItem(item); star {
+		var cols []column
+		for _, rel := range rels {
+			if qualifier != "" && !rel.matches(qualifier) {
+				continue
+			}
+			switch {
+			case rel.unknown:
+			case rel.derived:
+				for _, c := range rel.columns {
+					if !except[strings.ToLower(c.name)] {
+						cols = append(cols, column{name: c.name, sources: append([]string{}, c.sources...)})
+					}
+				}
+			default:
+				cols = append(cols, column{name: "*", sources: []string{rel.name + ".*"}})
+			}
+		}
+		return cols
+	}
+
+	expr, alias := splitAlias(item)
+	name := alias
+	if name == "" {
+		name = "_c" + strconv.Itoa(pos)
+		if parts, ok := singleReference(expr); ok {
+			name = parts[len(parts)-1]
+		}
+	}
+
+	c := column{name: name, sources: []string{}}
+	for _, ref := range references(expr) {
+		c.sources = appendUnique(c.sources, resolve(ref, rels)...)
+	}
+	return []column{c}
+}
+
+// starItem checks for *, t.* and * EXCEPT (cols)
+func starItem(item []sqltoken.Token) (bool, string, map[string]bool) {
+	idx := -1
+	for i, t := range item {
+		if t.IsPunct("*") {
+			idx = i
+			break
+		}
+		if !t.IsNamePart() && !t.IsPunct(".") {
+			return false, "", nil
+		}
+	}
+	if idx < 0 || (idx > 0 && !item[idx-1].IsPunct(".")) {
+		return false, "", nil
+	}
+
+	var parts []string
+	for _, t := range item[:idx] {
+		if t.IsNamePart() {
+			parts = append(parts, t.Text)
+		}
+	}
+
+	except := map[string]bool{}
+	rest := item[idx+1:]
+	if len(rest) > 0 && rest[0].Is("EXCEPT") {
+		for _, name := range identifiers(rest[1:]) {
+			except[strings.ToLower(name)] = true
+		}
+	}
+	return true, strings.Join(parts, "."), except
+}
+
+// splitAlias returns the expression and the alias of an item of select list
+func splitAlias(item []sqltoken.Token) ([]sqltoken.Token, string) {
+	n := len(item)
+	if n >= 2 && item[n-2].Is("AS") {
+		return item[:n-2], item[n-1].Text
+	}
+	if n >= 2 && (item[n-1].Kind == sqltoken.Word || item[n-1].Kind == sqltoken.Quoted) && !isKeyword(item[n-1]) {
+		prev := item[n-2]
+		if prev.Kind != sqltoken.Punct || prev.IsPunct(")") {
+			return item[:n-1], item[n-1].Text
+		}
+	}
+	return item, ""
+}
+
+// singleReference is true when the expression is only a column reference
+func singleReference(expr []sqltoken.Token) ([]string, bool) {
+	var parts []string
+	for i, t := range expr {
+		switch {
+		case i%2 == 0 && (t.Kind == sqltoken.Word || t.Kind == sqltoken.Quoted) && !isKeyword(t):
+			parts = append(parts, t.Text)
+		case i%2 == 1 && t.IsPunct("."):
+		default:
+			return nil, false
+		}
+	}
+	return parts, len(parts) > 0 && len(expr)%2 == 1
+}
+
+// references returns the dotted column references of an expression, function names,
+// keywords, literals and scalar subqueries are skipped
+func references(expr []sqltoken.Token) [][]string {
+	var refs [][]string
+	for i := 0; i < len(expr); i++ {
+		t := expr[i]
+		switch {
+		case t.IsPunct("(") && i+1 < len(expr) && (expr[i+1].Is("SELECT") || expr[i+1].Is("WITH")):
+			end := sqltoken.Matching(expr, i)
+			if end < 0 {
+				return refs
+			}
+			i = end
+			continue
+		case t.Is("INTERVAL"):
+			i += 2
+			continue
+		case t.Is("AS") || t.Is("NULLS"):
+			// type of CAST(x AS STRING) and NULLS FIRST
+			i++
+			continue
+		case t.Kind != sqltoken.Word && t.Kind != sqltoken.Quoted:
+			continue
+		case isKeyword(t):
+			continue
+		}
+
+		parts := []string{t.Text}
+		for i+2 < len(expr) && expr[i+1].IsPunct(".") && (expr[i+2].Kind == sqltoken.Word || expr[i+2].Kind == sqltoken.Quoted) {
+			parts = append(parts, expr[i+2].Text)
+			i += 2
+		}
+
+		var next, prev sqltoken.Token
+		if i+1 < len(expr) {
+			next = expr[i+1]
+		}
+		if i-len(parts)*2+1 >= 0 {
+			prev = expr[i-len(parts)*2+1]
+		}
+
+		switch {
+		case next.IsPunct("("):
+			// function call
+		case next.Kind == sqltoken.String && t.Kind == sqltoken.Word:
+			// typed literal like DATE '2024-01-01'
+		case len(parts) == 1 && dateParts[strings.ToUpper(t.Text)] &&
+			(prev.IsPunct(",") || prev.IsPunct("(")) && (next.IsPunct(")") || next.Is("FROM")):
+		default:
+			refs = append(refs, parts)
+		}
+	}
+	return refs
+}
+
+// resolve returns the qualified source columns of a reference
+func resolve(ref []string, rels []relation) []string {
+	if len(ref) > 1 {
+		qualifier := strings.Join(ref[:len(ref)-1], ".")
+		for _, rel := range rels {
+			if rel.matches(qualifier) {
+				return rel.sourcesOf(ref[len(ref)-1])
+			}
+		}
+		// a field of a struct column
+		return resolve(ref[:1], rels)
+	}
+
+	col := ref[0]
+	if len(rels) == 1 {
+		return rels[0].sourcesOf(col)
+	}
+
+	var derived, bases []relation
+	for _, rel := range rels {
+		switch {
+		case rel.derived && rel.hasColumn(col):
+			derived = append(derived, rel)
+		case !rel.derived && !rel.unknown:
+			bases = append(bases, rel)
+		}
+	}
+	if len(derived) == 1 {
+		return derived[0].sourcesOf(col)
+	}
+	if len(derived) == 0 && len(bases) == 1 {
+		return bases[0].sourcesOf(col)
+	}
+	return nil
+}
+
+func (r relation) matches(qualifier string) bool {
+	q := strings.ToLower(qualifier)
+	name := strings.ToLower(r.name)
+	return strings.ToLower(r.alias) == q || name == q || strings.HasSuffix(name, "."+q)
+}
+
+func (r relation) hasColumn(col string) bool {
+	for _, c := range r.columns {
+		if strings.EqualFold(c.name, col) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r relation) sourcesOf(col string) []string {
+	switch {
+	case r.unknown:
+		return nil
+	case !r.derived:
+		return []string{r.name + "." + col}
+	}
+
+	for _, c := range r.columns {
+		if strings.EqualFold(c.name, col) {
+			return c.sources
+		}
+	}
+
+	// columns selected with * from a table keep their name
+	var sources []string
+	for _, c := range r.columns {
+		if c.name != "*" {
+			continue
+		}
+		for _, s := range c.sources {
+			sources = append(sources, strings.TrimSuffix(s, "*")+col)
+		}
+	}
+	return sources
+}
+
+// splitTopLevel splits the tokens on the separator outside parenthesis
+func splitTopLevel(tokens []sqltoken.Token, sep string) [][]sqltoken.Token {
+	var parts [][]sqltoken.Token
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+		case depth == 0 && t.IsPunct(sep):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
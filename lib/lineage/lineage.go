@@ -0,0 +1,500 @@
+// Package lineage extracts the source and destination tables of sql queries, with the
+// lineage of columns when the query can be resolved without the table schemas.
+package lineage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sbchaos/opms/lib/sqltoken"
+)
+
+type Dialect string
+
+const (
+	DialectMaxCompute Dialect = "mc"
+	DialectBigQuery   Dialect = "bq"
+)
+
+// ParseDialect returns the dialect for mc, maxcompute, bq or bigquery
+func ParseDialect(s string) (Dialect, error) {
+	switch strings.ToLower(s) {
+	case "mc", "maxcompute":
+		return DialectMaxCompute, nil
+	case "bq", "bigquery":
+		return DialectBigQuery, nil
+	}
+	return "", fmt.Errorf("unknown dialect %s, use mc or bq", s)
+}
+
+// ColumnLineage lists the source columns used to compute a column of the destination,
+// columns are qualified with the table name, * is used when the columns are not known
+type ColumnLineage struct {
+	Target  string   `json:"target"`
+	Sources []string `json:"sources"`
+}
+
+type Lineage struct {
+	Sources      []string        `json:"sources"`
+	Destinations []string        `json:"destinations"`
+	Columns      []ColumnLineage `json:"columns,omitempty"`
+	// Statements keeps the tables of each statement, a destination depends only on the sources
+	// read by the statements which write it
+	Statements []Statement `json:"statements"`
+}
+
+// Statement lists the tables read and written by one statement, a select query has no destination
+type Statement struct {
+	Sources      []string `json:"sources"`
+	Destinations []string `json:"destinations"`
+}
+
+// Edge is a source table read to write the destination table
+type Edge struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// Parse extracts the lineage of all the statements in query. Tables created as temporary
+// are intermediate results of a script and are not listed as sources or destinations,
+// statements reading them are given the sources of the temporary table instead.
+func Parse(query string, dialect Dialect) (*Lineage, error) {
+	tokens, err := sqltoken.Tokenize(query, sqltoken.Options{HashComments: dialect == DialectBigQuery})
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := sqltoken.SplitStatements(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Lineage{}
+	temporary := map[string][]string{}
+	for _, stmt := range statements {
+		s := analyzeStatement(stmt, dialect)
+		l.Sources = appendUnique(l.Sources, s.sources...)
+		l.Destinations = appendUnique(l.Destinations, s.destinations...)
+
+		sources := resolveTemporary(s.sources, temporary)
+		if s.temporary {
+			for _, d := range s.destinations {
+				temporary[strings.ToLower(d)] = sources
+			}
+			continue
+		}
+
+		l.Columns = append(l.Columns, s.columns...)
+		if len(sources) > 0 || len(s.destinations) > 0 {
+			l.Statements = append(l.Statements, Statement{Sources: sources, Destinations: s.destinations})
+		}
+	}
+
+	l.Sources = withoutTables(l.Sources, temporary)
+	l.Destinations = withoutTables(l.Destinations, temporary)
+	return l, nil
+}
+
+// Edges pairs the sources and destinations of each statement, statements without a destination,
+// like the select query of a job, write to destination when it is not empty. A table read by the
+// statement writing it is not an edge.
+func (l *Lineage) Edges(destination string) []Edge {
+	var edges []Edge
+	seen := map[Edge]bool{}
+	for _, s := range l.Statements {
+		destinations := s.Destinations
+		if len(destinations) == 0 && destination != "" {
+			destinations = []string{destination}
+		}
+
+		for _, d := range destinations {
+			for _, src := range s.Sources {
+				if strings.EqualFold(src, d) {
+					continue
+				}
+				e := Edge{Source: src, Destination: d}
+				if !seen[e] {
+					seen[e] = true
+					edges = append(edges, e)
+				}
+			}
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Destination != edges[j].Destination {
+			return edges[i].Destination < edges[j].Destination
+		}
+		return edges[i].Source < edges[j].Source
+	})
+	return edges
+}
+
+// resolveTemporary replaces the temporary tables in sources with the tables they are created from
+func resolveTemporary(sources []string, temporary map[string][]string) []string {
+	var resolved []string
+	for _, src := range sources {
+		if from, ok := temporary[strings.ToLower(src)]; ok {
+			resolved = appendUnique(resolved, from...)
+			continue
+		}
+		resolved = appendUnique(resolved, src)
+	}
+	return resolved
+}
+
+type statement struct {
+	sources      []string
+	destinations []string
+	columns      []ColumnLineage
+	temporary    bool
+}
+
+// analyzeStatement collects tables after FROM and JOIN at any depth, names of CTEs are skipped
+func analyzeStatement(tokens []sqltoken.Token, dialect Dialect) statement {
+	var s statement
+	ctes := cteNames(tokens, dialect)
+
+	dest, destEnd, insertCols := -1, -1, []string(nil)
+	var owners []string
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsPunct("("):
+			owner := ""
+			if i > 0 && tokens[i-1].Kind == sqltoken.Word {
+				owner = strings.ToUpper(tokens[i-1].Text)
+			}
+			owners = append(owners, owner)
+			continue
+		case t.IsPunct(")"):
+			if len(owners) > 0 {
+				owners = owners[:len(owners)-1]
+			}
+			continue
+		case t.Kind != sqltoken.Word:
+			continue
+		}
+
+		switch strings.ToUpper(t.Text) {
+		case "FROM":
+			if isFunctionFrom(tokens, i, owners) {
+				continue
+			}
+			if i > 0 && tokens[i-1].Is("DELETE") {
+				name, next, ok := readName(tokens, i+1, dialect)
+				if ok {
+					s.destinations = appendUnique(s.destinations, name)
+					i = next - 1
+				}
+				continue
+			}
+			s.sources = appendUnique(s.sources, fromTables(tokens, i+1, dialect, ctes)...)
+
+		case "JOIN":
+			if name, _, ok := readName(tokens, i+1, dialect); ok && !isCTE(name, ctes) && !isFunctionCall(tokens, i+1) {
+				s.sources = appendUnique(s.sources, name)
+			}
+
+		case "USING":
+			if !hasMerge(tokens) {
+				continue
+			}
+			if name, _, ok := readName(tokens, i+1, dialect); ok && !isCTE(name, ctes) {
+				s.sources = appendUnique(s.sources, name)
+			}
+
+		case "INSERT":
+			j := skipWords(tokens, i+1, "INTO", "OVERWRITE", "TABLE")
+			name, next, ok := readName(tokens, j, dialect)
+			if !ok {
+				continue
+			}
+			s.destinations = appendUnique(s.destinations, name)
+			dest = len(s.destinations) - 1
+			next, insertCols = insertColumns(tokens, next)
+			destEnd = next
+			i = next - 1
+
+		case "CREATE":
+			j := skipWords(tokens, i+1, "OR", "REPLACE", "TEMP", "TEMPORARY", "EXTERNAL", "MATERIALIZED")
+			temporary := j > i+1 && (tokens[j-1].Is("TEMP") || tokens[j-1].Is("TEMPORARY"))
+			if j >= len(tokens) || !(tokens[j].Is("TABLE") || tokens[j].Is("VIEW")) {
+				continue
+			}
+			j = skipWords(tokens, j+1, "IF", "NOT", "EXISTS")
+			name, next, ok := readName(tokens, j, dialect)
+			if !ok {
+				continue
+			}
+			s.destinations = appendUnique(s.destinations, name)
+			s.temporary = s.temporary || temporary
+			dest = len(s.destinations) - 1
+			destEnd = next
+			i = next - 1
+
+		case "MERGE", "UPDATE":
+			j := skipWords(tokens, i+1, "INTO")
+			if name, next, ok := readName(tokens, j, dialect); ok {
+				s.destinations = appendUnique(s.destinations, name)
+				i = next - 1
+			}
+		}
+	}
+
+	if dest >= 0 {
+		s.columns = destinationColumns(tokens[destEnd:], s.destinations[dest], insertCols, dialect)
+	}
+	return s
+}
+
+// fromTables reads the comma separated tables of a FROM clause, subqueries are read by the caller
+func fromTables(tokens []sqltoken.Token, i int, dialect Dialect, ctes map[string]bool) []string {
+	var tables []string
+	aliases := map[string]bool{}
+	for i < len(tokens) {
+		if tokens[i].IsPunct("(") {
+			i = sqltoken.Matching(tokens, i) + 1
+			if i == 0 {
+				return tables
+			}
+		} else {
+			name, next, ok := readName(tokens, i, dialect)
+			if !ok {
+				return tables
+			}
+
+			// correlated paths like t.array_column are not tables
+			first, _, _ := strings.Cut(name, ".")
+			switch {
+			case isFunctionCall(tokens, i):
+				next = sqltoken.Matching(tokens, next) + 1
+			case !isCTE(name, ctes) && !aliases[strings.ToLower(first)]:
+				tables = append(tables, name)
+			}
+			i = next
+		}
+
+		alias, next := readAlias(tokens, i)
+		if alias != "" {
+			aliases[strings.ToLower(alias)] = true
+		}
+		i = next
+		if i >= len(tokens) || !tokens[i].IsPunct(",") {
+			return tables
+		}
+		i++
+	}
+	return tables
+}
+
+// cteNames collects the names defined in WITH clauses at any depth of the statement
+func cteNames(tokens []sqltoken.Token, dialect Dialect) map[string]bool {
+	names := map[string]bool{}
+	for i, t := range tokens {
+		if !t.Is("WITH") {
+			continue
+		}
+		ctes, _ := readCTEs(tokens, i, dialect)
+		for _, c := range ctes {
+			names[strings.ToLower(c.name)] = true
+		}
+	}
+	return names
+}
+
+type cte struct {
+	name    string
+	columns []string
+	body    []sqltoken.Token
+}
+
+// readCTEs reads the list of name [(columns)] AS (query) after WITH at i, with the position after the list
+func readCTEs(tokens []sqltoken.Token, i int, dialect Dialect) ([]cte, int) {
+	var ctes []cte
+	j := skipWords(tokens, i+1, "RECURSIVE")
+	for j < len(tokens) {
+		name, next, ok := readName(tokens, j, dialect)
+		if !ok {
+			return ctes, j
+		}
+
+		c := cte{name: name}
+		if next < len(tokens) && tokens[next].IsPunct("(") {
+			end := sqltoken.Matching(tokens, next)
+			if end < 0 {
+				return ctes, j
+			}
+			c.columns = identifiers(tokens[next+1 : end])
+			next = end + 1
+		}
+
+		if next+1 >= len(tokens) || !tokens[next].Is("AS") || !tokens[next+1].IsPunct("(") {
+			return ctes, j
+		}
+		end := sqltoken.Matching(tokens, next+1)
+		if end < 0 {
+			return ctes, j
+		}
+		c.body = tokens[next+2 : end]
+		ctes = append(ctes, c)
+
+		j = end + 1
+		if j >= len(tokens) || !tokens[j].IsPunct(",") {
+			return ctes, j
+		}
+		j++
+	}
+	return ctes, j
+}
+
+// readName reads a dotted table name, parts of name can be quoted or templated. Unquoted names
+// of bigquery can have dashes in project, partition decorators after $ are dropped.
+func readName(tokens []sqltoken.Token, i int, dialect Dialect) (string, int, bool) {
+	if i >= len(tokens) || !tokens[i].IsNamePart() || isKeyword(tokens[i]) {
+		return "", i, false
+	}
+
+	var b strings.Builder
+	for i < len(tokens) {
+		t := tokens[i]
+		switch {
+		case t.IsNamePart() || t.Kind == sqltoken.Number:
+			b.WriteString(t.Text)
+		case t.IsPunct("."):
+			b.WriteString(".")
+		case t.IsPunct("-") && dialect == DialectBigQuery:
+			b.WriteString("-")
+		default:
+			return cleanName(b.String()), i, true
+		}
+
+		i++
+		if i >= len(tokens) {
+			break
+		}
+
+		// parts of name are not separated by spaces, except around the dots
+		n := tokens[i]
+		if n.IsPunct(".") || t.IsPunct(".") {
+			continue
+		}
+		if n.Space || !(n.IsNamePart() || n.Kind == sqltoken.Number || n.IsPunct("-") && dialect == DialectBigQuery) {
+			break
+		}
+	}
+	return cleanName(b.String()), i, true
+}
+
+func cleanName(name string) string {
+	name, _, _ = strings.Cut(name, "$")
+	return strings.Trim(name, ".")
+}
+
+// readAlias reads an optional [AS] alias at i
+func readAlias(tokens []sqltoken.Token, i int) (string, int) {
+	if i < len(tokens) && tokens[i].Is("AS") {
+		i++
+	}
+	if i < len(tokens) && (tokens[i].Kind == sqltoken.Word || tokens[i].Kind == sqltoken.Quoted) && !isKeyword(tokens[i]) {
+		return tokens[i].Text, i + 1
+	}
+	return "", i
+}
+
+// insertColumns reads the optional partition spec and column list after the destination of INSERT
+func insertColumns(tokens []sqltoken.Token, i int) (int, []string) {
+	if i < len(tokens) && tokens[i].Is("PARTITION") && i+1 < len(tokens) && tokens[i+1].IsPunct("(") {
+		i = sqltoken.Matching(tokens, i+1) + 1
+	}
+	if i < len(tokens) && tokens[i].IsPunct("(") {
+		end := sqltoken.Matching(tokens, i)
+		if end > i && end+1 < len(tokens) && !tokens[i+1].Is("SELECT") && !tokens[i+1].Is("WITH") {
+			return end + 1, identifiers(tokens[i+1 : end])
+		}
+	}
+	return i, nil
+}
+
+func identifiers(tokens []sqltoken.Token) []string {
+	var names []string
+	for _, t := range tokens {
+		if t.Kind == sqltoken.Word || t.Kind == sqltoken.Quoted {
+			names = append(names, t.Text)
+		}
+	}
+	return names
+}
+
+func skipWords(tokens []sqltoken.Token, i int, words ...string) int {
+	for i < len(tokens) {
+		found := false
+		for _, w := range words {
+			if tokens[i].Is(w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// isFunctionFrom is true for FROM used in function arguments, EXTRACT(DAY FROM dt) and IS DISTINCT FROM
+func isFunctionFrom(tokens []sqltoken.Token, i int, owners []string) bool {
+	if i > 0 && tokens[i-1].Is("DISTINCT") {
+		return true
+	}
+	if len(owners) == 0 {
+		return false
+	}
+	switch owners[len(owners)-1] {
+	case "EXTRACT", "TRIM", "SUBSTRING", "POSITION", "OVERLAY":
+		return true
+	}
+	return false
+}
+
+func isFunctionCall(tokens []sqltoken.Token, i int) bool {
+	_, next, ok := readName(tokens, i, "")
+	return ok && next < len(tokens) && tokens[next].IsPunct("(")
+}
+
+func hasMerge(tokens []sqltoken.Token) bool {
+	return len(tokens) > 0 && tokens[0].Is("MERGE")
+}
+
+func isCTE(name string, ctes map[string]bool) bool {
+	return ctes[strings.ToLower(name)]
+}
+
+func appendUnique(values []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, v := range values {
+			if strings.EqualFold(v, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func withoutTables(tables []string, skip map[string][]string) []string {
+	result := make([]string, 0, len(tables))
+	for _, t := range tables {
+		if _, ok := skip[strings.ToLower(t)]; !ok {
+			result = append(result, t)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
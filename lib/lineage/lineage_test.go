@@ -0,0 +1,176 @@
+package lineage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/lib/lineage"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("returns tables of insert select with joins", func(t *testing.T) {
+		query := `INSERT OVERWRITE TABLE proj.mart.orders PARTITION (dt='{{ .DSTART }}')
+SELECT o.id, o.amount * r.rate AS amount_usd, c.name customer
+FROM proj.raw.orders o
+LEFT JOIN proj.raw.rates r ON o.currency = r.currency
+JOIN proj.raw.customers AS c ON c.id = o.customer_id
+WHERE o.dt = '{{ .DSTART }}';`
+
+		l, err := lineage.Parse(query, lineage.DialectMaxCompute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"proj.raw.customers", "proj.raw.orders", "proj.raw.rates"}, l.Sources)
+		assert.Equal(t, []string{"proj.mart.orders"}, l.Destinations)
+		assert.Equal(t, []lineage.ColumnLineage{
+			{Target: "proj.mart.orders.id", Sources: []string{"proj.raw.orders.id"}},
+			{Target: "proj.mart.orders.amount_usd", Sources: []string{"proj.raw.orders.amount", "proj.raw.rates.rate"}},
+			{Target: "proj.mart.orders.customer", Sources: []string{"proj.raw.customers.name"}},
+		}, l.Columns)
+	})
+
+	t.Run("resolves columns through ctes and subqueries", func(t *testing.T) {
+		query := "CREATE OR REPLACE TABLE `data-proj.mart.daily` AS\n" + `
+WITH base AS (
+  SELECT user_id, DATE(created_at) AS day FROM ` + "`data-proj.raw.events`" + `
+), counts (uid, day, total) AS (
+  SELECT b.user_id, b.day, COUNT(*) FROM base b GROUP BY 1, 2
+)
+SELECT c.uid, c.total, u.country
+FROM counts c
+JOIN (SELECT id, country FROM data-proj.raw.users) u ON u.id = c.uid`
+
+		l, err := lineage.Parse(query, lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"data-proj.raw.events", "data-proj.raw.users"}, l.Sources)
+		assert.Equal(t, []string{"data-proj.mart.daily"}, l.Destinations)
+		assert.Equal(t, []lineage.ColumnLineage{
+			{Target: "data-proj.mart.daily.uid", Sources: []string{"data-proj.raw.events.user_id"}},
+			{Target: "data-proj.mart.daily.total", Sources: []string{}},
+			{Target: "data-proj.mart.daily.country", Sources: []string{"data-proj.raw.users.country"}},
+		}, l.Columns)
+	})
+
+	t.Run("merges sources of union branches and uses insert columns", func(t *testing.T) {
+		query := `INSERT INTO ds.all_users (user_id, src)
+SELECT id, 'app' FROM ds.app_users
+UNION ALL
+SELECT user_id, 'web' FROM ds.web_users`
+
+		l, err := lineage.Parse(query, lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ds.app_users", "ds.web_users"}, l.Sources)
+		assert.Equal(t, []lineage.ColumnLineage{
+			{Target: "ds.all_users.user_id", Sources: []string{"ds.app_users.id", "ds.web_users.user_id"}},
+			{Target: "ds.all_users.src", Sources: []string{}},
+		}, l.Columns)
+	})
+
+	t.Run("expands star of ctes and keeps star of tables", func(t *testing.T) {
+		query := `INSERT INTO ds.t
+WITH x AS (SELECT a, b FROM ds.s1)
+SELECT * EXCEPT (b) FROM x`
+
+		l, err := lineage.Parse(query, lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []lineage.ColumnLineage{{Target: "ds.t.a", Sources: []string{"ds.s1.a"}}}, l.Columns)
+
+		l, err = lineage.Parse("insert into ds.t select * from ds.s1", lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []lineage.ColumnLineage{{Target: "ds.t.*", Sources: []string{"ds.s1.*"}}}, l.Columns)
+	})
+
+	t.Run("ignores comments, strings and function arguments", func(t *testing.T) {
+		query := `-- SELECT * FROM commented.table
+/* FROM block.comment */
+SELECT EXTRACT(DAY FROM dt) AS d, 'from quoted.string' AS s, DATE_TRUNC(dt, MONTH) AS m,
+  (SELECT MAX(v) FROM p.s.lookup) AS mx
+FROM p.s.events, UNNEST(tags) AS tag
+WHERE a IS DISTINCT FROM b`
+
+		l, err := lineage.Parse(query, lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"p.s.events", "p.s.lookup"}, l.Sources)
+		assert.Empty(t, l.Destinations)
+		assert.Empty(t, l.Columns)
+	})
+
+	t.Run("drops temporary tables of scripts", func(t *testing.T) {
+		query := `CREATE TEMP TABLE tmp AS SELECT id FROM ds.src;
+MERGE ds.target t USING tmp s ON t.id = s.id
+WHEN NOT MATCHED THEN INSERT (id) VALUES (s.id);
+DELETE FROM ds.old WHERE id IN (SELECT id FROM ds.removed);`
+
+		l, err := lineage.Parse(query, lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ds.removed", "ds.src"}, l.Sources)
+		assert.Equal(t, []string{"ds.old", "ds.target"}, l.Destinations)
+		assert.Equal(t, []lineage.Statement{
+			{Sources: []string{"ds.src"}, Destinations: []string{"ds.target"}},
+			{Sources: []string{"ds.removed"}, Destinations: []string{"ds.old"}},
+		}, l.Statements)
+	})
+
+	t.Run("keeps the tables of each statement", func(t *testing.T) {
+		query := `INSERT INTO ds.a SELECT id FROM ds.x;
+INSERT INTO ds.b SELECT id FROM ds.y JOIN ds.z USING (id);`
+
+		l, err := lineage.Parse(query, lineage.DialectMaxCompute)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ds.x", "ds.y", "ds.z"}, l.Sources)
+		assert.Equal(t, []string{"ds.a", "ds.b"}, l.Destinations)
+		assert.Equal(t, []lineage.Statement{
+			{Sources: []string{"ds.x"}, Destinations: []string{"ds.a"}},
+			{Sources: []string{"ds.y", "ds.z"}, Destinations: []string{"ds.b"}},
+		}, l.Statements)
+	})
+
+	t.Run("reads templated and partition decorated names", func(t *testing.T) {
+		query := "INSERT INTO `{{.PROJECT}}.ds.t$20240101` SELECT x FROM {{.PROJECT}}.ds.src_{{.SUFFIX}}"
+
+		l, err := lineage.Parse(query, lineage.DialectBigQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"{{.PROJECT}}.ds.src_{{.SUFFIX}}"}, l.Sources)
+		assert.Equal(t, []string{"{{.PROJECT}}.ds.t"}, l.Destinations)
+	})
+
+	t.Run("returns error for unbalanced query", func(t *testing.T) {
+		_, err := lineage.Parse("SELECT (a FROM t", lineage.DialectMaxCompute)
+		assert.ErrorContains(t, err, "unbalanced parenthesis")
+
+		_, err = lineage.Parse("SELECT 'a FROM t", lineage.DialectMaxCompute)
+		assert.ErrorContains(t, err, "unterminated string")
+	})
+}
+
+func TestEdges(t *testing.T) {
+	query := `CREATE TEMP TABLE tmp AS SELECT id FROM ds.src;
+INSERT INTO ds.a SELECT id FROM tmp JOIN ds.x USING (id);
+INSERT INTO ds.b SELECT id FROM ds.y;
+SELECT id FROM ds.z;`
+
+	l, err := lineage.Parse(query, lineage.DialectBigQuery)
+	assert.NoError(t, err)
+
+	t.Run("pairs tables of the same statement", func(t *testing.T) {
+		assert.Equal(t, []lineage.Edge{
+			{Source: "ds.src", Destination: "ds.a"},
+			{Source: "ds.x", Destination: "ds.a"},
+			{Source: "ds.y", Destination: "ds.b"},
+		}, l.Edges(""))
+	})
+	t.Run("writes select statements to the destination", func(t *testing.T) {
+		edges := l.Edges("ds.job_dest")
+		assert.Len(t, edges, 4)
+		assert.Contains(t, edges, lineage.Edge{Source: "ds.z", Destination: "ds.job_dest"})
+		assert.NotContains(t, edges, lineage.Edge{Source: "ds.y", Destination: "ds.job_dest"})
+	})
+}
+
+func TestParseDialect(t *testing.T) {
+	d, err := lineage.ParseDialect("BigQuery")
+	assert.NoError(t, err)
+	assert.Equal(t, lineage.DialectBigQuery, d)
+
+	_, err = lineage.ParseDialect("postgres")
+	assert.ErrorContains(t, err, "unknown dialect")
+}
@@ -0,0 +1,164 @@
+package table
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+)
+
+// Formats lists the output formats supported by NewWithFormat.
+var Formats = []string{FormatTable, FormatCSV, FormatJSON, FormatJSONL}
+
+// NewWithFormat initializes a printer for the requested output format. The table format behaves
+// exactly like New, the other formats ignore the terminal settings and produce machine-readable
+// output where the header is used for the column names.
+func NewWithFormat(w io.Writer, format string, isTTY bool, maxWidth int) (Printer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatTable:
+		return New(w, isTTY, maxWidth), nil
+	case FormatCSV:
+		return &csvPrinter{out: csv.NewWriter(w)}, nil
+	case FormatJSON:
+		return &jsonPrinter{out: w}, nil
+	case FormatJSONL:
+		return &jsonPrinter{out: w, lines: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, supported: %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// ValuePrinter is implemented by the printers which keep the type of values, like json.
+// Values should be json compatible, fields added with AddField are written as strings.
+type ValuePrinter interface {
+	AddValue(v any)
+}
+
+type csvPrinter struct {
+	out        *csv.Writer
+	hasHeaders bool
+	row        []string
+}
+
+func (c *csvPrinter) AddHeader(columns []string, _ ...fieldOption) {
+	if c.hasHeaders {
+		return
+	}
+
+	c.hasHeaders = true
+	_ = c.out.Write(columns)
+}
+
+func (c *csvPrinter) AddField(s string, _ ...fieldOption) {
+	c.row = append(c.row, s)
+}
+
+func (c *csvPrinter) EndRow() {
+	_ = c.out.Write(c.row)
+	c.row = nil
+}
+
+func (c *csvPrinter) Clear() {
+	c.row = nil
+}
+
+func (c *csvPrinter) Render() error {
+	c.out.Flush()
+	return c.out.Error()
+}
+
+// jsonPrinter writes every row as an object keyed by the header. In lines mode each row
+// is written as soon as it ends, otherwise all the rows are written as an array on Render.
+type jsonPrinter struct {
+	out   io.Writer
+	lines bool
+
+	headers []string
+	row     []any
+	rows    [][]any
+}
+
+func (j *jsonPrinter) AddHeader(columns []string, _ ...fieldOption) {
+	if j.headers != nil {
+		return
+	}
+	j.headers = columns
+}
+
+func (j *jsonPrinter) AddField(s string, _ ...fieldOption) {
+	j.row = append(j.row, s)
+}
+
+func (j *jsonPrinter) AddValue(v any) {
+	j.row = append(j.row, v)
+}
+
+func (j *jsonPrinter) EndRow() {
+	row := j.row
+	j.row = nil
+	if j.lines {
+		fmt.Fprintf(j.out, "%s\n", j.encodeRow(row))
+		return
+	}
+	j.rows = append(j.rows, row)
+}
+
+func (j *jsonPrinter) Clear() {
+	j.row = nil
+	j.rows = nil
+}
+
+func (j *jsonPrinter) Render() error {
+	if j.lines {
+		return nil
+	}
+
+	buf := bytes.NewBufferString("[")
+	for i, row := range j.rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		buf.Write(j.encodeRow(row))
+	}
+	if len(j.rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+
+	_, err := j.out.Write(buf.Bytes())
+	return err
+}
+
+// encodeRow keeps the column order of the header, which a map based encoding would lose.
+func (j *jsonPrinter) encodeRow(row []any) []byte {
+	buf := bytes.NewBufferString("{")
+	for i, value := range row {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key := fmt.Sprintf("col_%d", i+1)
+		if i < len(j.headers) {
+			key = j.headers[i]
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(value)
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprintf("%v", value))
+		}
+		buf.Write(k)
+		buf.WriteString(":")
+		buf.Write(v)
+	}
+	buf.WriteString("}")
+	return buf.Bytes()
+}
@@ -0,0 +1,126 @@
+package table_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sbchaos/opms/lib/printers/table"
+)
+
+func addRows(tp table.Printer) {
+	tp.AddHeader([]string{"name", "count"})
+	tp.AddField("a,b")
+	tp.AddField("1")
+	tp.EndRow()
+	tp.AddField("c")
+	tp.AddField("2")
+	tp.EndRow()
+}
+
+func Test_NewWithFormat_unknown(t *testing.T) {
+	_, err := table.NewWithFormat(&bytes.Buffer{}, "xml", false, 0)
+	if err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
+
+func Test_csvPrinter(t *testing.T) {
+	buf := bytes.Buffer{}
+	tp, err := table.NewWithFormat(&buf, table.FormatCSV, true, 80)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	addRows(tp)
+	if err := tp.Render(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "name,count\n\"a,b\",1\nc,2\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func Test_jsonPrinter(t *testing.T) {
+	buf := bytes.Buffer{}
+	tp, err := table.NewWithFormat(&buf, table.FormatJSON, true, 80)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	addRows(tp)
+	if err := tp.Render(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[\n  {\"name\":\"a,b\",\"count\":\"1\"},\n  {\"name\":\"c\",\"count\":\"2\"}\n]\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func Test_jsonPrinter_empty(t *testing.T) {
+	buf := bytes.Buffer{}
+	tp, _ := table.NewWithFormat(&buf, table.FormatJSON, false, 0)
+
+	tp.AddHeader([]string{"name"})
+	if err := tp.Render(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[]\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func Test_jsonlPrinter(t *testing.T) {
+	buf := bytes.Buffer{}
+	tp, err := table.NewWithFormat(&buf, table.FormatJSONL, false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	addRows(tp)
+	tp.AddField("extra")
+	tp.AddField("3")
+	tp.AddField("x")
+	tp.EndRow()
+	if err := tp.Render(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "{\"name\":\"a,b\",\"count\":\"1\"}\n{\"name\":\"c\",\"count\":\"2\"}\n{\"name\":\"extra\",\"count\":\"3\",\"col_3\":\"x\"}\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
+
+func Test_jsonlPrinter_values(t *testing.T) {
+	buf := bytes.Buffer{}
+	tp, err := table.NewWithFormat(&buf, table.FormatJSONL, false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vp, ok := tp.(table.ValuePrinter)
+	if !ok {
+		t.Fatalf("expected json printer to implement ValuePrinter")
+	}
+
+	tp.AddHeader([]string{"id", "name", "tags", "deleted"})
+	vp.AddValue(int64(1))
+	tp.AddField("a")
+	vp.AddValue([]any{"x", "y"})
+	vp.AddValue(nil)
+	tp.EndRow()
+	if err := tp.Render(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "{\"id\":1,\"name\":\"a\",\"tags\":[\"x\",\"y\"],\"deleted\":null}\n"
+	if buf.String() != expected {
+		t.Errorf("expected: %q, got: %q", expected, buf.String())
+	}
+}
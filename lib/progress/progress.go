@@ -0,0 +1,93 @@
+// Package progress prints the progress of long running transfers on a single line.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const refreshInterval = 200 * time.Millisecond
+
+// Bar shows the processed count against the total, when total is unknown only the count is shown.
+// A disabled bar prints nothing, which is used when the output is not a terminal.
+type Bar struct {
+	out     io.Writer
+	label   string
+	total   int64
+	enabled bool
+
+	mu        sync.Mutex
+	current   int64
+	lastPrint time.Time
+}
+
+func New(out io.Writer, label string, total int64, enabled bool) *Bar {
+	return &Bar{
+		out:     out,
+		label:   label,
+		total:   total,
+		enabled: enabled,
+	}
+}
+
+// Add increases the processed count and refreshes the line at most every refreshInterval
+func (b *Bar) Add(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current += n
+	if time.Since(b.lastPrint) < refreshInterval {
+		return
+	}
+	b.print()
+}
+
+// Current returns the processed count
+func (b *Bar) Current() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current
+}
+
+// Done prints the final state and ends the line
+func (b *Bar) Done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.print()
+	if b.enabled {
+		fmt.Fprintln(b.out)
+	}
+}
+
+func (b *Bar) print() {
+	b.lastPrint = time.Now()
+	if !b.enabled {
+		return
+	}
+
+	fmt.Fprint(b.out, "\r"+b.String())
+}
+
+func (b *Bar) String() string {
+	if b.total <= 0 {
+		return fmt.Sprintf("%s: %d", b.label, b.current)
+	}
+
+	percent := float64(b.current) * 100 / float64(b.total)
+	return fmt.Sprintf("%s: %d/%d (%.1f%%)", b.label, b.current, b.total, percent)
+}
+
+// Reader counts the bytes read from the underlying reader in the bar
+type Reader struct {
+	io.Reader
+	Bar *Bar
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.Bar.Add(int64(n))
+	return n, err
+}
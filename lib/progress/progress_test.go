@@ -0,0 +1,48 @@
+package progress_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/lib/progress"
+)
+
+func TestBar(t *testing.T) {
+	t.Run("prints count and percentage", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		bar := progress.New(&buf, "rows", 4, true)
+		bar.Add(1)
+		bar.Add(2)
+		bar.Done()
+
+		assert.Equal(t, int64(3), bar.Current())
+		assert.Equal(t, "rows: 3/4 (75.0%)", bar.String())
+		assert.True(t, strings.HasSuffix(buf.String(), "\rrows: 3/4 (75.0%)\n"))
+	})
+	t.Run("prints only count without total", func(t *testing.T) {
+		bar := progress.New(io.Discard, "rows", 0, true)
+		bar.Add(10)
+
+		assert.Equal(t, "rows: 10", bar.String())
+	})
+	t.Run("prints nothing when disabled", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		bar := progress.New(&buf, "rows", 10, false)
+		bar.Add(5)
+		bar.Done()
+
+		assert.Empty(t, buf.String())
+	})
+	t.Run("counts bytes of reader", func(t *testing.T) {
+		bar := progress.New(io.Discard, "bytes", 5, false)
+		r := &progress.Reader{Reader: strings.NewReader("hello"), Bar: bar}
+		_, err := io.ReadAll(r)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), bar.Current())
+	})
+}
@@ -0,0 +1,189 @@
+// Package schema compares table schemas coming from different warehouses.
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Column struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Table is the warehouse independent schema of a table, partition columns are
+// also expected to be part of the columns
+type Table struct {
+	Name      string
+	Columns   []Column
+	Partition []string
+}
+
+const (
+	KindMissing     = "missing"
+	KindExtra       = "extra"
+	KindType        = "type"
+	KindOrder       = "order"
+	KindDescription = "description"
+	KindPartition   = "partition"
+)
+
+// Difference is found in right when compared to left
+type Difference struct {
+	Kind   string
+	Column string
+	Left   string
+	Right  string
+}
+
+type Options struct {
+	// TypeMapping is applied on the types of left table before comparing
+	TypeMapping          map[string]string
+	IgnoreDescriptions   bool
+	IgnoreColumnOrdering bool
+}
+
+// Compare returns the differences of right when compared to left, column names are case-insensitive
+func Compare(left, right Table, opts Options) []Difference {
+	var diffs []Difference
+
+	rightCols := map[string]Column{}
+	for _, c := range right.Columns {
+		rightCols[strings.ToLower(c.Name)] = c
+	}
+	leftCols := map[string]bool{}
+
+	var leftOrder []string
+	for _, lc := range left.Columns {
+		name := strings.ToLower(lc.Name)
+		leftCols[name] = true
+
+		rc, ok := rightCols[name]
+		if !ok {
+			diffs = append(diffs, Difference{Kind: KindMissing, Column: lc.Name, Left: lc.Type})
+			continue
+		}
+		leftOrder = append(leftOrder, name)
+
+		leftType := MapType(lc.Type, opts.TypeMapping)
+		if !SameType(leftType, rc.Type) {
+			diffs = append(diffs, Difference{Kind: KindType, Column: lc.Name, Left: leftType, Right: rc.Type})
+		}
+
+		if !opts.IgnoreDescriptions && strings.TrimSpace(lc.Description) != strings.TrimSpace(rc.Description) {
+			diffs = append(diffs, Difference{Kind: KindDescription, Column: lc.Name, Left: lc.Description, Right: rc.Description})
+		}
+	}
+
+	var rightOrder []string
+	for _, rc := range right.Columns {
+		name := strings.ToLower(rc.Name)
+		if !leftCols[name] {
+			diffs = append(diffs, Difference{Kind: KindExtra, Column: rc.Name, Right: rc.Type})
+			continue
+		}
+		rightOrder = append(rightOrder, name)
+	}
+
+	if !opts.IgnoreColumnOrdering {
+		for i := range leftOrder {
+			if leftOrder[i] != rightOrder[i] {
+				diffs = append(diffs, Difference{
+					Kind:   KindOrder,
+					Column: leftOrder[i],
+					Left:   fmt.Sprintf("position %d", i+1),
+					Right:  fmt.Sprintf("position %d", indexOf(rightOrder, leftOrder[i])+1),
+				})
+			}
+		}
+	}
+
+	leftPartition := strings.ToLower(strings.Join(left.Partition, ","))
+	rightPartition := strings.ToLower(strings.Join(right.Partition, ","))
+	if leftPartition != rightPartition {
+		diffs = append(diffs, Difference{
+			Kind:  KindPartition,
+			Left:  strings.Join(left.Partition, ","),
+			Right: strings.Join(right.Partition, ","),
+		})
+	}
+
+	return diffs
+}
+
+// MapType replaces every type name in a type, including the types nested in ARRAY, MAP and STRUCT.
+// Names of struct fields, written as name:TYPE, are kept as is.
+func MapType(typ string, mapping map[string]string) string {
+	if len(mapping) == 0 {
+		return typ
+	}
+
+	if mapped, ok := mapping[strings.ToUpper(typ)]; ok {
+		return mapped
+	}
+
+	b := strings.Builder{}
+	runes := []rune(typ)
+	for i := 0; i < len(runes); {
+		if !isIdentifier(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(runes) && isIdentifier(runes[i]) {
+			i++
+		}
+		token := string(runes[start:i])
+
+		if i < len(runes) && runes[i] == ':' {
+			b.WriteString(token)
+			continue
+		}
+
+		if mapped, ok := mapping[strings.ToUpper(token)]; ok {
+			token = mapped
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
+
+// SameType compares the types ignoring case and spaces, a type without parameters
+// matches the same type with parameters, so DECIMAL is same as DECIMAL(38,9)
+func SameType(a, b string) bool {
+	a = normalize(a)
+	b = normalize(b)
+	if a == b {
+		return true
+	}
+
+	baseA, paramA, _ := strings.Cut(a, "(")
+	baseB, paramB, _ := strings.Cut(b, "(")
+	return baseA == baseB && (paramA == "" || paramB == "")
+}
+
+func normalize(typ string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '`' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, typ)
+}
+
+func isIdentifier(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func indexOf(list []string, item string) int {
+	for i, v := range list {
+		if v == item {
+			return i
+		}
+	}
+	return -1
+}
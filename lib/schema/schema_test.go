@@ -0,0 +1,93 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/lib/schema"
+)
+
+func TestMapType(t *testing.T) {
+	mapping := map[string]string{
+		"INT64":   "BIGINT",
+		"FLOAT64": "DOUBLE",
+		"NUMERIC": "DECIMAL",
+	}
+
+	t.Run("maps simple type", func(t *testing.T) {
+		assert.Equal(t, "BIGINT", schema.MapType("int64", mapping))
+	})
+	t.Run("keeps unknown type", func(t *testing.T) {
+		assert.Equal(t, "STRING", schema.MapType("STRING", mapping))
+	})
+	t.Run("maps nested types", func(t *testing.T) {
+		got := schema.MapType("ARRAY<STRUCT<int64:INT64,price:NUMERIC>>", mapping)
+		assert.Equal(t, "ARRAY<STRUCT<int64:BIGINT,price:DECIMAL>>", got)
+	})
+}
+
+func TestSameType(t *testing.T) {
+	assert.True(t, schema.SameType("decimal", "DECIMAL(38,9)"))
+	assert.True(t, schema.SameType("ARRAY<STRING>", "array< string >"))
+	assert.True(t, schema.SameType("STRUCT<`a`:BIGINT>", "STRUCT<a:BIGINT>"))
+	assert.False(t, schema.SameType("DECIMAL(10,2)", "DECIMAL(38,9)"))
+	assert.False(t, schema.SameType("BIGINT", "INT"))
+}
+
+func TestCompare(t *testing.T) {
+	mapping := map[string]string{"INT64": "BIGINT"}
+
+	t.Run("returns no difference for same schema", func(t *testing.T) {
+		left := schema.Table{
+			Columns:   []schema.Column{{Name: "id", Type: "INT64"}, {Name: "dt", Type: "DATE"}},
+			Partition: []string{"dt"},
+		}
+		right := schema.Table{
+			Columns:   []schema.Column{{Name: "ID", Type: "BIGINT"}, {Name: "dt", Type: "date"}},
+			Partition: []string{"DT"},
+		}
+
+		diffs := schema.Compare(left, right, schema.Options{TypeMapping: mapping})
+		assert.Empty(t, diffs)
+	})
+	t.Run("returns all differences", func(t *testing.T) {
+		left := schema.Table{
+			Columns: []schema.Column{
+				{Name: "id", Type: "INT64", Description: "identifier"},
+				{Name: "name", Type: "STRING"},
+				{Name: "amount", Type: "FLOAT64"},
+				{Name: "removed", Type: "STRING"},
+			},
+			Partition: []string{"created"},
+		}
+		right := schema.Table{
+			Columns: []schema.Column{
+				{Name: "name", Type: "STRING"},
+				{Name: "id", Type: "BIGINT"},
+				{Name: "amount", Type: "DOUBLE"},
+				{Name: "added", Type: "STRING"},
+			},
+			Partition: []string{"dt"},
+		}
+
+		diffs := schema.Compare(left, right, schema.Options{TypeMapping: mapping})
+		expected := []schema.Difference{
+			{Kind: schema.KindDescription, Column: "id", Left: "identifier"},
+			{Kind: schema.KindType, Column: "amount", Left: "FLOAT64", Right: "DOUBLE"},
+			{Kind: schema.KindMissing, Column: "removed", Left: "STRING"},
+			{Kind: schema.KindExtra, Column: "added", Right: "STRING"},
+			{Kind: schema.KindOrder, Column: "id", Left: "position 1", Right: "position 2"},
+			{Kind: schema.KindOrder, Column: "name", Left: "position 2", Right: "position 1"},
+			{Kind: schema.KindPartition, Left: "created", Right: "dt"},
+		}
+		assert.Equal(t, expected, diffs)
+	})
+	t.Run("ignores descriptions and ordering when asked", func(t *testing.T) {
+		left := schema.Table{Columns: []schema.Column{{Name: "a", Type: "STRING", Description: "x"}, {Name: "b", Type: "STRING"}}}
+		right := schema.Table{Columns: []schema.Column{{Name: "b", Type: "STRING"}, {Name: "a", Type: "STRING"}}}
+
+		diffs := schema.Compare(left, right, schema.Options{IgnoreDescriptions: true, IgnoreColumnOrdering: true})
+		assert.Empty(t, diffs)
+	})
+}
@@ -0,0 +1,279 @@
+package sqltoken
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	h "github.com/sbchaos/consume/comb"
+	b "github.com/sbchaos/consume/par"
+	"github.com/sbchaos/consume/par/char"
+	sp "github.com/sbchaos/consume/par/strings"
+	"github.com/sbchaos/consume/stream"
+)
+
+var errEmpty = errors.New("empty match")
+
+// Options select the syntax which differs between the dialects
+type Options struct {
+	// HashComments starts a line comment with #, as in bigquery
+	HashComments bool
+}
+
+// piece is the part of the query read by one parser, raw is the text as consumed. Spaces and
+// comments are skipped, unterminated is set when the query ends before the closing quote.
+type piece struct {
+	tok          Token
+	raw          string
+	skip         bool
+	unterminated string
+}
+
+// Tokenize splits the query into tokens, comments are dropped. Templates of optimus
+// like {{ .DSTART }} and variables like ${var} are kept as a single token.
+func Tokenize(query string, opts Options) ([]Token, error) {
+	pieces, err := b.ParseString(query, piecesParser(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []Token
+	offset, space := 0, false
+	for _, p := range pieces {
+		if p.unterminated != "" {
+			return nil, fmt.Errorf("unterminated %s at offset %d", p.unterminated, offset)
+		}
+		start := offset
+		offset += utf8.RuneCountInString(p.raw)
+		if p.skip {
+			space = true
+			continue
+		}
+
+		p.tok.Space = space
+		p.tok.Start, p.tok.End = start, offset
+		space = false
+		tokens = append(tokens, p.tok)
+	}
+
+	if runes := []rune(query); offset < len(runes) {
+		return nil, fmt.Errorf("unexpected character %q at offset %d", runes[offset], offset)
+	}
+	return tokens, nil
+}
+
+// piecesParser reads pieces till the end of query or the first unterminated piece
+func piecesParser(opts Options) b.Parser[rune, []piece] {
+	one := pieceParser(opts)
+	return func(ss stream.SimpleStream[rune]) ([]piece, error) {
+		var pieces []piece
+		for {
+			p, err := b.Parse(ss, one)
+			if err != nil || p.raw == "" {
+				return pieces, nil
+			}
+
+			pieces = append(pieces, p)
+			if p.unterminated != "" {
+				return pieces, nil
+			}
+		}
+	}
+}
+
+func pieceParser(opts Options) b.Parser[rune, piece] {
+	parsers := []b.Parser[rune, piece]{
+		h.FMap(skipped, runs(unicode.IsSpace)),
+		lineComment("--"),
+		enclosed("/*", "*/", "comment", skipped),
+	}
+	if opts.HashComments {
+		parsers = append(parsers, lineComment("#"))
+	}
+
+	word := wordParser()
+	number := h.And(runs(unicode.IsDigit), optionalRuns(func(r rune) bool {
+		return isWordPart(r) || r == '.'
+	}), concat)
+	parsers = append(parsers,
+		enclosed("{{", "}}", "template", tokenOf(Template)),
+		enclosed("{%", "%}", "template", tokenOf(Template)),
+		enclosed("${", "}", "variable", tokenOf(Template)),
+		enclosed("`", "`", "quoted identifier", quotedName),
+		stringLiteral('\''),
+		stringLiteral('"'),
+		prefixedString(),
+		h.FMap(tokenOf(Number), number),
+		h.FMap(tokenOf(Word), word),
+		h.FMap(tokenOf(Word), h.And(literal("@"), word, concat)),
+		h.FMap(tokenOf(Punct), single()),
+	)
+	return h.Choice(parsers...)
+}
+
+// wordParser reads a name or keyword, $ is kept for the partition decorators of bigquery like
+// table$20240101 while ${ starts a variable
+func wordParser() b.Parser[rune, string] {
+	start := runs(isWordPart)
+	decorator := h.Optional(h.And(literal("$"), runs(isWordPart), concat), "")
+	return func(ss stream.SimpleStream[rune]) (string, error) {
+		w, err := b.Parse(ss, start)
+		if err != nil {
+			return "", err
+		}
+		for {
+			d, _ := b.Parse(ss, decorator)
+			if d == "" {
+				return w, nil
+			}
+			w += d
+		}
+	}
+}
+
+func lineComment(start string) b.Parser[rune, piece] {
+	return h.FMap(skipped, h.And(literal(start), optionalRuns(func(r rune) bool {
+		return r != '\n'
+	}), concat))
+}
+
+// enclosed reads from open to the first close after it, the piece is unterminated
+// when the query ends before close
+func enclosed(open, close, what string, fn func(string) piece) b.Parser[rune, piece] {
+	first := []rune(close)[0]
+	openP := literal(open)
+	body := optionalRuns(func(r rune) bool { return r != first })
+	closeP := literal(close)
+	firstP := char.Single(first)
+
+	return func(ss stream.SimpleStream[rune]) (piece, error) {
+		start, err := b.Parse(ss, openP)
+		if err != nil {
+			return piece{}, err
+		}
+
+		var raw strings.Builder
+		raw.WriteString(start)
+		for {
+			s, _ := b.Parse(ss, body)
+			raw.WriteString(s)
+			if end, err := b.Parse(ss, closeP); err == nil {
+				raw.WriteString(end)
+				return fn(raw.String()), nil
+			}
+			if _, err := b.Parse(ss, firstP); err != nil {
+				p := fn(raw.String())
+				p.unterminated = what
+				return p, nil
+			}
+			raw.WriteRune(first)
+		}
+	}
+}
+
+// stringLiteral reads a string in quote q, triple quoted strings of bigquery are read to the
+// closing triple quote. Quotes are escaped by a backslash or by doubling them.
+func stringLiteral(q rune) b.Parser[rune, piece] {
+	triple := strings.Repeat(string(q), 3)
+	quote := char.Single(q)
+	body := optionalRuns(func(r rune) bool { return r != q && r != '\\' })
+	escape := h.Choice(
+		literal(`\`+string(q)),
+		literal(`\\`),
+		literal(string(q)+string(q)),
+		literal(`\`),
+	)
+
+	quoted := func(ss stream.SimpleStream[rune]) (piece, error) {
+		if _, err := b.Parse(ss, quote); err != nil {
+			return piece{}, err
+		}
+
+		var raw strings.Builder
+		raw.WriteRune(q)
+		for {
+			s, _ := b.Parse(ss, body)
+			raw.WriteString(s)
+			if e, err := b.Parse(ss, escape); err == nil {
+				raw.WriteString(e)
+				continue
+			}
+
+			if _, err := b.Parse(ss, quote); err != nil {
+				p := tokenOf(String)(raw.String())
+				p.unterminated = "string"
+				return p, nil
+			}
+			raw.WriteRune(q)
+			return tokenOf(String)(raw.String()), nil
+		}
+	}
+	return h.Choice(enclosed(triple, triple, "string", tokenOf(String)), quoted)
+}
+
+// prefixedString reads the raw and bytes strings of bigquery like r'\d+' and b"abc"
+func prefixedString() b.Parser[rune, piece] {
+	prefix := h.Choice(char.Single('r'), char.Single('R'), char.Single('b'), char.Single('B'))
+	return h.And(prefix, h.Choice(stringLiteral('\''), stringLiteral('"')), func(r rune, p piece) piece {
+		p.raw = string(r) + p.raw
+		p.tok.Text = p.raw
+		return p
+	})
+}
+
+// runs reads one or more runes accepted by fn
+func runs(fn func(rune) bool) b.Parser[rune, string] {
+	p := sp.CustomString(fn)
+	return func(ss stream.SimpleStream[rune]) (string, error) {
+		s, err := b.Parse(ss, p)
+		if err == nil && s == "" {
+			return "", errEmpty
+		}
+		return s, err
+	}
+}
+
+func optionalRuns(fn func(rune) bool) b.Parser[rune, string] {
+	return h.Optional(sp.CustomString(fn), "")
+}
+
+// single reads any one rune
+func single() b.Parser[rune, string] {
+	return func(ss stream.SimpleStream[rune]) (string, error) {
+		n := 0
+		return b.Parse(ss, sp.CustomString(func(rune) bool {
+			n++
+			return n == 1
+		}))
+	}
+}
+
+func literal(s string) b.Parser[rune, string] {
+	return sp.String(s, sp.Equals)
+}
+
+func concat(x, y string) string {
+	return x + y
+}
+
+func skipped(raw string) piece {
+	return piece{raw: raw, skip: true}
+}
+
+func tokenOf(kind Kind) func(string) piece {
+	return func(raw string) piece {
+		return piece{tok: Token{Kind: kind, Text: raw}, raw: raw}
+	}
+}
+
+func quotedName(raw string) piece {
+	text := strings.TrimPrefix(raw, "`")
+	text = strings.TrimSuffix(text, "`")
+	return piece{tok: Token{Kind: Quoted, Text: text}, raw: raw}
+}
+
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,135 @@
+package sqltoken_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/lib/sqltoken"
+)
+
+func TestTokenize(t *testing.T) {
+	texts := func(tokens []sqltoken.Token) []string {
+		var s []string
+		for _, tok := range tokens {
+			s = append(s, tok.Text)
+		}
+		return s
+	}
+
+	t.Run("splits words, names and punctuation", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("SELECT a.id, 1.5 FROM `p.d.t` WHERE x >= @start", sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"SELECT", "a", ".", "id", ",", "1.5", "FROM", "p.d.t", "WHERE", "x", ">", "=", "@start"}, texts(tokens))
+		assert.Equal(t, sqltoken.Quoted, tokens[7].Kind)
+		assert.Equal(t, sqltoken.Number, tokens[5].Kind)
+		assert.True(t, tokens[1].Space)
+		assert.False(t, tokens[2].Space)
+	})
+	t.Run("drops comments and marks the space", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("a/* x */b -- c\nd", sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "d"}, texts(tokens))
+		assert.True(t, tokens[1].Space)
+	})
+	t.Run("reads hash comments only when enabled", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("a # b", sqltoken.Options{HashComments: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a"}, texts(tokens))
+
+		tokens, err = sqltoken.Tokenize("a # b", sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "#", "b"}, texts(tokens))
+	})
+	t.Run("keeps templates and variables as one token", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("{{ .DSTART }}{% if x %}${var}", sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"{{ .DSTART }}", "{% if x %}", "${var}"}, texts(tokens))
+		assert.Equal(t, sqltoken.Template, tokens[2].Kind)
+	})
+	t.Run("keeps partition decorator in word", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("t$20240101 u${v}", sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"t$20240101", "u", "${v}"}, texts(tokens))
+	})
+	t.Run("reads escaped and triple quoted strings", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize(`'it''s' 'a\'b' """x"y""" ''`, sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`'it''s'`, `'a\'b'`, `"""x"y"""`, `''`}, texts(tokens))
+		for _, tok := range tokens {
+			assert.Equal(t, sqltoken.String, tok.Kind)
+		}
+	})
+	t.Run("reads prefixed strings with the offsets", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize(`é r'\d' b"x" rate`, sqltoken.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"é", `r'\d'`, `b"x"`, "rate"}, texts(tokens))
+		assert.Equal(t, sqltoken.String, tokens[1].Kind)
+		assert.Equal(t, sqltoken.Word, tokens[3].Kind)
+		assert.Equal(t, []int{2, 7}, []int{tokens[1].Start, tokens[1].End})
+		assert.Equal(t, []int{13, 17}, []int{tokens[3].Start, tokens[3].End})
+	})
+	t.Run("returns error for unterminated parts", func(t *testing.T) {
+		_, err := sqltoken.Tokenize("SELECT 'abc", sqltoken.Options{})
+		assert.ErrorContains(t, err, "unterminated string at offset 7")
+
+		_, err = sqltoken.Tokenize("a /* b", sqltoken.Options{})
+		assert.ErrorContains(t, err, "unterminated comment at offset 2")
+
+		_, err = sqltoken.Tokenize("{{ .X", sqltoken.Options{})
+		assert.ErrorContains(t, err, "unterminated template")
+	})
+}
+
+func TestSplitStatements(t *testing.T) {
+	t.Run("splits on semicolons outside parenthesis", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("a (b; c); ; d", sqltoken.Options{})
+		assert.NoError(t, err)
+
+		statements, err := sqltoken.SplitStatements(tokens)
+		assert.NoError(t, err)
+		assert.Len(t, statements, 2)
+		assert.Len(t, statements[0], 6)
+		assert.Equal(t, "d", statements[1][0].Text)
+	})
+	t.Run("returns error for unbalanced parenthesis", func(t *testing.T) {
+		tokens, err := sqltoken.Tokenize("a (b", sqltoken.Options{})
+		assert.NoError(t, err)
+
+		_, err = sqltoken.SplitStatements(tokens)
+		assert.ErrorContains(t, err, "unbalanced parenthesis")
+	})
+}
+
+func TestMatching(t *testing.T) {
+	tokens, err := sqltoken.Tokenize("f((a), b) c", sqltoken.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, sqltoken.Matching(tokens, 1))
+	assert.Equal(t, 4, sqltoken.Matching(tokens, 2))
+	assert.Equal(t, -1, sqltoken.Matching(tokens[:5], 1))
+
+	tokens, err = sqltoken.Tokenize("ARRAY<STRUCT<a INT64>> [1]", sqltoken.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, sqltoken.Matching(tokens, 1))
+	assert.Equal(t, 10, sqltoken.Matching(tokens, 8))
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		name     string
+		literal  string
+		expected string
+	}{
+		{name: "escapes", literal: `'a\'b\n'`, expected: "a'b\n"},
+		{name: "doubled quote", literal: `'it''s'`, expected: "it's"},
+		{name: "triple quoted", literal: `"""say "hi" """`, expected: `say "hi" `},
+		{name: "raw", literal: `r'\d+'`, expected: `\d+`},
+		{name: "bytes", literal: `b"a\tb"`, expected: "a\tb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sqltoken.Unquote(tt.literal))
+		})
+	}
+}
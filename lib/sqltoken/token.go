@@ -0,0 +1,155 @@
+// Package sqltoken splits sql queries into tokens with the parser combinators of consume,
+// the tokens are shared by the packages which read queries without a full sql grammar.
+package sqltoken
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Word Kind = iota
+	Quoted
+	String
+	Number
+	Template
+	Punct
+)
+
+// Token of a query, Space is set when the token is preceded by whitespace or a comment.
+// Start and End are the rune offsets in the query, used to copy the source of a part.
+type Token struct {
+	Kind  Kind
+	Text  string
+	Space bool
+	Start int
+	End   int
+}
+
+// Is compares a word with s ignoring case
+func (t Token) Is(s string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, s)
+}
+
+func (t Token) IsPunct(s string) bool {
+	return t.Kind == Punct && t.Text == s
+}
+
+// IsNamePart is true for the tokens which can be a part of a table or column name
+func (t Token) IsNamePart() bool {
+	return t.Kind == Word || t.Kind == Quoted || t.Kind == Template
+}
+
+// Matching returns the index of the token closing the one at open, for ( ), < > or [ ]
+func Matching(tokens []Token, open int) int {
+	o := tokens[open].Text
+	c := map[string]string{"(": ")", "<": ">", "[": "]"}[o]
+
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch {
+		case tokens[i].IsPunct(o):
+			depth++
+		case tokens[i].IsPunct(c):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// SplitStatements splits on the semicolons outside parenthesis, empty statements are dropped
+func SplitStatements(tokens []Token) ([][]Token, error) {
+	var statements [][]Token
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parenthesis near %s", near(tokens, i))
+			}
+		case t.IsPunct(";") && depth == 0:
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parenthesis near %s", near(tokens, len(tokens)-1))
+	}
+	if start < len(tokens) {
+		statements = append(statements, tokens[start:])
+	}
+	return statements, nil
+}
+
+// Unquote returns the value of a string literal, raw strings of bigquery with the r prefix
+// keep the backslashes
+func Unquote(s string) string {
+	raw := false
+	if len(s) > 0 && strings.ContainsRune("rRbB", rune(s[0])) {
+		raw = s[0] == 'r' || s[0] == 'R'
+		s = s[1:]
+	}
+
+	q := ""
+	if len(s) >= 6 && (strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''")) {
+		s = s[3 : len(s)-3]
+	} else if len(s) >= 2 {
+		q = s[:1]
+		s = s[1 : len(s)-1]
+	}
+	if raw {
+		return s
+	}
+
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if q != "" && strings.HasPrefix(s[i:], q+q) {
+			b.WriteString(q)
+			i++
+			continue
+		}
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func near(tokens []Token, i int) string {
+	from := i - 3
+	if from < 0 {
+		from = 0
+	}
+	to := i + 3
+	if to > len(tokens) {
+		to = len(tokens)
+	}
+
+	parts := make([]string, 0, to-from)
+	for _, t := range tokens[from:to] {
+		parts = append(parts, t.Text)
+	}
+	return fmt.Sprintf("%q", strings.Join(parts, " "))
+}
@@ -432,3 +432,39 @@ func TestIndent(t *testing.T) {
 		})
 	}
 }
+
+func TestHumanBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1024:                   "1.0 KiB",
+		1536:                   "1.5 KiB",
+		5 * 1024 * 1024:        "5.0 MiB",
+		3 * 1024 * 1024 * 1024: "3.0 GiB",
+	}
+	for size, expected := range cases {
+		assert.Equal(t, expected, HumanBytes(size))
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	cases := map[string]int64{
+		"0":      0,
+		"512":    512,
+		"512B":   512,
+		"10KB":   10 * 1024,
+		"1.5GiB": 1536 * 1024 * 1024,
+		"2t":     2 * 1024 * 1024 * 1024 * 1024,
+		" 3 MB ": 3 * 1024 * 1024,
+	}
+	for s, expected := range cases {
+		size, err := ParseBytes(s)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, size, s)
+	}
+
+	for _, s := range []string{"", "GB", "ten", "-1KB"} {
+		_, err := ParseBytes(s)
+		assert.Error(t, err, s)
+	}
+}
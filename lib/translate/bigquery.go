@@ -0,0 +1,514 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sbchaos/opms/lib/sqltoken"
+)
+
+// Type of a column, Elem is set for ARRAY and Fields for STRUCT
+type Type struct {
+	Name   string
+	Params []string
+	Elem   *Type
+	Fields []Column
+}
+
+// String returns the type in the form used by bigquery, eg ARRAY<STRUCT<a INT64>>
+func (t Type) String() string {
+	switch {
+	case t.Elem != nil:
+		return t.Name + "<" + t.Elem.String() + ">"
+	case t.Fields != nil:
+		fields := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = f.Name + " " + f.Type.String()
+		}
+		return t.Name + "<" + strings.Join(fields, ", ") + ">"
+	case len(t.Params) > 0:
+		return t.Name + "(" + strings.Join(t.Params, ",") + ")"
+	}
+	return t.Name
+}
+
+type Column struct {
+	Name        string
+	Type        Type
+	NotNull     bool
+	Description string
+}
+
+// Partition of a table, Column is empty for the tables partitioned by ingestion time
+type Partition struct {
+	Column     string
+	Expression string
+	Range      bool
+}
+
+// Table is a CREATE TABLE or CREATE VIEW statement, Query is set for views
+type Table struct {
+	Name         string
+	View         bool
+	Materialized bool
+	Columns      []Column
+	Partition    *Partition
+	ClusterBy    []string
+	Options      map[string]string
+	Query        string
+}
+
+// Description returns the description of the table from options
+func (t Table) Description() string {
+	return t.Options["description"]
+}
+
+// ParseBigQuery parses the CREATE TABLE and CREATE VIEW statements of bigquery, other statements
+// like external tables and table functions return an error
+func ParseBigQuery(content string) ([]Table, error) {
+	tokens, err := sqltoken.Tokenize(content, sqltoken.Options{HashComments: true})
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := sqltoken.SplitStatements(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(content)
+	var tables []Table
+	for _, stmt := range statements {
+		p := &parser{tokens: stmt, source: runes}
+		t, err := p.parseCreate()
+		if err != nil {
+			return tables, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+type parser struct {
+	tokens []sqltoken.Token
+	source []rune
+	pos    int
+}
+
+func (p *parser) peek() sqltoken.Token {
+	if p.pos >= len(p.tokens) {
+		return sqltoken.Token{Kind: sqltoken.Punct}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// accept consumes the words when all of them are next
+func (p *parser) accept(words ...string) bool {
+	for i, w := range words {
+		if p.pos+i >= len(p.tokens) || !p.tokens[p.pos+i].Is(w) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *parser) acceptPunct(s string) bool {
+	if p.peek().IsPunct(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectPunct(s string) error {
+	if !p.acceptPunct(s) {
+		return p.errorf("expected %s", s)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	near := "end of statement"
+	if !p.done() {
+		near = fmt.Sprintf("%q", p.peek().Text)
+	}
+	return fmt.Errorf(format+" near %s", append(args, near)...)
+}
+
+func (p *parser) parseCreate() (Table, error) {
+	if !p.accept("CREATE") {
+		return Table{}, p.errorf("expected CREATE")
+	}
+	p.accept("OR", "REPLACE")
+	_ = p.accept("TEMP") || p.accept("TEMPORARY")
+
+	t := Table{Options: map[string]string{}}
+	switch {
+	case p.accept("TABLE"):
+	case p.accept("VIEW"):
+		t.View = true
+	case p.accept("MATERIALIZED", "VIEW"):
+		t.View, t.Materialized = true, true
+	case p.accept("EXTERNAL", "TABLE"):
+		return t, errors.New("external tables are not supported, use optimus generate external-table")
+	default:
+		return t, p.errorf("expected TABLE or VIEW")
+	}
+	p.accept("IF", "NOT", "EXISTS")
+
+	name, err := p.parseName()
+	if err != nil {
+		return t, err
+	}
+	t.Name = name
+
+	if p.peek().IsPunct("(") {
+		if t.View {
+			t.Columns, err = p.parseViewColumns()
+		} else {
+			t.Columns, err = p.parseColumns()
+		}
+		if err != nil {
+			return t, fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+
+	err = p.parseClauses(&t)
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	if !t.View && len(t.Columns) == 0 {
+		return t, fmt.Errorf("%s: table without column list is not supported", t.Name)
+	}
+	return t, nil
+}
+
+// parseName reads a dotted name, unquoted project names can have dashes and quoted
+// parts can have dots, eg `my-proj.dataset`.table
+func (p *parser) parseName() (string, error) {
+	if !p.peek().IsNamePart() {
+		return "", p.errorf("expected name")
+	}
+
+	var b strings.Builder
+	prevEnd := -1
+	for !p.done() {
+		t := p.peek()
+		adjacent := prevEnd < 0 || t.Start == prevEnd
+		switch {
+		case t.IsPunct("."):
+		case t.IsNamePart() || t.Kind == sqltoken.Number:
+			if !adjacent && !p.tokens[p.pos-1].IsPunct(".") {
+				return b.String(), nil
+			}
+		case t.IsPunct("-") && adjacent:
+		default:
+			return b.String(), nil
+		}
+
+		b.WriteString(t.Text)
+		prevEnd = t.End
+		p.pos++
+	}
+	return b.String(), nil
+}
+
+// parseColumns reads the column definitions, constraints like PRIMARY KEY are skipped
+func (p *parser) parseColumns() ([]Column, error) {
+	_ = p.expectPunct("(")
+
+	var columns []Column
+	for {
+		if p.peek().Is("PRIMARY") || p.peek().Is("FOREIGN") || p.peek().Is("CONSTRAINT") {
+			p.skipUntilComma()
+		} else {
+			col, err := p.parseColumn()
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, col)
+		}
+
+		if p.acceptPunct(",") {
+			continue
+		}
+		return columns, p.expectPunct(")")
+	}
+}
+
+// parseColumn reads name type [NOT NULL] [DEFAULT expr] [OPTIONS(...)], name and type can be
+// separated by a colon in the fields of struct
+func (p *parser) parseColumn() (Column, error) {
+	if !p.peek().IsNamePart() {
+		return Column{}, p.errorf("expected column name")
+	}
+	col := Column{Name: p.peek().Text}
+	p.pos++
+	p.acceptPunct(":")
+
+	typ, err := p.parseType()
+	if err != nil {
+		return col, fmt.Errorf("column %s: %w", col.Name, err)
+	}
+	col.Type = typ
+
+	for {
+		switch {
+		case p.accept("NOT", "NULL"):
+			col.NotNull = true
+		case p.accept("COLLATE"):
+			p.pos++
+		case p.accept("DEFAULT"):
+			p.skipExpression()
+		case p.peek().Is("OPTIONS"):
+			opts, err := p.parseOptions()
+			if err != nil {
+				return col, fmt.Errorf("column %s: %w", col.Name, err)
+			}
+			col.Description = opts["description"]
+		default:
+			return col, nil
+		}
+	}
+}
+
+// parseType reads a type with the parameters and nested types of ARRAY and STRUCT
+func (p *parser) parseType() (Type, error) {
+	if p.peek().Kind != sqltoken.Word {
+		return Type{}, p.errorf("expected type")
+	}
+	typ := Type{Name: strings.ToUpper(p.peek().Text)}
+	p.pos++
+
+	switch typ.Name {
+	case "ARRAY":
+		if err := p.expectPunct("<"); err != nil {
+			return typ, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return typ, err
+		}
+		typ.Elem = &elem
+		return typ, p.expectPunct(">")
+
+	case "STRUCT":
+		if err := p.expectPunct("<"); err != nil {
+			return typ, err
+		}
+		typ.Fields = []Column{}
+		for {
+			field, err := p.parseColumn()
+			if err != nil {
+				return typ, err
+			}
+			typ.Fields = append(typ.Fields, field)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		return typ, p.expectPunct(">")
+	}
+
+	if p.acceptPunct("(") {
+		for !p.done() && !p.peek().IsPunct(")") {
+			if !p.peek().IsPunct(",") {
+				typ.Params = append(typ.Params, p.peek().Text)
+			}
+			p.pos++
+		}
+		return typ, p.expectPunct(")")
+	}
+	return typ, nil
+}
+
+// parseViewColumns reads the column names of a view with their options
+func (p *parser) parseViewColumns() ([]Column, error) {
+	_ = p.expectPunct("(")
+
+	var columns []Column
+	for {
+		if !p.peek().IsNamePart() {
+			return nil, p.errorf("expected column name")
+		}
+		col := Column{Name: p.peek().Text}
+		p.pos++
+
+		if p.peek().Is("OPTIONS") {
+			opts, err := p.parseOptions()
+			if err != nil {
+				return nil, err
+			}
+			col.Description = opts["description"]
+		}
+		columns = append(columns, col)
+
+		if p.acceptPunct(",") {
+			continue
+		}
+		return columns, p.expectPunct(")")
+	}
+}
+
+func (p *parser) parseClauses(t *Table) error {
+	for !p.done() {
+		switch {
+		case p.accept("DEFAULT", "COLLATE"):
+			p.pos++
+
+		case p.accept("PARTITION", "BY"):
+			start := p.pos
+			p.skipClause()
+			t.Partition = partitionOf(p.tokens[start:p.pos], p.text(start, p.pos))
+
+		case p.accept("CLUSTER", "BY"):
+			for {
+				name, err := p.parseName()
+				if err != nil {
+					return err
+				}
+				t.ClusterBy = append(t.ClusterBy, name)
+				if !p.acceptPunct(",") {
+					break
+				}
+			}
+
+		case p.peek().Is("OPTIONS"):
+			opts, err := p.parseOptions()
+			if err != nil {
+				return err
+			}
+			for k, v := range opts {
+				t.Options[k] = v
+			}
+
+		case p.accept("AS"):
+			if t.View {
+				t.Query = strings.TrimSpace(p.text(p.pos, len(p.tokens)))
+			} else if len(t.Columns) == 0 {
+				return errors.New("CREATE TABLE AS SELECT without column list is not supported")
+			}
+			p.pos = len(p.tokens)
+
+		default:
+			return p.errorf("unexpected clause")
+		}
+	}
+	return nil
+}
+
+// parseOptions reads OPTIONS(key=value, ...), string values are unquoted and other
+// values like arrays and expressions are kept as written
+func (p *parser) parseOptions() (map[string]string, error) {
+	p.accept("OPTIONS")
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+
+	opts := map[string]string{}
+	for !p.acceptPunct(")") {
+		if p.peek().Kind != sqltoken.Word {
+			return nil, p.errorf("expected option name")
+		}
+		key := strings.ToLower(p.peek().Text)
+		p.pos++
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+
+		start := p.pos
+		p.skipExpression()
+		if p.pos == start+1 && p.tokens[start].Kind == sqltoken.String {
+			opts[key] = sqltoken.Unquote(p.tokens[start].Text)
+		} else {
+			opts[key] = p.text(start, p.pos)
+		}
+		p.acceptPunct(",")
+	}
+	return opts, nil
+}
+
+// skipExpression moves to the comma or closing parenthesis after the expression
+func (p *parser) skipExpression() {
+	for !p.done() {
+		t := p.peek()
+		switch {
+		case t.IsPunct("(") || t.IsPunct("["):
+			p.pos = sqltoken.Matching(p.tokens, p.pos) + 1
+			if p.pos == 0 {
+				p.pos = len(p.tokens)
+			}
+			continue
+		case t.IsPunct(",") || t.IsPunct(")") || t.IsPunct(">"):
+			return
+		case t.Is("OPTIONS") || t.Is("NOT") && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Is("NULL"):
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) skipUntilComma() {
+	for !p.done() && !p.peek().IsPunct(",") && !p.peek().IsPunct(")") {
+		if p.peek().IsPunct("(") {
+			p.pos = sqltoken.Matching(p.tokens, p.pos)
+		}
+		p.pos++
+	}
+}
+
+// skipClause moves to the start of the next clause of the table
+func (p *parser) skipClause() {
+	for !p.done() {
+		t := p.peek()
+		if t.Is("CLUSTER") || t.Is("OPTIONS") || t.Is("AS") {
+			return
+		}
+		if t.IsPunct("(") {
+			p.pos = sqltoken.Matching(p.tokens, p.pos)
+		}
+		p.pos++
+	}
+}
+
+// text returns the source between the tokens from and to
+func (p *parser) text(from, to int) string {
+	if from >= to || from >= len(p.tokens) {
+		return ""
+	}
+	return string(p.source[p.tokens[from].Start:p.tokens[to-1].End])
+}
+
+var partitionFunctions = map[string]bool{
+	"DATE": true, "TIMESTAMP_TRUNC": true, "DATETIME_TRUNC": true, "DATE_TRUNC": true,
+	"RANGE_BUCKET": true, "GENERATE_ARRAY": true,
+}
+
+// partitionOf finds the column in a partition expression like DATE(ts) or RANGE_BUCKET(id, ...)
+func partitionOf(tokens []sqltoken.Token, expr string) *Partition {
+	part := &Partition{Expression: expr}
+	for i, t := range tokens {
+		if t.Is("RANGE_BUCKET") {
+			part.Range = true
+		}
+		if !t.IsNamePart() || i+1 < len(tokens) && tokens[i+1].IsPunct("(") {
+			continue
+		}
+		if t.Kind == sqltoken.Word && partitionFunctions[strings.ToUpper(t.Text)] {
+			continue
+		}
+		if strings.HasPrefix(strings.ToUpper(t.Text), "_PARTITION") {
+			return part
+		}
+		part.Column = t.Text
+		return part
+	}
+	return part
+}
@@ -0,0 +1,393 @@
+package translate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sbchaos/opms/lib/names"
+	"github.com/sbchaos/opms/lib/schema"
+	"github.com/sbchaos/opms/lib/sqltoken"
+)
+
+const maxDecimalPrecision = 38
+
+// DefaultTypeMapping maps the types of bigquery to maxcompute, mappings from a type-map json
+// are applied over these
+var DefaultTypeMapping = map[string]string{
+	"INT64":      "BIGINT",
+	"INTEGER":    "BIGINT",
+	"INT":        "BIGINT",
+	"SMALLINT":   "BIGINT",
+	"BIGINT":     "BIGINT",
+	"TINYINT":    "BIGINT",
+	"BYTEINT":    "BIGINT",
+	"FLOAT64":    "DOUBLE",
+	"FLOAT":      "DOUBLE",
+	"BOOL":       "BOOLEAN",
+	"BOOLEAN":    "BOOLEAN",
+	"NUMERIC":    "DECIMAL(38,9)",
+	"DECIMAL":    "DECIMAL(38,9)",
+	"BIGNUMERIC": "DECIMAL(38,18)",
+	"BIGDECIMAL": "DECIMAL(38,18)",
+	"STRING":     "STRING",
+	"BYTES":      "BINARY",
+	"DATE":       "DATE",
+	"DATETIME":   "DATETIME",
+	"TIMESTAMP":  "TIMESTAMP",
+	"TIME":       "STRING",
+	"JSON":       "JSON",
+	"GEOGRAPHY":  "STRING",
+	"INTERVAL":   "STRING",
+}
+
+// WithDefaultTypes returns the default type mapping with the mapping applied over it
+func WithDefaultTypes(mapping map[string]string) map[string]string {
+	merged := make(map[string]string, len(DefaultTypeMapping)+len(mapping))
+	for k, v := range DefaultTypeMapping {
+		merged[k] = v
+	}
+	for k, v := range mapping {
+		merged[strings.ToUpper(k)] = v
+	}
+	return merged
+}
+
+type Options struct {
+	// TypeMapping of bigquery to maxcompute types, keys are upper case. DefaultTypeMapping
+	// is used when not set
+	TypeMapping map[string]string
+	// ProjectMapping is applied on names with names.MapName
+	ProjectMapping map[string]string
+	// Lifecycle in days, partition_expiration_days of table is used when not set
+	Lifecycle int
+	// PartitionColumn is the name of the partition for tables partitioned by ingestion time
+	PartitionColumn string
+}
+
+// ToMaxCompute generates the maxcompute create statement for the table. Partition column of
+// bigquery is moved to PARTITIONED BY with a STRING type for dates, as used by the daily jobs.
+func ToMaxCompute(t Table, opts Options) (string, error) {
+	tab, err := names.MapName(opts.ProjectMapping, t.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.TypeMapping == nil {
+		opts.TypeMapping = DefaultTypeMapping
+	}
+
+	if t.View {
+		ddl, err := viewDDL(tab.String(), t, opts)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", t.Name, err)
+		}
+		return ddl, nil
+	}
+
+	columns, partition, err := splitPartition(t, opts)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.Name, err)
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("CREATE TABLE IF NOT EXISTS " + tab.String() + " (\n")
+	writeColumns(b, columns, opts.TypeMapping, true)
+	b.WriteString(")")
+
+	if desc := t.Description(); desc != "" {
+		b.WriteString("\nCOMMENT " + Quote(desc))
+	}
+
+	if partition != nil {
+		b.WriteString("\nPARTITIONED BY (\n")
+		writeColumns(b, []Column{*partition}, opts.TypeMapping, false)
+		b.WriteString(")")
+	}
+
+	// partition columns can not be used for clustering in maxcompute
+	var cluster []string
+	for _, c := range t.ClusterBy {
+		if partition == nil || !strings.EqualFold(c, partition.Name) {
+			cluster = append(cluster, c)
+		}
+	}
+	if len(cluster) > 0 {
+		cols := "`" + strings.Join(cluster, "`, `") + "`"
+		b.WriteString("\nRANGE CLUSTERED BY (" + cols + ") SORTED BY (" + cols + ")")
+	}
+
+	lifecycle := opts.Lifecycle
+	if lifecycle == 0 {
+		days, err := strconv.ParseFloat(t.Options["partition_expiration_days"], 64)
+		if err == nil && days >= 1 {
+			lifecycle = int(days)
+		}
+	}
+	if lifecycle > 0 {
+		b.WriteString(fmt.Sprintf("\nLIFECYCLE %d", lifecycle))
+	}
+	b.WriteString(";\n")
+	return b.String(), nil
+}
+
+// splitPartition removes the partition column from columns, range partitions keep the type
+// of column and time partitions are kept as date string like 2024-01-01
+func splitPartition(t Table, opts Options) ([]Column, *Column, error) {
+	if t.Partition == nil {
+		return t.Columns, nil, nil
+	}
+
+	if t.Partition.Column == "" {
+		name := opts.PartitionColumn
+		if name == "" {
+			name = "dt"
+		}
+		part := &Column{
+			Name:        name,
+			Type:        Type{Name: "STRING"},
+			Description: "partition of ingestion time " + t.Partition.Expression,
+		}
+		return t.Columns, part, nil
+	}
+
+	var columns []Column
+	var part *Column
+	for _, c := range t.Columns {
+		if !strings.EqualFold(c.Name, t.Partition.Column) {
+			columns = append(columns, c)
+			continue
+		}
+
+		p := c
+		p.NotNull = false
+		if !t.Partition.Range {
+			p.Type = Type{Name: "STRING"}
+		}
+		part = &p
+	}
+
+	if part == nil {
+		return nil, nil, fmt.Errorf("partition column %s not found in columns", t.Partition.Column)
+	}
+	return columns, part, nil
+}
+
+func viewDDL(name string, t Table, opts Options) (string, error) {
+	query, err := mapQueryTables(strings.TrimSuffix(strings.TrimSpace(t.Query), ";"), opts.ProjectMapping)
+	if err != nil {
+		return "", err
+	}
+
+	b := &strings.Builder{}
+	if t.Materialized {
+		b.WriteString("CREATE MATERIALIZED VIEW IF NOT EXISTS ")
+	} else {
+		b.WriteString("CREATE VIEW IF NOT EXISTS ")
+	}
+	b.WriteString(name)
+
+	if len(t.Columns) > 0 {
+		b.WriteString(" (\n")
+		for i, c := range t.Columns {
+			b.WriteString("  `" + c.Name + "`")
+			if c.Description != "" {
+				b.WriteString(" COMMENT " + Quote(c.Description))
+			}
+			if i < len(t.Columns)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(")")
+	}
+
+	if desc := t.Description(); desc != "" {
+		b.WriteString("\nCOMMENT " + Quote(desc))
+	}
+	if t.Materialized && opts.Lifecycle > 0 {
+		b.WriteString(fmt.Sprintf("\nLIFECYCLE %d", opts.Lifecycle))
+	}
+
+	// only the tables of query are mapped, functions of bigquery need to be translated by hand
+	b.WriteString("\nAS\n")
+	b.WriteString(query)
+	b.WriteString(";\n")
+	return b.String(), nil
+}
+
+// mapQueryTables replaces the tables after FROM and JOIN with the maxcompute names of names.MapName.
+// Names with one part are kept as they refer to a CTE, names without a project are returned as
+// error as the project can not be mapped.
+func mapQueryTables(query string, projMap map[string]string) (string, error) {
+	tokens, err := sqltoken.Tokenize(query, sqltoken.Options{HashComments: true})
+	if err != nil {
+		return "", err
+	}
+
+	source := []rune(query)
+	b := &strings.Builder{}
+	last := 0
+	// owners are the function names of the open parenthesis, FROM of EXTRACT(DAY FROM dt) is not a table
+	var owners []string
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsPunct("("):
+			owner := ""
+			if i > 0 && tokens[i-1].Kind == sqltoken.Word {
+				owner = strings.ToUpper(tokens[i-1].Text)
+			}
+			owners = append(owners, owner)
+			continue
+		case t.IsPunct(")"):
+			if len(owners) > 0 {
+				owners = owners[:len(owners)-1]
+			}
+			continue
+		case t.Is("JOIN"):
+		case t.Is("FROM"):
+			if i > 0 && tokens[i-1].Is("DISTINCT") || len(owners) > 0 && owners[len(owners)-1] == "EXTRACT" {
+				continue
+			}
+		default:
+			continue
+		}
+
+		// tables of a FROM list after the first can be paths of arrays, like FROM t, t.items
+		for j, first := i+1, true; ; first = false {
+			name, next := tableName(tokens, j)
+			if name == "" || next < len(tokens) && tokens[next].IsPunct("(") {
+				break
+			}
+
+			parts := strings.Count(name, ".") + 1
+			if parts == 2 && first {
+				return "", fmt.Errorf("table %s of query has no project", name)
+			}
+			if parts == 3 {
+				tab, err := names.MapName(projMap, name)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(string(source[last:tokens[j].Start]))
+				b.WriteString(tab.String())
+				last = tokens[next-1].End
+			}
+			i = next - 1
+
+			next = skipAlias(tokens, next)
+			if next >= len(tokens) || !tokens[next].IsPunct(",") {
+				break
+			}
+			j = next + 1
+		}
+	}
+
+	b.WriteString(string(source[last:]))
+	return b.String(), nil
+}
+
+// skipAlias skips an optional [AS] alias at i
+func skipAlias(tokens []sqltoken.Token, i int) int {
+	if i < len(tokens) && tokens[i].Is("AS") {
+		i++
+	}
+	if i < len(tokens) && (tokens[i].Kind == sqltoken.Word || tokens[i].Kind == sqltoken.Quoted) && !isClause(tokens[i]) {
+		i++
+	}
+	return i
+}
+
+func isClause(t sqltoken.Token) bool {
+	for _, w := range []string{"WHERE", "GROUP", "ORDER", "HAVING", "LIMIT", "QUALIFY", "WINDOW", "UNION", "INTERSECT",
+		"EXCEPT", "JOIN", "INNER", "LEFT", "RIGHT", "FULL", "CROSS", "ON", "USING"} {
+		if t.Is(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableName reads a dotted name at i without quotes, unquoted project names can have dashes
+// and quoted parts can have dots, eg `my-proj.dataset`.table
+func tableName(tokens []sqltoken.Token, i int) (string, int) {
+	if i >= len(tokens) || !tokens[i].IsNamePart() {
+		return "", i
+	}
+
+	var b strings.Builder
+	for j := i; j < len(tokens); j++ {
+		t := tokens[j]
+		adjacent := j == i || t.Start == tokens[j-1].End
+		switch {
+		case t.IsPunct("."):
+		case t.IsNamePart() || t.Kind == sqltoken.Number:
+			if !adjacent && !tokens[j-1].IsPunct(".") {
+				return b.String(), j
+			}
+		case t.IsPunct("-") && adjacent:
+		default:
+			return b.String(), j
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String(), len(tokens)
+}
+
+func writeColumns(b *strings.Builder, cols []Column, mapping map[string]string, withNullable bool) {
+	for i, c := range cols {
+		b.WriteString("  `" + c.Name + "` " + MapType(c.Type, mapping))
+		if withNullable && c.NotNull {
+			b.WriteString(" NOT NULL")
+		}
+		if c.Description != "" {
+			b.WriteString(" COMMENT " + Quote(c.Description))
+		}
+		if i < len(cols)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+}
+
+// MapType returns the maxcompute type for the type of bigquery, nested types of ARRAY and STRUCT
+// are mapped with schema.MapType. Precision of numerics is kept, up to the maximum of maxcompute.
+func MapType(t Type, mapping map[string]string) string {
+	switch {
+	case t.Elem != nil:
+		return "ARRAY<" + MapType(*t.Elem, mapping) + ">"
+	case t.Fields != nil:
+		fields := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = f.Name + ":" + MapType(f.Type, mapping)
+			if f.Description != "" {
+				fields[i] += " COMMENT " + Quote(f.Description)
+			}
+		}
+		return "STRUCT<" + strings.Join(fields, ", ") + ">"
+	}
+
+	mapped := schema.MapType(t.Name, mapping)
+	base, _, _ := strings.Cut(mapped, "(")
+	if len(t.Params) == 0 || !strings.EqualFold(base, "DECIMAL") {
+		return mapped
+	}
+
+	params := append([]string{}, t.Params...)
+	if p, err := strconv.Atoi(params[0]); err == nil && p > maxDecimalPrecision {
+		params[0] = strconv.Itoa(maxDecimalPrecision)
+		if len(params) > 1 {
+			if s, err := strconv.Atoi(params[1]); err == nil && s > maxDecimalPrecision/2 {
+				params[1] = strconv.Itoa(maxDecimalPrecision / 2)
+			}
+		}
+	}
+	return base + "(" + strings.Join(params, ",") + ")"
+}
+
+// Quote returns the value as a sql string literal of maxcompute
+func Quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
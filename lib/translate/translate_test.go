@@ -0,0 +1,172 @@
+package translate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sbchaos/opms/lib/translate"
+)
+
+func TestParseBigQuery(t *testing.T) {
+	t.Run("parses table with nested types, partition, cluster and options", func(t *testing.T) {
+		ddl := "CREATE TABLE `data-proj.sales.orders`\n" + `(
+  id INT64 NOT NULL OPTIONS(description="order id"),
+  amount NUMERIC(12, 2),
+  items ARRAY<STRUCT<sku STRING, qty INT64 OPTIONS(description='it\'s quantity')>>,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP(),
+  PRIMARY KEY (id) NOT ENFORCED
+)
+PARTITION BY DATE(created_at)
+CLUSTER BY id, amount
+OPTIONS(
+  description="""orders of "shop" """,
+  partition_expiration_days=30.0,
+  labels=[("team", "sales")]
+);`
+
+		tables, err := translate.ParseBigQuery(ddl)
+		assert.NoError(t, err)
+		assert.Len(t, tables, 1)
+
+		tab := tables[0]
+		assert.Equal(t, "data-proj.sales.orders", tab.Name)
+		assert.Len(t, tab.Columns, 4)
+		assert.Equal(t, translate.Column{Name: "id", Type: translate.Type{Name: "INT64"}, NotNull: true, Description: "order id"}, tab.Columns[0])
+		assert.Equal(t, "NUMERIC(12,2)", tab.Columns[1].Type.String())
+		assert.Equal(t, "ARRAY<STRUCT<sku STRING, qty INT64>>", tab.Columns[2].Type.String())
+		assert.Equal(t, "it's quantity", tab.Columns[2].Type.Elem.Fields[1].Description)
+		assert.Equal(t, &translate.Partition{Column: "created_at", Expression: "DATE(created_at)"}, tab.Partition)
+		assert.Equal(t, []string{"id", "amount"}, tab.ClusterBy)
+		assert.Equal(t, `orders of "shop" `, tab.Description())
+		assert.Equal(t, "30.0", tab.Options["partition_expiration_days"])
+		assert.Equal(t, `[("team", "sales")]`, tab.Options["labels"])
+	})
+
+	t.Run("reads doubled quotes and raw strings of options", func(t *testing.T) {
+		ddl := `CREATE TABLE ds.t (a STRING OPTIONS(description='it''s a')) OPTIONS(description=r"\d+; done")`
+
+		tables, err := translate.ParseBigQuery(ddl)
+		assert.NoError(t, err)
+		assert.Len(t, tables, 1)
+		assert.Equal(t, "it's a", tables[0].Columns[0].Description)
+		assert.Equal(t, `\d+; done`, tables[0].Description())
+	})
+
+	t.Run("parses views and ingestion time partitions", func(t *testing.T) {
+		ddl := `CREATE OR REPLACE VIEW proj.ds.v (a OPTIONS(description="col a"), b)
+OPTIONS(description="view")
+AS SELECT x AS a, y AS b FROM proj.ds.t WHERE z > 0;
+CREATE TABLE IF NOT EXISTS proj.ds.events (name STRING) PARTITION BY _PARTITIONDATE`
+
+		tables, err := translate.ParseBigQuery(ddl)
+		assert.NoError(t, err)
+		assert.Len(t, tables, 2)
+
+		assert.True(t, tables[0].View)
+		assert.Equal(t, []translate.Column{{Name: "a", Description: "col a"}, {Name: "b"}}, tables[0].Columns)
+		assert.Equal(t, "SELECT x AS a, y AS b FROM proj.ds.t WHERE z > 0", tables[0].Query)
+
+		assert.Equal(t, "proj.ds.events", tables[1].Name)
+		assert.Equal(t, &translate.Partition{Expression: "_PARTITIONDATE"}, tables[1].Partition)
+	})
+
+	t.Run("returns error for unsupported statements", func(t *testing.T) {
+		_, err := translate.ParseBigQuery("CREATE EXTERNAL TABLE p.d.t OPTIONS(format='GOOGLE_SHEETS')")
+		assert.ErrorContains(t, err, "external tables are not supported")
+
+		_, err = translate.ParseBigQuery("CREATE TABLE p.d.t AS SELECT 1 AS a")
+		assert.ErrorContains(t, err, "without column list is not supported")
+
+		_, err = translate.ParseBigQuery("CREATE TABLE p.d.t (a ARRAY<INT64)")
+		assert.ErrorContains(t, err, "expected >")
+	})
+}
+
+func TestToMaxCompute(t *testing.T) {
+	t.Run("generates table with partition, cluster and lifecycle", func(t *testing.T) {
+		tables, err := translate.ParseBigQuery(`CREATE TABLE bq-proj.sales.orders (
+  id INT64 NOT NULL OPTIONS(description="order id"),
+  amount BIGNUMERIC(50, 30),
+  items ARRAY<STRUCT<sku STRING, qty INT64>>,
+  created_at TIMESTAMP
+)
+PARTITION BY DATE(created_at)
+CLUSTER BY id, created_at
+OPTIONS(description="it's orders", partition_expiration_days=90)`)
+		assert.NoError(t, err)
+
+		ddl, err := translate.ToMaxCompute(tables[0], translate.Options{
+			TypeMapping:    translate.WithDefaultTypes(map[string]string{"int64": "INT"}),
+			ProjectMapping: map[string]string{"bq-proj": "mc_proj"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE TABLE IF NOT EXISTS mc_proj.sales.orders (\n"+
+			"  `id` INT NOT NULL COMMENT 'order id',\n"+
+			"  `amount` DECIMAL(38,19),\n"+
+			"  `items` ARRAY<STRUCT<sku:STRING, qty:INT>>\n"+
+			")\n"+
+			"COMMENT 'it\\'s orders'\n"+
+			"PARTITIONED BY (\n"+
+			"  `created_at` STRING\n"+
+			")\n"+
+			"RANGE CLUSTERED BY (`id`) SORTED BY (`id`)\n"+
+			"LIFECYCLE 90;\n", ddl)
+	})
+
+	t.Run("adds partition column for ingestion time and uses lifecycle of options", func(t *testing.T) {
+		tables, err := translate.ParseBigQuery(`CREATE TABLE p.d.events (name STRING) PARTITION BY DATE(_PARTITIONTIME)
+OPTIONS(partition_expiration_days=7)`)
+		assert.NoError(t, err)
+
+		ddl, err := translate.ToMaxCompute(tables[0], translate.Options{Lifecycle: 365, PartitionColumn: "pt"})
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE TABLE IF NOT EXISTS p.d.events (\n"+
+			"  `name` STRING\n"+
+			")\n"+
+			"PARTITIONED BY (\n"+
+			"  `pt` STRING COMMENT 'partition of ingestion time DATE(_PARTITIONTIME)'\n"+
+			")\n"+
+			"LIFECYCLE 365;\n", ddl)
+	})
+
+	t.Run("generates view with the query", func(t *testing.T) {
+		tables, err := translate.ParseBigQuery("CREATE VIEW `p.d.v` OPTIONS(description='users') AS SELECT id FROM p.d.users")
+		assert.NoError(t, err)
+
+		ddl, err := translate.ToMaxCompute(tables[0], translate.Options{ProjectMapping: map[string]string{"p.d": "mc.ods"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE VIEW IF NOT EXISTS mc.ods.v\nCOMMENT 'users'\nAS\nSELECT id FROM mc.ods.users;\n", ddl)
+	})
+
+	t.Run("maps the tables of view query", func(t *testing.T) {
+		query := "WITH u AS (SELECT * FROM `p.d.users`) " +
+			"SELECT EXTRACT(DAY FROM u.created_at), o.id FROM u JOIN `p`.d.orders AS o ON u.id = o.user_id, o.items " +
+			"LEFT JOIN other.d.items i USING (id) WHERE o.id IN (SELECT id FROM p.e.ids, UNNEST(x))"
+		tables, err := translate.ParseBigQuery("CREATE VIEW p.d.v AS " + query)
+		assert.NoError(t, err)
+
+		ddl, err := translate.ToMaxCompute(tables[0], translate.Options{ProjectMapping: map[string]string{"p.d": "mc.ods", "p": "mcp"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE VIEW IF NOT EXISTS mc.ods.v\nAS\n"+
+			"WITH u AS (SELECT * FROM mc.ods.users) "+
+			"SELECT EXTRACT(DAY FROM u.created_at), o.id FROM u JOIN mc.ods.orders AS o ON u.id = o.user_id, o.items "+
+			"LEFT JOIN other.d.items i USING (id) WHERE o.id IN (SELECT id FROM mcp.e.ids, UNNEST(x));\n", ddl)
+	})
+
+	t.Run("returns error for view query with tables without project", func(t *testing.T) {
+		tables, err := translate.ParseBigQuery("CREATE VIEW p.d.v AS SELECT id FROM d.users")
+		assert.NoError(t, err)
+
+		_, err = translate.ToMaxCompute(tables[0], translate.Options{})
+		assert.ErrorContains(t, err, "p.d.v: table d.users of query has no project")
+	})
+
+	t.Run("returns error for names without project", func(t *testing.T) {
+		tables, err := translate.ParseBigQuery("CREATE TABLE ds.t (a STRING)")
+		assert.NoError(t, err)
+
+		_, err = translate.ToMaxCompute(tables[0], translate.Options{})
+		assert.ErrorContains(t, err, "invalid table name")
+	})
+}